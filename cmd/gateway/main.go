@@ -0,0 +1,322 @@
+package main
+
+import (
+	"assignments-jelauria/servers/gateway/handlers"
+	"assignments-jelauria/servers/gateway/mailer"
+	"assignments-jelauria/servers/gateway/models/users"
+	"assignments-jelauria/servers/gateway/sessions"
+	"context"
+	"database/sql"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/go-sql-driver/mysql"
+)
+
+//sessionDuration is how long a session may sit idle in redis
+//before it expires
+const sessionDuration = time.Hour
+
+//sessionMaxAge is how long a session may last, however
+//recently it was used, before the user must sign in again
+const sessionMaxAge = 7 * 24 * time.Hour
+
+//sudoDuration is how long after giving their password users may make
+//sensitive changes, such as to their email, before they must
+//reauthenticate with POST /v1/sessions/mine. Changing the password
+//and deleting the account take the password instead.
+const sudoDuration = 15 * time.Minute
+
+//storeTimeout is how long each call to the user store may take
+//before the request is answered with 503 Service Unavailable
+const storeTimeout = 5 * time.Second
+
+//memoryDSN is the DSN that keeps users in memory
+const memoryDSN = "memory"
+
+//purgeInterval is how often deleted users whose
+//grace period is over are purged
+const purgeInterval = time.Hour
+
+//main is the main entry point for the server. Run it as
+//`gateway migrate ...` to manage the database schema instead.
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
+
+	//read the configuration from the environment:
+	//- ADDR: address the server should listen on (default ":443")
+	//- TLSCERT/TLSKEY: paths to the TLS certificate and private key
+	//- SESSIONKEY: key used to sign and validate SessionIDs
+	//- SESSIONKEYS: keyring used instead of SESSIONKEY, as a
+	//  comma-separated list of <key ID>:<key> pairs such as
+	//  "2:newkey,1:oldkey". New SessionIDs are signed with the first
+	//  key, and validated with any of them. To rotate keys, list a new
+	//  key first, and remove the old one once sessionMaxAge has
+	//  passed, or right away if it may have leaked.
+	//- TOKENKEY: key used to sign the tokens that confirm a new email
+	//  address. Use a key of its own rather than a session signing key.
+	//- REDISADDR: address of the redis server (default "127.0.0.1:6379")
+	//- DSN: data source name for the users database. Set it to
+	//  "memory" to keep users and password reset tokens in memory
+	//  instead, which is only suitable for local development, since
+	//  every account is lost on restart and each replica has users
+	//  of its own. Otherwise reset tokens are kept in the database.
+	//- DBDRIVER: database the DSN is for, one of "mysql" (default),
+	//  "postgres" or "sqlite3". SQLite only works in builds with cgo.
+	//- CORSORIGINS: comma-separated list of origins allowed to make
+	//  cross-origin requests (default "*")
+	//- PASSWORDBLOCKLIST: optional path to a file of common or breached
+	//  passwords, one per line, that users may not choose
+	//- PASSWORDHASHER: algorithm used to hash new passwords, either
+	//  "bcrypt" (default) or "argon2id". Existing hashes are upgraded
+	//  as users sign in.
+	//- SMTPADDR/MAILFROM: SMTP server and from address used to email
+	//  users. If SMTPADDR is not set, emails are written to the log.
+	//- SIGNINPOLICY: what to do when users who haven't verified their
+	//  email sign in: "allow" (default), "restrict" their sessions to
+	//  read-only requests, or "reject" them
+	//- DELETIONGRACEPERIOD: how long deleted accounts are kept before
+	//  being purged, such as "720h". Signing in again during the grace
+	//  period restores the account. Accounts are deleted right away if
+	//  it is not set.
+	//- SESSIONTRANSPORTS: comma-separated list of how SessionIDs are
+	//  sent, in the order they are read: "header" (the Authorization
+	//  header), "query" (the `auth` parameter) or "cookie" (an HttpOnly,
+	//  Secure cookie). The default is "header,query".
+	//  With "cookie", unsafe requests must come from the server's own
+	//  origin or one in CORSORIGINS, and carry the session's CSRF token
+	//  in the X-CSRF-Token header.
+	//- SESSIONCOOKIE: name of the session cookie (default "sid")
+	//- COOKIESAMESITE: SameSite mode of the session cookie, one of
+	//  "lax" (default), "strict" or "none"
+	addr := os.Getenv("ADDR")
+	if len(addr) == 0 {
+		addr = ":443"
+	}
+	tlsCertPath := requireEnv("TLSCERT")
+	tlsKeyPath := requireEnv("TLSKEY")
+	var signer sessions.Signer
+	if keys := os.Getenv("SESSIONKEYS"); len(keys) > 0 {
+		keyring, err := sessions.ParseKeyring(keys)
+		if err != nil {
+			log.Fatalf("invalid SESSIONKEYS: %v", err)
+		}
+		signer = keyring
+	} else {
+		signer = sessions.SigningKey(requireEnv("SESSIONKEY"))
+	}
+	tokenKey := requireEnv("TOKENKEY")
+	dsn := requireEnv("DSN")
+	redisAddr := os.Getenv("REDISADDR")
+	if len(redisAddr) == 0 {
+		redisAddr = "127.0.0.1:6379"
+	}
+	var corsOrigins []string
+	for _, origin := range strings.Split(os.Getenv("CORSORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); len(origin) > 0 {
+			corsOrigins = append(corsOrigins, origin)
+		}
+	}
+	switch hasher := os.Getenv("PASSWORDHASHER"); hasher {
+	case "", "bcrypt":
+	case "argon2id":
+		users.DefaultHasher = users.NewArgon2idHasher()
+	default:
+		log.Fatalf("unknown PASSWORDHASHER %q: must be bcrypt or argon2id", hasher)
+	}
+	if blocklist := os.Getenv("PASSWORDBLOCKLIST"); len(blocklist) > 0 {
+		if err := users.DefaultPasswordPolicy.LoadBreachedPasswords(blocklist); err != nil {
+			log.Fatalf("error loading password blocklist: %v", err)
+		}
+	}
+
+	var signInPolicy handlers.SignInPolicy
+	switch policy := os.Getenv("SIGNINPOLICY"); policy {
+	case "", "allow":
+	case "restrict":
+		signInPolicy = handlers.RestrictUnverified
+	case "reject":
+		signInPolicy = handlers.RejectUnverified
+	default:
+		log.Fatalf("unknown SIGNINPOLICY %q: must be allow, restrict or reject", policy)
+	}
+
+	var deletionGracePeriod time.Duration
+	if grace := os.Getenv("DELETIONGRACEPERIOD"); len(grace) > 0 {
+		var err error
+		deletionGracePeriod, err = time.ParseDuration(grace)
+		if err != nil || deletionGracePeriod < 0 {
+			log.Fatalf("invalid DELETIONGRACEPERIOD %q: must be a duration such as 720h", grace)
+		}
+	}
+
+	transport, usesCookies := parseTransports(os.Getenv("SESSIONTRANSPORTS"))
+
+	redisClient := redis.NewClient(&redis.Options{
+		Addr: redisAddr,
+	})
+	if err := redisClient.Ping().Err(); err != nil {
+		log.Fatalf("error connecting to redis at %s: %v", redisAddr, err)
+	}
+
+	var userStore users.Store
+	var resetTokens users.TokenStore
+	if dsn != memoryDSN {
+		db, dialect := openDB(dsn)
+		defer db.Close()
+		if err := db.Ping(); err != nil {
+			log.Fatalf("error pinging database: %v", err)
+		}
+		switch dialect {
+		case users.Postgres:
+			userStore = users.NewPostgresStore(db)
+		case users.SQLite:
+			userStore = users.NewSQLiteStore(db)
+		default:
+			userStore = users.NewSQLStore(db)
+		}
+		resetTokens = users.NewSQLTokenStore(db, dialect)
+	} else {
+		log.Printf("DSN is %q, keeping users in memory", memoryDSN)
+		userStore = users.NewMemStore()
+		resetTokens = users.NewMemTokenStore()
+	}
+
+	var mailSender mailer.Sender = mailer.NewLogSender(os.Stdout)
+	if smtpAddr := os.Getenv("SMTPADDR"); len(smtpAddr) > 0 {
+		mailSender = mailer.NewSMTPSender(smtpAddr, requireEnv("MAILFROM"), nil)
+	}
+
+	seshStore := sessions.NewRedisStore(redisClient, sessionDuration)
+	seshStore.MaxAge = sessionMaxAge
+
+	if _, ok := userStore.(users.SoftDeleteStore); deletionGracePeriod > 0 && !ok {
+		log.Fatal("the user store can't soft-delete users, so DELETIONGRACEPERIOD must be 0")
+	}
+	timeoutStore := users.NewTimeoutStore(userStore, storeTimeout)
+
+	ctx := &handlers.Context{
+		SeshKey:             signer,
+		SeshStore:           seshStore,
+		SeshTransport:       transport,
+		UserStore:           timeoutStore,
+		ResetTokens:         users.NewTimeoutTokenStore(resetTokens, storeTimeout),
+		TokenKey:            tokenKey,
+		Mailer:              mailSender,
+		SignInPolicy:        signInPolicy,
+		DeletionGracePeriod: deletionGracePeriod,
+		SudoDuration:        sudoDuration,
+	}
+
+	if deletionGracePeriod > 0 {
+		purger := users.NewPurger(timeoutStore, deletionGracePeriod, purgeInterval)
+		go purger.Run(context.Background())
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/summary", handlers.SummaryHandler)
+	mux.HandleFunc("/v1/users", ctx.UsersHandler)
+	mux.HandleFunc("/v1/users/", ctx.EnsureAuth(ctx.SpecificUsersHandler))
+	mux.HandleFunc("/v1/users/verify", ctx.VerificationHandler)
+	mux.HandleFunc("/v1/users/me/password", ctx.EnsureAuth(ctx.PasswordHandler))
+	mux.HandleFunc("/v1/resets", ctx.ResetsHandler)
+	mux.HandleFunc("/v1/emailchanges", ctx.EmailChangesHandler)
+	mux.HandleFunc("/v1/sessions", ctx.SessionsHandler)
+	mux.HandleFunc("/v1/sessions/", ctx.SpecificSessionHandler)
+
+	var handler http.Handler = mux
+	if usesCookies {
+		//browsers send cookies with requests forged by other sites,
+		//so only the origins allowed by CORS are trusted
+		handler = handlers.NewCSRF(mux, ctx, corsOrigins...)
+	}
+	wrappedMux := handlers.NewCORS(handler, corsOrigins...)
+	//browsers only send cookies cross-origin if credentials are allowed
+	wrappedMux.AllowCredentials = usesCookies
+
+	log.Printf("server is listening at %s...", addr)
+	log.Fatal(http.ListenAndServeTLS(addr, tlsCertPath, tlsKeyPath, wrappedMux))
+}
+
+//parseTransports returns the session Transport configured by
+//`config`, as described for SESSIONTRANSPORTS, and whether it uses
+//cookies, exiting the process on error
+func parseTransports(config string) (sessions.Transport, bool) {
+	if len(config) == 0 {
+		return sessions.DefaultTransport, false
+	}
+	var transports sessions.Transports
+	usesCookies := false
+	for _, name := range strings.Split(config, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case "header":
+			transports = append(transports, sessions.HeaderTransport{})
+		case "query":
+			transports = append(transports, sessions.QueryTransport{})
+		case "cookie":
+			cookieName := os.Getenv("SESSIONCOOKIE")
+			if len(cookieName) == 0 {
+				cookieName = "sid"
+			}
+			cookie := sessions.NewCookieTransport(cookieName)
+			switch sameSite := os.Getenv("COOKIESAMESITE"); sameSite {
+			case "", "lax":
+			case "strict":
+				cookie.SameSite = http.SameSiteStrictMode
+			case "none":
+				cookie.SameSite = http.SameSiteNoneMode
+			default:
+				log.Fatalf("unknown COOKIESAMESITE %q: must be lax, strict or none", sameSite)
+			}
+			transports = append(transports, cookie)
+			usesCookies = true
+		default:
+			log.Fatalf("unknown session transport %q: must be header, query or cookie", name)
+		}
+	}
+	return transports, usesCookies
+}
+
+//openDB opens the users database at `dsn` using the driver named
+//by the DBDRIVER environment variable, exiting the process on error
+func openDB(dsn string) (*sql.DB, users.Dialect) {
+	driver := os.Getenv("DBDRIVER")
+	if len(driver) == 0 {
+		driver = "mysql"
+	}
+	dialect, err := users.DialectFor(driver)
+	if err != nil {
+		log.Fatal(err)
+	}
+	//MySQL only scans timestamps into time.Time with parseTime
+	if dialect == users.MySQL {
+		cfg, err := mysql.ParseDSN(dsn)
+		if err != nil {
+			log.Fatalf("error parsing DSN: %v", err)
+		}
+		cfg.ParseTime = true
+		dsn = cfg.FormatDSN()
+	}
+	db, err := sql.Open(dialect.Name(), dsn)
+	if err != nil {
+		log.Fatalf("error opening database: %v", err)
+	}
+	return db, dialect
+}
+
+//requireEnv returns the value of the environment variable `name`,
+//exiting the process if it is not set
+func requireEnv(name string) string {
+	val := os.Getenv(name)
+	if len(val) == 0 {
+		log.Fatalf("please set the %s environment variable", name)
+	}
+	return val
+}
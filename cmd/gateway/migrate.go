@@ -0,0 +1,78 @@
+package main
+
+import (
+	"assignments-jelauria/servers/gateway/models/users"
+	"fmt"
+	"log"
+	"os"
+)
+
+//migrateUsage describes the migrate subcommand
+const migrateUsage = `usage: gateway migrate up|down|status
+
+  up      apply every pending migration
+  down    revert the most recently applied migration
+  status  list the migrations and whether each has been applied
+
+The database is read from the DSN and DBDRIVER environment variables.
+With MySQL and Postgres, up and down wait for any other migrate command
+running against the same database to finish. SQLite has no such lock,
+so only run one at a time.`
+
+//runMigrate runs the migrate subcommand with the given arguments
+func runMigrate(args []string) {
+	if len(args) != 1 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+
+	dsn := requireEnv("DSN")
+	if dsn == memoryDSN {
+		log.Fatal("users kept in memory have no schema to migrate")
+	}
+	db, dialect := openDB(dsn)
+	defer db.Close()
+	migrator, err := users.NewMigrator(db, dialect)
+	if err != nil {
+		log.Fatalf("error loading migrations: %v", err)
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up()
+		for _, migration := range applied {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("database is up to date")
+		}
+	case "down":
+		reverted, err := migrator.Down()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if reverted == nil {
+			fmt.Println("no migrations have been applied")
+		} else {
+			fmt.Printf("reverted %04d_%s\n", reverted.Version, reverted.Name)
+		}
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.Applied {
+				appliedAt = "applied " + status.AppliedAt
+			}
+			fmt.Printf("%04d_%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		os.Exit(2)
+	}
+}
@@ -3,12 +3,14 @@ package handlers
 import (
 	"assignments-jelauria/servers/gateway/models/users"
 	"assignments-jelauria/servers/gateway/sessions"
+	"context"
 	"encoding/json"
-	"errors"
+	"log"
 	"net/http"
 	"path"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
@@ -18,153 +20,404 @@ import (
 //access to things like the session store and user store.
 func (c *Context) UsersHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodPost {
-		conentType := r.Header.Get("Content-Type")
 		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
-			http.Error(w, errors.New("Request body must be in JSON."), http.StatusUnsupportedMediaType)
+			respondError(w, http.StatusUnsupportedMediaType, ErrCodeUnsupportedMediaType, "Request body must be in JSON.")
 			return
 		}
 		var nu users.NewUser
 		jsonErr := json.NewDecoder(r.Body).Decode(&nu)
-		if err != nil {
-			http.Error(w, jsonErr.Error(), http.StatusBadRequest)
+		if jsonErr != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeBadRequest, "Request body is not valid JSON.")
+			return
+		}
+		if valErr := nu.Validate(); valErr != nil {
+			respondValidationError(w, valErr)
 			return
 		}
 		user, toUsrErr := nu.ToUser()
 		if toUsrErr != nil {
-			http.Error(w, toUsrErr.Error(), http.StatusBadRequest)
+			respondInternalError(w, toUsrErr)
 			return
 		}
-		authUsr, insertErr := c.UserStore.Insert(user)
+		authUsr, insertErr := c.UserStore.Insert(r.Context(), user)
 		if insertErr != nil {
-			http.Error(w, insertErr.Error(), http.StatusBadRequest)
+			respondConflict(w, insertErr)
 			return
 		}
-		_, keyErr := sessions.BeginSession(c.SeshKey, c.SeshStore, &SessionState{time.Now(), authUsr}, w)
-		if keyErr != nil {
-			http.Error(w, keyErr.Error(), http.StatusBadRequest)
-			return
+		//the account exists now, so a failure to send the
+		//verification email shouldn't fail the sign-up
+		if err := c.sendVerificationToken(authUsr); err != nil {
+			log.Printf("error sending verification token to user %d: %v", authUsr.ID, err)
+		}
+		if c.signInDecision(authUsr) != SignInReject {
+			_, keyErr := c.beginSession(r.Context(), authUsr, w)
+			if keyErr != nil {
+				respondInternalError(w, keyErr)
+				return
+			}
 		}
-		w.Header().Add("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(authUsr)
+		respondJSON(w, http.StatusCreated, authUsr)
 		return
 	}
-	http.Error(w, errors.New("Http method not allowed."), http.StatusMethodNotAllowed)
+	respondMethodNotAllowed(w)
 	return
 }
 
+//invalidCredentialsMsg is the response message for a failed sign-in.
+//It deliberately doesn't say whether the email or the password was wrong.
+const invalidCredentialsMsg = "Invalid email or password."
+
+var fakePassHashOnce sync.Once
+var fakePassHashVal []byte
+
+//fakePassHash returns a hash made with users.DefaultHasher, which is
+//checked when someone signs in with an unknown email. It is made on
+//first use, after DefaultHasher has been configured.
+func fakePassHash() []byte {
+	fakePassHashOnce.Do(func() {
+		fakePassHashVal, _ = users.DefaultHasher.Hash("loveUAvatarAang")
+	})
+	return fakePassHashVal
+}
+
+//SpecificUsersHandler handles requests for a specific user.
+//It must be wrapped with EnsureAuth so that the current
+//SessionState is available from the request context.
 func (c *Context) SpecificUsersHandler(w http.ResponseWriter, r *http.Request) {
-	seshID, seshErr := sessions.GetSessionID(r, c.SeshKey)
-	if seshErr != nil {
-		http.Error(w, seshErr.Error(), http.StatusUnauthorized)
-		return
-	}
-	currState := &SessionState{}
-	valErr := c.SeshStore.Get(seshID, currState)
-	if valErr != nil {
-		http.Error(w, valErr.Error(), http.StatusUnauthorized)
+	currState, ok := SessionStateFromContext(r.Context())
+	if !ok {
+		respondError(w, http.StatusUnauthorized, ErrCodeUnauthorized, unauthorizedMsg)
 		return
 	}
 	if r.Method == http.MethodGet {
 		strID := path.Base(r.URL.Path)
 		if strID == "me" {
-			w.Header().Add("Content-Type", "application/json")
-			w.WriteHeader(http.StatusOK)
-			json.NewEncoder(w).Encode(currState.AuthUser)
+			respondJSON(w, http.StatusOK, currState.AuthUser)
 			return
 		}
 		intID, strErr := strconv.ParseInt(strID, 10, 64)
 		if strErr != nil {
-			http.Error(w, errors.New("No user with given ID."), http.StatusNotFound)
+			respondError(w, http.StatusNotFound, ErrCodeNotFound, "No user with given ID.")
+			return
+		}
+		qUser, sqlErr := c.UserStore.GetByID(r.Context(), intID)
+		if sqlErr == users.ErrUserNotFound {
+			respondError(w, http.StatusNotFound, ErrCodeNotFound, "No user with given ID.")
+			return
 		}
-		qUser, sqlErr := c.UserStore.GetByID(intID)
 		if sqlErr != nil {
-			http.Error(w, sqlErr.Error(), http.StatusNotFound)
+			respondInternalError(w, sqlErr)
+			return
 		}
-		w.Header().Add("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(qUser)
+		//accounts waiting to be purged are already gone to everyone else
+		if qUser.DeletedAt != nil {
+			respondError(w, http.StatusNotFound, ErrCodeNotFound, "No user with given ID.")
+			return
+		}
+		respondJSON(w, http.StatusOK, qUser)
 		return
 	}
 	if r.Method == http.MethodPatch {
 		pID := path.Base(r.URL.Path)
 		if pID != "me" {
-			http.Error(w, errors.New("Forbidden request."), http.StatusForbidden)
+			respondError(w, http.StatusForbidden, ErrCodeForbidden, "Forbidden request.")
+			return
 		}
-		conentType := r.Header.Get("Content-Type")
 		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
-			http.Error(w, errors.New("Request body must be in JSON."), http.StatusUnsupportedMediaType)
+			respondError(w, http.StatusUnsupportedMediaType, ErrCodeUnsupportedMediaType, "Request body must be in JSON.")
 			return
 		}
 		var updates users.Updates
 		jsonErr := json.NewDecoder(r.Body).Decode(&updates)
-		if err != nil {
-			http.Error(w, jsonErr.Error(), http.StatusBadRequest)
+		if jsonErr != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeBadRequest, "Request body is not valid JSON.")
+			return
+		}
+		if err := updates.Validate(); err != nil {
+			respondValidationError(w, err)
 			return
 		}
-		updUser, upErr := c.UserStore.Update(currState.AuthUser.ID, updates)
+		//a new email only replaces the current one once it's confirmed
+		var newEmail string
+		if updates.Email != nil {
+			email := strings.TrimSpace(*updates.Email)
+			updates.Email = nil
+			//the session's copy of the user has no email, since
+			//it isn't serialized, so compare with the stored user
+			user, err := c.UserStore.GetByID(r.Context(), currState.AuthUser.ID)
+			if err != nil {
+				respondInternalError(w, err)
+				return
+			}
+			if email != user.Email {
+				if !c.recentlyAuthenticated(currState) {
+					respondReauthRequired(w)
+					return
+				}
+				if err := c.checkEmailChange(r.Context(), user.ID, email); err != nil {
+					respondConflict(w, err)
+					return
+				}
+				newEmail = email
+			}
+		}
+		updUser, upErr := c.UserStore.Update(r.Context(), currState.AuthUser.ID, &updates)
 		if upErr != nil {
-			http.Error(w, upErr.Error(), http.StatusBadRequest)
+			respondConflict(w, upErr)
 			return
 		}
-		w.Header().Add("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(updUser)
+		//only record and mail the email change once the rest of
+		//the update has succeeded, so a failed PATCH changes nothing
+		if len(newEmail) > 0 {
+			if err := c.requestEmailChange(r.Context(), updUser, newEmail); err != nil {
+				respondConflict(w, err)
+				return
+			}
+		}
+		//keep the session's copy of the user current,
+		//so that GET /v1/users/me shows the changes
+		currState.AuthUser = updUser
+		if sid, ok := SessionIDFromContext(r.Context()); ok {
+			if err := c.saveSession(r.Context(), sid, currState); err != nil {
+				if err == sessions.ErrStateNotFound {
+					respondError(w, http.StatusUnauthorized, ErrCodeUnauthorized, unauthorizedMsg)
+				} else {
+					respondInternalError(w, err)
+				}
+				return
+			}
+		}
+		respondJSON(w, http.StatusOK, updUser)
 		return
 	}
-	http.Error(w, errors.New("Http method not allowed."), http.StatusMethodNotAllowed)
+	if r.Method == http.MethodDelete {
+		pID := path.Base(r.URL.Path)
+		if pID != "me" {
+			respondError(w, http.StatusForbidden, ErrCodeForbidden, "Forbidden request.")
+			return
+		}
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			respondError(w, http.StatusUnsupportedMediaType, ErrCodeUnsupportedMediaType, "Request body must be in JSON.")
+			return
+		}
+		var deletion users.AccountDeletion
+		if err := json.NewDecoder(r.Body).Decode(&deletion); err != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeBadRequest, "Request body is not valid JSON.")
+			return
+		}
+		//the session state doesn't include the password hash,
+		//so get the current user from the store
+		user, err := c.UserStore.GetByID(r.Context(), currState.AuthUser.ID)
+		if err != nil {
+			respondInternalError(w, err)
+			return
+		}
+		if err := user.Authenticate(deletion.Password); err != nil {
+			respondError(w, http.StatusForbidden, ErrCodeInvalidCredentials, "Password is incorrect.")
+			return
+		}
+		if err := c.deleteUser(r.Context(), user.ID); err != nil {
+			respondInternalError(w, err)
+			return
+		}
+		//also end the current session directly, in case
+		//the session store can't end them all
+		if sid, ok := SessionIDFromContext(r.Context()); ok {
+			if err := c.SeshStore.Delete(r.Context(), sid); err != nil {
+				respondUnavailable(w, err)
+				return
+			}
+		}
+		c.transport().Clear(w)
+		w.Write([]byte("account deleted"))
+		return
+	}
+	respondMethodNotAllowed(w)
 	return
 }
 
 func (c *Context) SessionsHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method == http.MethodPost {
-		conentType := r.Header.Get("Content-Type")
 		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
-			http.Error(w, errors.New("Request body must be in JSON."), http.StatusUnsupportedMediaType)
+			respondError(w, http.StatusUnsupportedMediaType, ErrCodeUnsupportedMediaType, "Request body must be in JSON.")
 			return
 		}
-		var creds *users.Credentials
+		var creds users.Credentials
 		jsonErr := json.NewDecoder(r.Body).Decode(&creds)
-		if err != nil {
-			http.Error(w, jsonErr.Error(), http.StatusBadRequest)
+		if jsonErr != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeBadRequest, "Request body is not valid JSON.")
+			return
+		}
+		user, getErr := c.UserStore.GetByEmail(r.Context(), creds.Email)
+		if getErr == users.ErrUserNotFound {
+			//take as long as checking the password of a real user, so
+			//the response time doesn't reveal who has an account
+			fakeUsr := &users.User{ID: -123, Email: "helloworld", PassHash: fakePassHash(), UserName: "Toph", FirstName: "Beifong", LastName: "blah"}
+			fakeUsr.Authenticate(creds.Password)
+			respondError(w, http.StatusUnauthorized, ErrCodeInvalidCredentials, invalidCredentialsMsg)
 			return
 		}
-		user, getErr := c.UserStore.GetByEmail(creds.Email)
 		if getErr != nil {
-			fakeUsr := &users.User{-123, "helloworld", []byte("loveUAvatarAang"), "Toph", "Beifong", "blah"}
-			fakeUsr.Authenticate("12345")
-			http.Error(w, getErr.Error(), http.StatusUnauthorized)
+			respondInternalError(w, getErr)
 			return
 		}
 		authErr := user.Authenticate(creds.Password)
 		if authErr != nil {
-			http.Error(w, getErr.Error(), http.StatusUnauthorized)
+			respondError(w, http.StatusUnauthorized, ErrCodeInvalidCredentials, invalidCredentialsMsg)
+			return
+		}
+		if c.signInDecision(user) == SignInReject {
+			respondError(w, http.StatusForbidden, ErrCodeEmailUnverified, unverifiedMsg)
 			return
 		}
-		_, keyErr := sessions.BeginSession(c.SeshKey, c.SeshStore, &SessionState{time.Now(), user}, w)
+		//signing in during the grace period cancels the deletion
+		if user.DeletedAt != nil {
+			if err := c.restoreUser(r.Context(), user.ID); err != nil {
+				respondInternalError(w, err)
+				return
+			}
+			user.DeletedAt = nil
+		}
+		//now that we have the plaintext password, upgrade a hash made
+		//with an outdated algorithm or cost. Failing to do so shouldn't
+		//stop the user from signing in, so the error is only logged.
+		if user.NeedsRehash() {
+			if err := c.setPassword(r.Context(), user, creds.Password); err != nil {
+				log.Printf("error upgrading password hash of user %d: %v", user.ID, err)
+			}
+		}
+		_, keyErr := c.beginSession(r.Context(), user, w)
 		if keyErr != nil {
-			http.Error(w, keyErr.Error(), http.StatusUnauthorized)
+			respondInternalError(w, keyErr)
 			return
 		}
-		w.Header().Add("Content-Type", "application/json")
-		w.WriteHeader(http.StatusCreated)
-		json.NewEncoder(w).Encode(user)
+		respondJSON(w, http.StatusCreated, user)
+		return
+	}
+	if r.Method == http.MethodGet {
+		c.EnsureAuth(c.listSessions)(w, r)
 		return
 	}
-	http.Error(w, errors.New("Http method not allowed."), http.StatusMethodNotAllowed)
+	respondMethodNotAllowed(w)
 	return
 }
 
+//SpecificSessionHandler ends sessions. DELETE /v1/sessions/mine signs
+//out of the current session, and DELETE /v1/sessions/{id} ends the
+//signed-in user's session with the given ID, as listed by GET /v1/sessions.
+//POST /v1/sessions/mine reauthenticates the current session.
 func (c *Context) SpecificSessionHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		if path.Base(r.URL.Path) != "mine" {
+			respondError(w, http.StatusForbidden, ErrCodeForbidden, "Forbidden request.")
+			return
+		}
+		c.EnsureAuth(c.reauthenticate)(w, r)
+		return
+	}
 	if r.Method == http.MethodDelete {
 		pID := path.Base(r.URL.Path)
 		if pID != "mine" {
-			http.Error(w, errors.New("Forbidden request."), http.StatusForbidden)
+			c.EnsureAuth(c.revokeSession)(w, r)
+			return
+		}
+		sid, err := sessions.GetSessionID(r, c.SeshKey, c.transport())
+		if err != nil {
+			respondError(w, http.StatusUnauthorized, ErrCodeUnauthorized, unauthorizedMsg)
+			return
+		}
+		if err := c.SeshStore.Delete(r.Context(), sid); err != nil {
+			respondUnavailable(w, err)
+			return
 		}
-		sessions.EndSession(r, c.SeshKey, c.SeshStore)
+		c.transport().Clear(w)
 		w.Write([]byte("signed out"))
 		return
 	}
-	http.Error(w, errors.New("Http method not allowed."), http.StatusMethodNotAllowed)
+	respondMethodNotAllowed(w)
 	return
 }
+
+//beginSession begins a new session for the authenticated `user`,
+//and indexes it under the user's ID if the session store supports that.
+//The session is Restricted if the SignInPolicy restricts the user.
+//Its CSRF token is sent in the X-CSRF-Token header.
+func (c *Context) beginSession(ctx context.Context, user *users.User, w http.ResponseWriter) (sessions.SessionID, error) {
+	csrfToken, err := newCSRFToken()
+	if err != nil {
+		return sessions.InvalidSessionID, err
+	}
+	now := time.Now()
+	state := &SessionState{
+		SeshStart:  now,
+		AuthUser:   user,
+		Restricted: c.signInDecision(user) == SignInRestrict,
+		CSRFToken:  csrfToken,
+		AuthTime:   now,
+	}
+	sid, err := sessions.BeginSession(ctx, c.SeshKey, c.transport(), c.SeshStore, state, w)
+	if err != nil {
+		return sessions.InvalidSessionID, err
+	}
+	w.Header().Set(headerCSRFToken, csrfToken)
+	if indexed, ok := c.SeshStore.(sessions.IndexedStore); ok {
+		if err := indexed.Index(ctx, user.ID, sid); err != nil {
+			return sessions.InvalidSessionID, err
+		}
+	}
+	return sid, nil
+}
+
+//endUserSessions ends every session of the user with the given ID,
+//except for those in `keep`. Stores that don't keep an index of each
+//user's sessions can't do this, so their sessions are left to expire.
+func (c *Context) endUserSessions(ctx context.Context, userID int64, keep ...sessions.SessionID) error {
+	indexed, ok := c.SeshStore.(sessions.IndexedStore)
+	if !ok {
+		log.Printf("session store can't end the sessions of user %d", userID)
+		return nil
+	}
+	return indexed.DeleteAll(ctx, userID, keep...)
+}
+
+//saveSession saves the `state` of the session `sid` after EnsureAuth
+//loaded it. Stores that keep an index of each user's sessions only save
+//it if the session hasn't been ended meanwhile, for example by a
+//password change, and return sessions.ErrStateNotFound instead.
+func (c *Context) saveSession(ctx context.Context, sid sessions.SessionID, state *SessionState) error {
+	if indexed, ok := c.SeshStore.(sessions.IndexedStore); ok {
+		return indexed.Update(ctx, state.AuthUser.ID, sid, state)
+	}
+	return c.SeshStore.Save(ctx, sid, state)
+}
+
+//deleteUser deletes the user with the given ID and ends all of their
+//sessions. With a DeletionGracePeriod, the user is only soft-deleted,
+//and is purged once the grace period is over unless they sign in again.
+//ErrNotSupported is returned if the user store can't soft-delete users.
+func (c *Context) deleteUser(ctx context.Context, userID int64) error {
+	if c.DeletionGracePeriod > 0 {
+		store, ok := c.UserStore.(users.SoftDeleteStore)
+		if !ok {
+			return users.ErrNotSupported
+		}
+		now := time.Now()
+		if err := store.SetDeletedAt(ctx, userID, &now); err != nil {
+			return err
+		}
+	} else if err := c.UserStore.Delete(ctx, userID); err != nil {
+		return err
+	}
+	return c.endUserSessions(ctx, userID)
+}
+
+//restoreUser cancels the deletion of the soft-deleted user with
+//the given ID. Only a SoftDeleteStore has soft-deleted users, so
+//ErrNotSupported is returned if the user store isn't one.
+func (c *Context) restoreUser(ctx context.Context, userID int64) error {
+	store, ok := c.UserStore.(users.SoftDeleteStore)
+	if !ok {
+		return users.ErrNotSupported
+	}
+	return store.SetDeletedAt(ctx, userID, nil)
+}
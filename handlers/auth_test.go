@@ -0,0 +1,251 @@
+package handlers
+
+import (
+	"assignments-jelauria/servers/gateway/models/users"
+	"assignments-jelauria/servers/gateway/sessions"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUsersHandlerConflict(t *testing.T) {
+	cases := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedField  string
+	}{
+		{
+			"New User",
+			`{"email": "aang@test.com", "password": "AvatarAang!", "passwordConf": "AvatarAang!", "userName": "Aang"}`,
+			http.StatusCreated,
+			"",
+		},
+		{
+			"Email Taken",
+			`{"email": "toph@test.com", "password": "AvatarAang!", "passwordConf": "AvatarAang!", "userName": "Aang"}`,
+			http.StatusConflict,
+			"email",
+		},
+		{
+			"UserName Taken",
+			`{"email": "aang@test.com", "password": "AvatarAang!", "passwordConf": "AvatarAang!", "userName": "TheBlindBandit"}`,
+			http.StatusConflict,
+			"userName",
+		},
+	}
+
+	for _, c := range cases {
+		ctx, _ := newTestContext(t)
+		req, _ := http.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(c.body))
+		req.Header.Set(headerContentType, contentTypeJSON)
+		resp := httptest.NewRecorder()
+		ctx.UsersHandler(resp, req)
+
+		if resp.Code != c.expectedStatus {
+			t.Errorf("case %s: incorrect status code: expected %d but got %d", c.name, c.expectedStatus, resp.Code)
+			continue
+		}
+		if c.expectedStatus != http.StatusConflict {
+			continue
+		}
+		errResp := &ErrorResponse{}
+		if err := json.NewDecoder(resp.Body).Decode(errResp); err != nil {
+			t.Fatalf("case %s: error decoding response body: %v", c.name, err)
+		}
+		if errResp.Code != ErrCodeConflict || len(errResp.Fields) != 1 || errResp.Fields[0].Field != c.expectedField {
+			t.Errorf("case %s: incorrect error response: expected a conflict on %s but got %+v", c.name, c.expectedField, errResp)
+		}
+	}
+}
+
+func TestSessionsHandlerUnknownEmail(t *testing.T) {
+	ctx, _ := newTestContext(t)
+	req, _ := http.NewRequest(http.MethodPost, "/v1/sessions", strings.NewReader(`{"email": "aang@test.com", "password": "TophRocks1337"}`))
+	req.Header.Set(headerContentType, contentTypeJSON)
+	resp := httptest.NewRecorder()
+	ctx.SessionsHandler(resp, req)
+
+	if resp.Code != http.StatusUnauthorized {
+		t.Errorf("incorrect status code: expected %d but got %d", http.StatusUnauthorized, resp.Code)
+	}
+	if len(resp.Header().Get("Authorization")) > 0 {
+		t.Errorf("a session was begun for an unknown email")
+	}
+}
+
+func TestSpecificUsersHandlerPatch(t *testing.T) {
+	cases := []struct {
+		name              string
+		body              string
+		expectedStatus    int
+		expectedFirstName string
+		expectedUserName  string
+		expectedBio       string
+	}{
+		{
+			"Bio Only",
+			`{"bio": "Greatest earthbender in the world"}`,
+			http.StatusOK,
+			"Toph",
+			"TheBlindBandit",
+			"Greatest earthbender in the world",
+		},
+		{
+			"New UserName",
+			`{"userName": "MeltyBlob", "displayName": "Toph Beifong", "locale": "en-US"}`,
+			http.StatusOK,
+			"Toph",
+			"MeltyBlob",
+			"",
+		},
+		{
+			"UserName Taken",
+			`{"userName": "Aang"}`,
+			http.StatusConflict,
+			"Toph",
+			"TheBlindBandit",
+			"",
+		},
+		{
+			"Invalid Locale",
+			`{"firstName": "Melty", "locale": "earth kingdom"}`,
+			http.StatusBadRequest,
+			"Toph",
+			"TheBlindBandit",
+			"",
+		},
+	}
+
+	for _, c := range cases {
+		ctx, user := newTestContext(t)
+		if _, err := ctx.UserStore.Insert(context.Background(), &users.User{Email: "aang@test.com", UserName: "Aang"}); err != nil {
+			t.Fatalf("case %s: error inserting user: %v", c.name, err)
+		}
+		sid := newTestSession(t, ctx, user)
+		handler := ctx.EnsureAuth(ctx.SpecificUsersHandler)
+
+		req, _ := http.NewRequest(http.MethodPatch, "/v1/users/me", strings.NewReader(c.body))
+		req.Header.Set(headerContentType, contentTypeJSON)
+		req.Header.Set("Authorization", "Bearer "+sid.String())
+		resp := httptest.NewRecorder()
+		handler(resp, req)
+		if resp.Code != c.expectedStatus {
+			t.Errorf("case %s: incorrect status code: expected %d but got %d", c.name, c.expectedStatus, resp.Code)
+		}
+
+		//the session should see the same user as the store
+		req, _ = http.NewRequest(http.MethodGet, "/v1/users/me", nil)
+		req.Header.Set("Authorization", "Bearer "+sid.String())
+		resp = httptest.NewRecorder()
+		handler(resp, req)
+		me := &users.User{}
+		if err := json.NewDecoder(resp.Body).Decode(me); err != nil {
+			t.Fatalf("case %s: error decoding response body: %v", c.name, err)
+		}
+		stored, _ := ctx.UserStore.GetByID(context.Background(), user.ID)
+		for _, got := range []*users.User{me, stored} {
+			if got.FirstName != c.expectedFirstName || got.UserName != c.expectedUserName || got.Bio != c.expectedBio {
+				t.Errorf("case %s: incorrect user after update: %+v", c.name, got)
+			}
+		}
+	}
+}
+
+func TestSpecificUsersHandlerDelete(t *testing.T) {
+	cases := []struct {
+		name           string
+		path           string
+		body           string
+		gracePeriod    time.Duration
+		expectedStatus int
+	}{
+		{
+			"Deleted",
+			"/v1/users/me",
+			`{"password": "TophRocks1337"}`,
+			0,
+			http.StatusOK,
+		},
+		{
+			"Soft Deleted",
+			"/v1/users/me",
+			`{"password": "TophRocks1337"}`,
+			24 * time.Hour,
+			http.StatusOK,
+		},
+		{
+			"Incorrect Password",
+			"/v1/users/me",
+			`{"password": "Aang"}`,
+			0,
+			http.StatusForbidden,
+		},
+		{
+			"Other User",
+			"/v1/users/2",
+			`{"password": "TophRocks1337"}`,
+			0,
+			http.StatusForbidden,
+		},
+	}
+
+	for _, c := range cases {
+		ctx, user := newTestContext(t)
+		ctx.DeletionGracePeriod = c.gracePeriod
+		sid := newTestSession(t, ctx, user)
+		otherSID := newTestSession(t, ctx, user)
+
+		req, _ := http.NewRequest(http.MethodDelete, c.path, strings.NewReader(c.body))
+		req.Header.Set(headerContentType, contentTypeJSON)
+		req.Header.Set("Authorization", "Bearer "+sid.String())
+		resp := httptest.NewRecorder()
+		ctx.EnsureAuth(ctx.SpecificUsersHandler)(resp, req)
+		if resp.Code != c.expectedStatus {
+			t.Errorf("case %s: incorrect status code: expected %d but got %d", c.name, c.expectedStatus, resp.Code)
+		}
+
+		deleted := c.expectedStatus == http.StatusOK
+		stored, err := ctx.UserStore.GetByID(context.Background(), user.ID)
+		switch {
+		case !deleted && (err != nil || stored.DeletedAt != nil):
+			t.Errorf("case %s: user should not be deleted", c.name)
+		case deleted && c.gracePeriod == 0 && err != users.ErrUserNotFound:
+			t.Errorf("case %s: user should be deleted right away", c.name)
+		case deleted && c.gracePeriod > 0 && (err != nil || stored.DeletedAt == nil):
+			t.Errorf("case %s: user should be soft-deleted", c.name)
+		}
+		for _, s := range []sessions.SessionID{sid, otherSID} {
+			err := ctx.SeshStore.Get(context.Background(), s, &SessionState{})
+			if deleted && err != sessions.ErrStateNotFound {
+				t.Errorf("case %s: sessions should be ended when the account is deleted", c.name)
+			}
+			if !deleted && err != nil {
+				t.Errorf("case %s: sessions should not be ended when the account is kept", c.name)
+			}
+		}
+	}
+}
+
+func TestSessionsHandlerRestoresDeletedUser(t *testing.T) {
+	ctx, user := newTestContext(t)
+	ctx.DeletionGracePeriod = 24 * time.Hour
+	if err := ctx.deleteUser(context.Background(), user.ID); err != nil {
+		t.Fatalf("error deleting user: %v", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "/v1/sessions", strings.NewReader(`{"email": "toph@test.com", "password": "TophRocks1337"}`))
+	req.Header.Set(headerContentType, contentTypeJSON)
+	resp := httptest.NewRecorder()
+	ctx.SessionsHandler(resp, req)
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("incorrect status code: expected %d but got %d", http.StatusCreated, resp.Code)
+	}
+	if stored, _ := ctx.UserStore.GetByID(context.Background(), user.ID); stored.DeletedAt != nil {
+		t.Errorf("signing in did not restore the deleted user")
+	}
+}
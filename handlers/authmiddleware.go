@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"assignments-jelauria/servers/gateway/sessions"
+	"context"
+	"net/http"
+)
+
+//contextKey is the type of the keys this package uses
+//to store values in a request's context.Context
+type contextKey int
+
+const (
+	sessionStateKey contextKey = iota
+	sessionIDKey
+	loadedSessionKey
+)
+
+//loadedSession is a session whose state a middleware, such as CSRF,
+//already read from the session store before EnsureAuth ran
+type loadedSession struct {
+	sid   sessions.SessionID
+	state *SessionState
+}
+
+//unauthorizedMsg is the response body sent for requests
+//without a valid session
+const unauthorizedMsg = "Please sign in."
+
+//EnsureAuth is a middleware that only passes the request on to
+//`handler` if it carries a valid SessionID with state in the session
+//store. The SessionState and SessionID are added to the request's
+//context, where `handler` can read them back using
+//SessionStateFromContext and SessionIDFromContext.
+//Requests without a valid session are answered with a 401, and
+//requests that fail because the session store is down with a 503.
+//Restricted sessions may only make safe requests, such as GETs,
+//until the SignInPolicy no longer restricts the user.
+//The session's CSRF token is sent in the X-CSRF-Token header,
+//so that clients can find it again after reloading.
+func (c *Context) EnsureAuth(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		sid, err := sessions.GetSessionID(r, c.SeshKey, c.transport())
+		if err != nil {
+			respondError(w, http.StatusUnauthorized, ErrCodeUnauthorized, unauthorizedMsg)
+			return
+		}
+		state, err := c.loadSession(r.Context(), sid)
+		if err != nil {
+			if err == sessions.ErrStateNotFound {
+				respondError(w, http.StatusUnauthorized, ErrCodeUnauthorized, unauthorizedMsg)
+			} else {
+				respondUnavailable(w, err)
+			}
+			return
+		}
+		if state.Restricted && !isSafeMethod(r.Method) {
+			lifted, err := c.liftRestriction(r.Context(), state)
+			if err != nil {
+				respondInternalError(w, err)
+				return
+			}
+			if !lifted {
+				respondError(w, http.StatusForbidden, ErrCodeEmailUnverified, unverifiedMsg)
+				return
+			}
+			if err := c.saveSession(r.Context(), sid, state); err != nil {
+				if err == sessions.ErrStateNotFound {
+					respondError(w, http.StatusUnauthorized, ErrCodeUnauthorized, unauthorizedMsg)
+				} else {
+					respondUnavailable(w, err)
+				}
+				return
+			}
+		}
+		if len(state.CSRFToken) > 0 {
+			w.Header().Set(headerCSRFToken, state.CSRFToken)
+		}
+		ctx := context.WithValue(r.Context(), sessionStateKey, state)
+		ctx = context.WithValue(ctx, sessionIDKey, sid)
+		handler(w, r.WithContext(ctx))
+	}
+}
+
+//withLoadedSession returns a copy of `ctx` recording that the state
+//of the session `sid` was read, so that EnsureAuth can reuse it
+func withLoadedSession(ctx context.Context, sid sessions.SessionID, state *SessionState) context.Context {
+	return context.WithValue(ctx, loadedSessionKey, &loadedSession{sid, state})
+}
+
+//loadSession returns the state of the session `sid`, reusing the
+//state recorded in `ctx` by withLoadedSession if there is one,
+//rather than reading it from the session store again
+func (c *Context) loadSession(ctx context.Context, sid sessions.SessionID) (*SessionState, error) {
+	if loaded, ok := ctx.Value(loadedSessionKey).(*loadedSession); ok && loaded.sid == sid {
+		return loaded.state, nil
+	}
+	state := &SessionState{}
+	if err := c.SeshStore.Get(ctx, sid, state); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+//SessionStateFromContext returns the SessionState added to `ctx`
+//by EnsureAuth, and false if there isn't one
+func SessionStateFromContext(ctx context.Context) (*SessionState, bool) {
+	state, ok := ctx.Value(sessionStateKey).(*SessionState)
+	return state, ok
+}
+
+//SessionIDFromContext returns the SessionID added to `ctx`
+//by EnsureAuth, and false if there isn't one
+func SessionIDFromContext(ctx context.Context) (sessions.SessionID, bool) {
+	sid, ok := ctx.Value(sessionIDKey).(sessions.SessionID)
+	return sid, ok
+}
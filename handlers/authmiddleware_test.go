@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"assignments-jelauria/servers/gateway/models/users"
+	"assignments-jelauria/servers/gateway/sessions"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestEnsureAuth(t *testing.T) {
+	ctx := &Context{
+		SeshKey:   sessions.SigningKey("test key"),
+		SeshStore: sessions.NewMemStore(time.Hour, time.Minute),
+	}
+	state := &SessionState{SeshStart: time.Now(), AuthUser: &users.User{ID: 1, UserName: "TheBlindBandit"}}
+	respRec := httptest.NewRecorder()
+	sid, err := sessions.BeginSession(context.Background(), ctx.SeshKey, sessions.DefaultTransport, ctx.SeshStore, state, respRec)
+	if err != nil {
+		t.Fatalf("error beginning session: %v", err)
+	}
+
+	cases := []struct {
+		name           string
+		authHeader     string
+		expectedStatus int
+	}{
+		{
+			"Valid Session",
+			"Bearer " + sid.String(),
+			http.StatusOK,
+		},
+		{
+			"No Session",
+			"",
+			http.StatusUnauthorized,
+		},
+		{
+			"Invalid Session",
+			"Bearer invalid",
+			http.StatusUnauthorized,
+		},
+	}
+
+	for _, c := range cases {
+		var gotState *SessionState
+		var gotID sessions.SessionID
+		handler := ctx.EnsureAuth(func(w http.ResponseWriter, r *http.Request) {
+			gotState, _ = SessionStateFromContext(r.Context())
+			gotID, _ = SessionIDFromContext(r.Context())
+		})
+
+		req, _ := http.NewRequest(http.MethodGet, "/v1/users/me", nil)
+		if len(c.authHeader) > 0 {
+			req.Header.Set("Authorization", c.authHeader)
+		}
+		resp := httptest.NewRecorder()
+		handler(resp, req)
+
+		if resp.Code != c.expectedStatus {
+			t.Errorf("case %s: incorrect status code: expected %d but got %d", c.name, c.expectedStatus, resp.Code)
+		}
+		if c.expectedStatus == http.StatusOK {
+			if gotState == nil || gotState.AuthUser == nil || gotState.AuthUser.ID != state.AuthUser.ID {
+				t.Errorf("case %s: session state was not added to the request context", c.name)
+			}
+			if gotID != sid {
+				t.Errorf("case %s: incorrect SessionID in request context: expected %s but got %s", c.name, sid, gotID)
+			}
+		} else if gotState != nil {
+			t.Errorf("case %s: wrapped handler should not have been called", c.name)
+		}
+	}
+}
+
+//downStore is a sessions.Store whose server can't be reached
+type downStore struct{}
+
+var errStoreDown = errors.New("connection refused")
+
+func (downStore) Save(ctx context.Context, sid sessions.SessionID, state interface{}) error {
+	return errStoreDown
+}
+
+func (downStore) Get(ctx context.Context, sid sessions.SessionID, state interface{}) error {
+	return errStoreDown
+}
+
+func (downStore) Delete(ctx context.Context, sid sessions.SessionID) error {
+	return errStoreDown
+}
+
+func TestEnsureAuthStoreDown(t *testing.T) {
+	ctx := &Context{SeshKey: sessions.SigningKey("test key"), SeshStore: downStore{}}
+	sid, err := ctx.SeshKey.NewSessionID()
+	if err != nil {
+		t.Fatalf("error generating SessionID: %v", err)
+	}
+
+	called := false
+	handler := ctx.EnsureAuth(func(w http.ResponseWriter, r *http.Request) { called = true })
+	req, _ := http.NewRequest(http.MethodGet, "/v1/users/me", nil)
+	req.Header.Set("Authorization", "Bearer "+sid.String())
+	resp := httptest.NewRecorder()
+	handler(resp, req)
+
+	//an outage must not look like the user was signed out
+	if resp.Code != http.StatusServiceUnavailable {
+		t.Errorf("incorrect status code: expected %d but got %d", http.StatusServiceUnavailable, resp.Code)
+	}
+	if called {
+		t.Errorf("wrapped handler should not have been called")
+	}
+
+	req, _ = http.NewRequest(http.MethodDelete, "/v1/sessions/mine", nil)
+	req.Header.Set("Authorization", "Bearer "+sid.String())
+	resp = httptest.NewRecorder()
+	ctx.SpecificSessionHandler(resp, req)
+	if resp.Code != http.StatusServiceUnavailable {
+		t.Errorf("incorrect status code when signing out: expected %d but got %d", http.StatusServiceUnavailable, resp.Code)
+	}
+}
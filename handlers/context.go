@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"assignments-jelauria/servers/gateway/mailer"
 	"assignments-jelauria/servers/gateway/models/users"
 	"assignments-jelauria/servers/gateway/sessions"
+	"time"
 )
 
 //TODO: define a handler context struct that
@@ -12,7 +14,48 @@ import (
 //and verifying SessionIDs, the session store
 //and the user store
 type Context struct {
-	SeshKey   string
-	SeshStore *sessions.Store
-	UserStore *users.Store
+	//SeshKey signs and validates SessionIDs. Use a
+	//sessions.Keyring to be able to rotate keys.
+	SeshKey   sessions.Signer
+	SeshStore sessions.Store
+	//SeshTransport carries SessionIDs to and from clients. It may
+	//be nil to use sessions.DefaultTransport, which uses the
+	//Authorization header.
+	SeshTransport sessions.Transport
+	//UserStore holds the user accounts. Handlers pass it the
+	//request's context, so wrap it with users.NewTimeoutStore
+	//to also bound how long each call may take.
+	UserStore users.Store
+	//ResetTokens holds the tokens emailed to users who forgot
+	//their password. Wrap it with users.NewTimeoutTokenStore to
+	//bound how long each call may take.
+	ResetTokens users.TokenStore
+	//TokenKey signs the tokens emailed to users
+	//to confirm a new email address
+	TokenKey string
+	//Mailer sends email to users
+	Mailer mailer.Sender
+	//SignInPolicy decides whether users may sign in, for
+	//example before verifying their email. It may be nil
+	//to allow everyone with the right credentials.
+	SignInPolicy SignInPolicy
+	//DeletionGracePeriod is how long deleted accounts are kept,
+	//during which signing in again restores them. Run a users.Purger
+	//with the same grace period to delete them afterwards. Accounts
+	//are deleted right away if it is zero.
+	DeletionGracePeriod time.Duration
+	//SudoDuration is how long after signing in or reauthenticating
+	//a session may make sensitive changes, such as to the user's
+	//email or password. Sessions are never asked to reauthenticate
+	//if it is zero.
+	SudoDuration time.Duration
+}
+
+//transport returns the SeshTransport, or sessions.DefaultTransport
+//if there isn't one
+func (c *Context) transport() sessions.Transport {
+	if c.SeshTransport == nil {
+		return sessions.DefaultTransport
+	}
+	return c.SeshTransport
 }
@@ -1,12 +1,100 @@
 package handlers
 
-/* TODO: implement a CORS middleware handler, as described
-in https://drstearns.github.io/tutorials/cors/ that responds
-with the following headers to all requests:
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
 
-  Access-Control-Allow-Origin: *
+const headerCORS = "Access-Control-Allow-Origin"
+const headerAllowMethods = "Access-Control-Allow-Methods"
+const headerAllowHeaders = "Access-Control-Allow-Headers"
+const headerExposeHeaders = "Access-Control-Expose-Headers"
+const headerMaxAge = "Access-Control-Max-Age"
+const headerAllowCredentials = "Access-Control-Allow-Credentials"
+const headerOrigin = "Origin"
+const headerVary = "Vary"
+
+const corsAnyOrig = "*"
+const corsMethods = "GET, PUT, POST, PATCH, DELETE"
+const corsHeaders = "Content-Type, Authorization, X-CSRF-Token"
+const corsExposedHeaders = "Authorization, X-CSRF-Token"
+
+//corsMaxAge is how long, in seconds, a browser may cache preflight results
+const corsMaxAge = 600
+
+/* CORS is a middleware handler, as described in
+https://drstearns.github.io/tutorials/cors/ that responds
+with the following headers to requests from allowed origins:
+
+  Access-Control-Allow-Origin: <origin, or * if any origin is allowed>
   Access-Control-Allow-Methods: GET, PUT, POST, PATCH, DELETE
-  Access-Control-Allow-Headers: Content-Type, Authorization
-  Access-Control-Expose-Headers: Authorization
+  Access-Control-Allow-Headers: Content-Type, Authorization, X-CSRF-Token
+  Access-Control-Expose-Headers: Authorization, X-CSRF-Token
   Access-Control-Max-Age: 600
+  Access-Control-Allow-Credentials: true (if AllowCredentials is set
+    and the origin is listed in AllowedOrigins)
+
+Preflight OPTIONS requests are answered directly and are
+not passed on to the wrapped handler.
 */
+type CORS struct {
+	Handler http.Handler
+	//AllowedOrigins lists the origins that may make cross-origin
+	//requests. If it is empty or contains "*", any origin is allowed.
+	AllowedOrigins []string
+	//AllowCredentials lets browsers send cookies with cross-origin
+	//requests, as needed when SessionIDs are kept in a cookie.
+	//Browsers only do so for origins listed in AllowedOrigins,
+	//never when any origin is allowed.
+	AllowCredentials bool
+}
+
+//NewCORS constructs a new CORS middleware handler wrapping `handler`
+//that allows requests from the `allowedOrigins`
+func NewCORS(handler http.Handler, allowedOrigins ...string) *CORS {
+	return &CORS{Handler: handler, AllowedOrigins: allowedOrigins}
+}
+
+//ServeHTTP adds the CORS headers and either answers the preflight
+//request or passes the request on to the wrapped handler
+func (c *CORS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	allowOrig := c.allowedOrigin(r.Header.Get(headerOrigin))
+	if allowOrig != corsAnyOrig {
+		//the response depends on the Origin, so caches must key on it
+		w.Header().Add(headerVary, headerOrigin)
+	}
+	if len(allowOrig) > 0 {
+		w.Header().Set(headerCORS, allowOrig)
+		w.Header().Set(headerAllowMethods, corsMethods)
+		w.Header().Set(headerAllowHeaders, corsHeaders)
+		w.Header().Set(headerExposeHeaders, corsExposedHeaders)
+		w.Header().Set(headerMaxAge, strconv.Itoa(corsMaxAge))
+		if c.AllowCredentials && allowOrig != corsAnyOrig {
+			w.Header().Set(headerAllowCredentials, "true")
+		}
+	}
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	c.Handler.ServeHTTP(w, r)
+}
+
+//allowedOrigin returns the value to use for the Access-Control-Allow-Origin
+//header given the request's `origin`, or an empty string if the origin
+//is not allowed
+func (c *CORS) allowedOrigin(origin string) string {
+	if len(c.AllowedOrigins) == 0 {
+		return corsAnyOrig
+	}
+	for _, o := range c.AllowedOrigins {
+		if o == corsAnyOrig {
+			return corsAnyOrig
+		}
+		if len(origin) > 0 && strings.EqualFold(o, origin) {
+			return origin
+		}
+	}
+	return ""
+}
@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCORS(t *testing.T) {
+	cases := []struct {
+		name           string
+		allowedOrigins []string
+		method         string
+		origin         string
+		expectedOrigin string
+		expectHandler  bool
+	}{
+		{
+			"Any Origin",
+			nil,
+			http.MethodGet,
+			"https://example.com",
+			corsAnyOrig,
+			true,
+		},
+		{
+			"Wildcard In Allowlist",
+			[]string{"https://other.com", corsAnyOrig},
+			http.MethodGet,
+			"https://example.com",
+			corsAnyOrig,
+			true,
+		},
+		{
+			"Allowed Origin",
+			[]string{"https://example.com"},
+			http.MethodGet,
+			"https://example.com",
+			"https://example.com",
+			true,
+		},
+		{
+			"Disallowed Origin",
+			[]string{"https://example.com"},
+			http.MethodGet,
+			"https://evil.com",
+			"",
+			true,
+		},
+		{
+			"Preflight Request",
+			[]string{"https://example.com"},
+			http.MethodOptions,
+			"https://example.com",
+			"https://example.com",
+			false,
+		},
+	}
+
+	for _, c := range cases {
+		handlerCalled := false
+		handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handlerCalled = true
+		})
+		cors := NewCORS(handler, c.allowedOrigins...)
+
+		req, _ := http.NewRequest(c.method, "/v1/users", nil)
+		req.Header.Set(headerOrigin, c.origin)
+		resp := httptest.NewRecorder()
+		cors.ServeHTTP(resp, req)
+
+		if resp.Code != http.StatusOK {
+			t.Errorf("case %s: incorrect status code: expected %d but got %d", c.name, http.StatusOK, resp.Code)
+		}
+		if origin := resp.Header().Get(headerCORS); origin != c.expectedOrigin {
+			t.Errorf("case %s: incorrect %s header: expected `%s` but got `%s`", c.name, headerCORS, c.expectedOrigin, origin)
+		}
+		if handlerCalled != c.expectHandler {
+			t.Errorf("case %s: wrapped handler called: expected %t but got %t", c.name, c.expectHandler, handlerCalled)
+		}
+		if len(c.expectedOrigin) > 0 {
+			if exposed := resp.Header().Get(headerExposeHeaders); exposed != corsExposedHeaders {
+				t.Errorf("case %s: incorrect %s header: expected `%s` but got `%s`", c.name, headerExposeHeaders, corsExposedHeaders, exposed)
+			}
+			if maxAge := resp.Header().Get(headerMaxAge); maxAge != "600" {
+				t.Errorf("case %s: incorrect %s header: expected `600` but got `%s`", c.name, headerMaxAge, maxAge)
+			}
+		}
+	}
+}
+
+func TestCORSAllowCredentials(t *testing.T) {
+	cases := []struct {
+		name              string
+		allowedOrigins    []string
+		origin            string
+		expectCredentials bool
+	}{
+		{
+			"Allowed Origin",
+			[]string{"https://example.com"},
+			"https://example.com",
+			true,
+		},
+		{
+			"Any Origin",
+			nil,
+			"https://example.com",
+			false,
+		},
+		{
+			"Disallowed Origin",
+			[]string{"https://example.com"},
+			"https://evil.com",
+			false,
+		},
+	}
+
+	for _, c := range cases {
+		cors := NewCORS(http.NotFoundHandler(), c.allowedOrigins...)
+		cors.AllowCredentials = true
+
+		req, _ := http.NewRequest(http.MethodOptions, "/v1/users", nil)
+		req.Header.Set(headerOrigin, c.origin)
+		resp := httptest.NewRecorder()
+		cors.ServeHTTP(resp, req)
+
+		if allowed := resp.Header().Get(headerAllowCredentials) == "true"; allowed != c.expectCredentials {
+			t.Errorf("case %s: incorrect %s header: expected %t but got %t", c.name, headerAllowCredentials, c.expectCredentials, allowed)
+		}
+	}
+}
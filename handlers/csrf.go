@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"assignments-jelauria/servers/gateway/sessions"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const headerCSRFToken = "X-CSRF-Token"
+const headerReferer = "Referer"
+
+//csrfTokenLength is the number of random bytes in a CSRF token
+const csrfTokenLength = 32
+
+//csrfOriginMsg and csrfTokenMsg are the response bodies sent for
+//requests that fail the origin and token checks
+const csrfOriginMsg = "Requests from this origin are not allowed."
+const csrfTokenMsg = "Missing or invalid CSRF token."
+
+//CSRF is a middleware handler that protects sessions kept in cookies
+//from cross-site request forgery. Browsers send cookies with requests
+//that other sites make, so every unsafe request, such as a POST, must
+//pass two checks before it is passed on to the wrapped handler:
+//
+//1. If the request has an Origin header, or else a Referer header, it
+//   must be from the server's own origin or one of TrustedOrigins.
+//   Browsers send an Origin with every cross-origin POST, PATCH and
+//   DELETE, while other clients usually send neither.
+//2. If the request's SessionID is read from a cookie, the request must
+//   have an X-CSRF-Token header matching the token in its SessionState.
+//   Other sites can't read the token, since it is only sent in the
+//   X-CSRF-Token response header when signing in and with each request
+//   that passes EnsureAuth.
+//
+//Deployments that don't keep SessionIDs in cookies don't need it.
+type CSRF struct {
+	Handler http.Handler
+	Context *Context
+	//TrustedOrigins lists the origins, such as "https://example.com",
+	//other than the server's own that may make unsafe requests
+	TrustedOrigins []string
+}
+
+//NewCSRF constructs a new CSRF middleware handler wrapping `handler`
+//that reads sessions using `ctx` and trusts the `trustedOrigins`
+func NewCSRF(handler http.Handler, ctx *Context, trustedOrigins ...string) *CSRF {
+	return &CSRF{Handler: handler, Context: ctx, TrustedOrigins: trustedOrigins}
+}
+
+//ServeHTTP checks unsafe requests and passes those that
+//pass the checks on to the wrapped handler
+func (c *CSRF) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if isSafeMethod(r.Method) {
+		c.Handler.ServeHTTP(w, r)
+		return
+	}
+	if !c.trustedSource(r) {
+		respondError(w, http.StatusForbidden, ErrCodeCSRF, csrfOriginMsg)
+		return
+	}
+	if readsCookie(c.Context.transport(), r) {
+		//requests without a valid session are left to the wrapped
+		//handler, which has nothing to protect for them
+		sid, err := sessions.GetSessionID(r, c.Context.SeshKey, c.Context.transport())
+		if err == nil {
+			state, err := c.Context.loadSession(r.Context(), sid)
+			if err != nil && err != sessions.ErrStateNotFound {
+				respondUnavailable(w, err)
+				return
+			}
+			if err == nil {
+				if !validCSRFToken(state, r.Header.Get(headerCSRFToken)) {
+					respondError(w, http.StatusForbidden, ErrCodeCSRF, csrfTokenMsg)
+					return
+				}
+				//pass the state on, so that EnsureAuth doesn't
+				//read the session from the store a second time
+				r = r.WithContext(withLoadedSession(r.Context(), sid, state))
+			}
+		}
+	}
+	c.Handler.ServeHTTP(w, r)
+}
+
+//trustedSource reports whether the request's Origin, or Referer if
+//there is no Origin, is the server's own origin or a trusted one.
+//Requests with neither header are trusted, since browsers may strip
+//the Referer and API clients send neither. A forged request from a
+//browser that sends neither is still rejected by the token check in
+//ServeHTTP when it carries a session cookie, and has no session to
+//act on when it doesn't.
+func (c *CSRF) trustedSource(r *http.Request) bool {
+	source := r.Header.Get(headerOrigin)
+	if len(source) == 0 {
+		source = r.Header.Get(headerReferer)
+	}
+	if len(source) == 0 {
+		return true
+	}
+	u, err := url.Parse(source)
+	if err != nil || len(u.Scheme) == 0 || len(u.Host) == 0 {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	origin := u.Scheme + "://" + u.Host
+	for _, o := range c.TrustedOrigins {
+		if strings.EqualFold(o, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+//readsCookie reports whether `transport` reads the request's
+//SessionID from a cookie, rather than from a header or parameter
+//that other sites can't make browsers send
+func readsCookie(transport sessions.Transport, r *http.Request) bool {
+	switch t := transport.(type) {
+	case *sessions.CookieTransport:
+		_, err := t.Read(r)
+		return err == nil
+	case sessions.Transports:
+		for _, each := range t {
+			if _, err := each.Read(r); err != sessions.ErrNoSessionID {
+				return readsCookie(each, r)
+			}
+		}
+	}
+	return false
+}
+
+//newCSRFToken returns a new random CSRF token. An error is returned
+//only if there was an error generating random bytes.
+func newCSRFToken() (string, error) {
+	buf := make([]byte, csrfTokenLength)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+//validCSRFToken reports whether `token` matches the CSRF token of the
+//session. Sessions begun before tokens were added have none, and so
+//must sign in again to make unsafe requests.
+func validCSRFToken(state *SessionState, token string) bool {
+	return len(state.CSRFToken) > 0 &&
+		subtle.ConstantTimeCompare([]byte(state.CSRFToken), []byte(token)) == 1
+}
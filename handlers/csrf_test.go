@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"assignments-jelauria/servers/gateway/models/users"
+	"assignments-jelauria/servers/gateway/sessions"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCSRF(t *testing.T) {
+	cookie := sessions.NewCookieTransport("sid")
+	ctx := &Context{
+		SeshKey:       sessions.SigningKey("test key"),
+		SeshStore:     sessions.NewMemStore(time.Hour, time.Minute),
+		SeshTransport: sessions.Transports{sessions.HeaderTransport{}, cookie},
+	}
+	user := &users.User{ID: 1, UserName: "TheBlindBandit"}
+	respRec := httptest.NewRecorder()
+	sid, err := ctx.beginSession(context.Background(), user, respRec)
+	if err != nil {
+		t.Fatalf("error beginning session: %v", err)
+	}
+	token := respRec.Header().Get(headerCSRFToken)
+	if len(token) == 0 {
+		t.Fatalf("no CSRF token was sent when beginning the session")
+	}
+
+	cases := []struct {
+		name           string
+		method         string
+		origin         string
+		referer        string
+		cookie         string
+		bearer         string
+		csrfToken      string
+		expectedStatus int
+	}{
+		{
+			"Safe Method Without Token",
+			http.MethodGet,
+			"https://evil.com",
+			"",
+			sid.String(),
+			"",
+			"",
+			http.StatusOK,
+		},
+		{
+			"Cookie Session With Token",
+			http.MethodPatch,
+			"https://example.com",
+			"",
+			sid.String(),
+			"",
+			token,
+			http.StatusOK,
+		},
+		{
+			"Cookie Session Without Token",
+			http.MethodPatch,
+			"https://example.com",
+			"",
+			sid.String(),
+			"",
+			"",
+			http.StatusForbidden,
+		},
+		{
+			"Cookie Session With Wrong Token",
+			http.MethodDelete,
+			"",
+			"",
+			sid.String(),
+			"",
+			token + "x",
+			http.StatusForbidden,
+		},
+		{
+			"Cookie Session Without Origin Or Token",
+			http.MethodPost,
+			"",
+			"",
+			sid.String(),
+			"",
+			"",
+			http.StatusForbidden,
+		},
+		{
+			"Bearer Session Without Token",
+			http.MethodPatch,
+			"",
+			"",
+			"",
+			"Bearer " + sid.String(),
+			"",
+			http.StatusOK,
+		},
+		{
+			"Expired Cookie Session",
+			http.MethodPost,
+			"",
+			"",
+			"expired",
+			"",
+			"",
+			http.StatusOK,
+		},
+		{
+			"No Session",
+			http.MethodPost,
+			"https://example.com",
+			"",
+			"",
+			"",
+			"",
+			http.StatusOK,
+		},
+		{
+			"Cross-Site Origin",
+			http.MethodPost,
+			"https://evil.com",
+			"",
+			sid.String(),
+			"",
+			token,
+			http.StatusForbidden,
+		},
+		{
+			"Cross-Site Origin Without Session",
+			http.MethodPost,
+			"https://evil.com",
+			"",
+			"",
+			"",
+			"",
+			http.StatusForbidden,
+		},
+		{
+			"Null Origin",
+			http.MethodPost,
+			"null",
+			"",
+			"",
+			"",
+			"",
+			http.StatusForbidden,
+		},
+		{
+			"Trusted Origin",
+			http.MethodPost,
+			"https://app.example.com",
+			"",
+			sid.String(),
+			"",
+			token,
+			http.StatusOK,
+		},
+		{
+			"Cross-Site Referer",
+			http.MethodPost,
+			"",
+			"https://evil.com/attack.html",
+			"",
+			"",
+			"",
+			http.StatusForbidden,
+		},
+		{
+			"Same-Site Referer",
+			http.MethodPost,
+			"",
+			"https://example.com/account",
+			"",
+			"",
+			"",
+			http.StatusOK,
+		},
+	}
+
+	for _, c := range cases {
+		handlerCalled := false
+		csrf := NewCSRF(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handlerCalled = true
+		}), ctx, "https://app.example.com")
+
+		req := httptest.NewRequest(c.method, "https://example.com/v1/users/me", nil)
+		if len(c.origin) > 0 {
+			req.Header.Set(headerOrigin, c.origin)
+		}
+		if len(c.referer) > 0 {
+			req.Header.Set(headerReferer, c.referer)
+		}
+		if len(c.cookie) > 0 {
+			req.AddCookie(&http.Cookie{Name: cookie.Name, Value: c.cookie})
+		}
+		if len(c.bearer) > 0 {
+			req.Header.Set("Authorization", c.bearer)
+		}
+		if len(c.csrfToken) > 0 {
+			req.Header.Set(headerCSRFToken, c.csrfToken)
+		}
+		resp := httptest.NewRecorder()
+		csrf.ServeHTTP(resp, req)
+
+		if resp.Code != c.expectedStatus {
+			t.Errorf("case %s: incorrect status code: expected %d but got %d", c.name, c.expectedStatus, resp.Code)
+		}
+		if handlerCalled != (c.expectedStatus == http.StatusOK) {
+			t.Errorf("case %s: handler called should be %t but was %t", c.name, c.expectedStatus == http.StatusOK, handlerCalled)
+		}
+		if c.expectedStatus == http.StatusForbidden {
+			errResp := &ErrorResponse{}
+			if err := json.NewDecoder(resp.Body).Decode(errResp); err != nil || errResp.Code != ErrCodeCSRF {
+				t.Errorf("case %s: incorrect error response: %v %+v", c.name, err, errResp)
+			}
+		}
+	}
+}
+
+//countingStore counts the calls to Get of the sessions.Store it wraps
+type countingStore struct {
+	sessions.Store
+	gets int
+}
+
+func (cs *countingStore) Get(ctx context.Context, sid sessions.SessionID, state interface{}) error {
+	cs.gets++
+	return cs.Store.Get(ctx, sid, state)
+}
+
+func TestCSRFReusesSession(t *testing.T) {
+	cookie := sessions.NewCookieTransport("sid")
+	store := &countingStore{Store: sessions.NewMemStore(time.Hour, time.Minute)}
+	ctx := &Context{
+		SeshKey:       sessions.SigningKey("test key"),
+		SeshStore:     store,
+		SeshTransport: cookie,
+	}
+	respRec := httptest.NewRecorder()
+	sid, err := ctx.beginSession(context.Background(), &users.User{ID: 1}, respRec)
+	if err != nil {
+		t.Fatalf("error beginning session: %v", err)
+	}
+
+	handlerCalled := false
+	csrf := NewCSRF(ctx.EnsureAuth(func(w http.ResponseWriter, r *http.Request) {
+		handlerCalled = true
+	}), ctx)
+	req := httptest.NewRequest(http.MethodPost, "https://example.com/v1/users/me", nil)
+	req.AddCookie(&http.Cookie{Name: cookie.Name, Value: sid.String()})
+	req.Header.Set(headerCSRFToken, respRec.Header().Get(headerCSRFToken))
+	resp := httptest.NewRecorder()
+	csrf.ServeHTTP(resp, req)
+	if !handlerCalled {
+		t.Fatalf("handler was not called: status %d", resp.Code)
+	}
+	if store.gets != 1 {
+		t.Errorf("incorrect number of session store reads: expected 1 but got %d", store.gets)
+	}
+}
+
+func TestEnsureAuthSendsCSRFToken(t *testing.T) {
+	ctx := &Context{
+		SeshKey:   sessions.SigningKey("test key"),
+		SeshStore: sessions.NewMemStore(time.Hour, time.Minute),
+	}
+	sid, err := ctx.beginSession(context.Background(), &users.User{ID: 1}, httptest.NewRecorder())
+	if err != nil {
+		t.Fatalf("error beginning session: %v", err)
+	}
+	state := &SessionState{}
+	if err := ctx.SeshStore.Get(context.Background(), sid, state); err != nil {
+		t.Fatalf("error getting session state: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/users/me", nil)
+	req.Header.Set("Authorization", "Bearer "+sid.String())
+	resp := httptest.NewRecorder()
+	ctx.EnsureAuth(func(w http.ResponseWriter, r *http.Request) {})(resp, req)
+	if token := resp.Header().Get(headerCSRFToken); token != state.CSRFToken {
+		t.Errorf("incorrect %s header: expected %q but got %q", headerCSRFToken, state.CSRFToken, token)
+	}
+}
@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"assignments-jelauria/servers/gateway/mailer"
+	"assignments-jelauria/servers/gateway/models/users"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//emailTokenTTL is how long a token confirming an email address can be used
+const emailTokenTTL = 24 * time.Hour
+
+//VerificationHandler verifies the email addresses of new users.
+//A signed token is emailed to each user when they sign up, and
+//POST requests here carry that token, marking the address verified.
+func (c *Context) VerificationHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			respondError(w, http.StatusUnsupportedMediaType, ErrCodeUnsupportedMediaType, "Request body must be in JSON.")
+			return
+		}
+		var confirmation users.EmailConfirmation
+		if err := json.NewDecoder(r.Body).Decode(&confirmation); err != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeBadRequest, "Request body is not valid JSON.")
+			return
+		}
+		claims, err := users.VerifyEmailToken([]byte(c.TokenKey), users.PurposeVerifyEmail, confirmation.Token)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidToken, "Verification token is invalid or has expired.")
+			return
+		}
+		store, ok := c.UserStore.(users.VerificationStore)
+		if !ok {
+			respondNotSupported(w)
+			return
+		}
+		user, err := store.VerifyEmail(r.Context(), claims.UserID, claims.Email)
+		if err == users.ErrInvalidToken || err == users.ErrUserNotFound {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidToken, "Verification token is invalid or has expired.")
+			return
+		}
+		if err != nil {
+			respondInternalError(w, err)
+			return
+		}
+		respondJSON(w, http.StatusOK, user)
+		return
+	}
+	respondMethodNotAllowed(w)
+	return
+}
+
+//EmailChangesHandler confirms changes to users' email addresses.
+//Changes are asked for by sending a new email to PATCH /v1/users/me,
+//which emails a signed token to the new address. PATCH requests
+//here carry that token, and make the new address the user's email.
+func (c *Context) EmailChangesHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPatch {
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			respondError(w, http.StatusUnsupportedMediaType, ErrCodeUnsupportedMediaType, "Request body must be in JSON.")
+			return
+		}
+		var confirmation users.EmailConfirmation
+		if err := json.NewDecoder(r.Body).Decode(&confirmation); err != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeBadRequest, "Request body is not valid JSON.")
+			return
+		}
+		claims, err := users.VerifyEmailToken([]byte(c.TokenKey), users.PurposeChangeEmail, confirmation.Token)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidToken, "Email token is invalid or has expired.")
+			return
+		}
+		store, ok := c.UserStore.(users.EmailChangeStore)
+		if !ok {
+			respondNotSupported(w)
+			return
+		}
+		user, err := store.ConfirmEmail(r.Context(), claims.UserID, claims.Email)
+		if err == users.ErrNoPendingEmail || err == users.ErrUserNotFound {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidToken, "Email token is invalid or has expired.")
+			return
+		}
+		if err != nil {
+			respondConflict(w, err)
+			return
+		}
+		respondJSON(w, http.StatusOK, user)
+		return
+	}
+	respondMethodNotAllowed(w)
+	return
+}
+
+//sendVerificationToken emails a token to the user
+//that verifies they can receive email at their address
+func (c *Context) sendVerificationToken(user *users.User) error {
+	token, err := users.SignEmailToken([]byte(c.TokenKey), users.PurposeVerifyEmail, user.ID, user.Email, emailTokenTTL)
+	if err != nil {
+		return err
+	}
+	return c.Mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Use this code to verify your email address: %s\n\n"+
+			"It expires in %v. If you didn't sign up, you can ignore this email.",
+			token, emailTokenTTL),
+	})
+}
+
+//checkEmailChange returns the error requestEmailChange would return
+//before changing anything, for when the user with ID `userID` asks
+//to change their email to `email`.
+func (c *Context) checkEmailChange(ctx context.Context, userID int64, email string) error {
+	if _, ok := c.UserStore.(users.EmailChangeStore); !ok {
+		return users.ErrNotSupported
+	}
+	other, err := c.UserStore.GetByEmail(ctx, email)
+	if err == nil && other.ID != userID {
+		return users.ErrEmailTaken
+	}
+	if err != nil && err != users.ErrUserNotFound {
+		return err
+	}
+	return nil
+}
+
+//requestEmailChange saves `email` as the pending email of the user,
+//and emails a token to that address that confirms the change.
+//ErrEmailTaken is returned if another user already has the address,
+//and ErrNotSupported if the user store can't change email addresses.
+func (c *Context) requestEmailChange(ctx context.Context, user *users.User, email string) error {
+	if err := c.checkEmailChange(ctx, user.ID, email); err != nil {
+		return err
+	}
+	if err := c.UserStore.(users.EmailChangeStore).SetPendingEmail(ctx, user.ID, email); err != nil {
+		return err
+	}
+	token, err := users.SignEmailToken([]byte(c.TokenKey), users.PurposeChangeEmail, user.ID, email, emailTokenTTL)
+	if err != nil {
+		return err
+	}
+	return c.Mailer.Send(&mailer.Message{
+		To:      email,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Use this code to confirm your new email address: %s\n\n"+
+			"It expires in %v. If you didn't ask to change your email address, you can ignore this email.",
+			token, emailTokenTTL),
+	})
+}
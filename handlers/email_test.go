@@ -0,0 +1,219 @@
+package handlers
+
+import (
+	"assignments-jelauria/servers/gateway/mailer"
+	"assignments-jelauria/servers/gateway/models/users"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+//emailTokenPattern finds the token in an email change message
+var emailTokenPattern = regexp.MustCompile(`confirm your new email address: (\S+)`)
+
+func TestEmailChange(t *testing.T) {
+	ctx, user := newTestContext(t)
+	sent := &bytes.Buffer{}
+	ctx.Mailer = mailer.NewLogSender(sent)
+	if _, err := ctx.UserStore.Insert(context.Background(), &users.User{Email: "aang@test.com", UserName: "Aang"}); err != nil {
+		t.Fatalf("error inserting user: %v", err)
+	}
+	sid := newTestSession(t, ctx, user)
+	patchMe := func(body string) int {
+		req, _ := http.NewRequest(http.MethodPatch, "/v1/users/me", strings.NewReader(body))
+		req.Header.Set(headerContentType, contentTypeJSON)
+		req.Header.Set("Authorization", "Bearer "+sid.String())
+		resp := httptest.NewRecorder()
+		ctx.EnsureAuth(ctx.SpecificUsersHandler)(resp, req)
+		return resp.Code
+	}
+	confirm := func(token string) int {
+		req, _ := http.NewRequest(http.MethodPatch, "/v1/emailchanges", strings.NewReader(`{"token": "`+token+`"}`))
+		req.Header.Set(headerContentType, contentTypeJSON)
+		resp := httptest.NewRecorder()
+		ctx.EmailChangesHandler(resp, req)
+		return resp.Code
+	}
+
+	//sending the current email along with other changes isn't
+	//an email change, so needs neither sudo mode nor a token
+	ctx.SudoDuration = time.Nanosecond
+	if status := patchMe(`{"email": "toph@test.com", "firstName": "T"}`); status != http.StatusOK {
+		t.Errorf("incorrect status code sending the current email: expected %d but got %d", http.StatusOK, status)
+	}
+	ctx.SudoDuration = 0
+	if stored, _ := ctx.UserStore.GetByID(context.Background(), user.ID); stored.FirstName != "T" || len(stored.PendingEmail) > 0 {
+		t.Errorf("current email should not be pending: %+v", stored)
+	}
+	if sent.Len() > 0 {
+		t.Errorf("a token was emailed for the current email:\n%s", sent.String())
+	}
+
+	if status := patchMe(`{"email": "aang@test.com"}`); status != http.StatusConflict {
+		t.Errorf("incorrect status code changing to a taken email: expected %d but got %d", http.StatusConflict, status)
+	}
+	//a new email sent with a taken user name shouldn't be
+	//recorded or mailed, since the update as a whole failed
+	if status := patchMe(`{"email": "melty@test.com", "userName": "Aang"}`); status != http.StatusConflict {
+		t.Errorf("incorrect status code changing email with a taken user name: expected %d but got %d", http.StatusConflict, status)
+	}
+	if stored, _ := ctx.UserStore.GetByID(context.Background(), user.ID); len(stored.PendingEmail) > 0 {
+		t.Errorf("email change was recorded for a failed update: %+v", stored)
+	}
+	if sent.Len() > 0 {
+		t.Errorf("a token was emailed for a failed update:\n%s", sent.String())
+	}
+	if status := patchMe(`{"email": "melty@test.com", "firstName": "Melty"}`); status != http.StatusOK {
+		t.Fatalf("incorrect status code changing email: expected %d but got %d", http.StatusOK, status)
+	}
+	stored, _ := ctx.UserStore.GetByID(context.Background(), user.ID)
+	if stored.Email != user.Email || stored.PendingEmail != "melty@test.com" || stored.FirstName != "Melty" {
+		t.Errorf("email should be pending until it is confirmed: %+v", stored)
+	}
+	match := emailTokenPattern.FindStringSubmatch(sent.String())
+	if match == nil || !strings.Contains(sent.String(), "To: melty@test.com") {
+		t.Fatalf("token was not emailed to the new address:\n%s", sent.String())
+	}
+
+	if status := confirm("not a token"); status != http.StatusBadRequest {
+		t.Errorf("incorrect status code confirming with an invalid token: expected %d but got %d", http.StatusBadRequest, status)
+	}
+	if status := confirm(match[1]); status != http.StatusOK {
+		t.Fatalf("incorrect status code confirming email: expected %d but got %d", http.StatusOK, status)
+	}
+	stored, _ = ctx.UserStore.GetByID(context.Background(), user.ID)
+	if stored.Email != "melty@test.com" || len(stored.PendingEmail) > 0 || stored.PhotoURL == user.PhotoURL {
+		t.Errorf("email and photo were not changed: %+v", stored)
+	}
+	if status := confirm(match[1]); status != http.StatusBadRequest {
+		t.Errorf("incorrect status code reusing a token: expected %d but got %d", http.StatusBadRequest, status)
+	}
+}
+
+//plainUserStore hides the optional capabilities of the users.Store it wraps
+type plainUserStore struct {
+	users.Store
+}
+
+func TestEmailNotSupported(t *testing.T) {
+	ctx, user := newTestContext(t)
+	ctx.UserStore = plainUserStore{ctx.UserStore}
+	sid := newTestSession(t, ctx, user)
+	changeToken, err := users.SignEmailToken([]byte(ctx.TokenKey), users.PurposeChangeEmail, user.ID, "melty@test.com", time.Hour)
+	if err != nil {
+		t.Fatalf("error signing token: %v", err)
+	}
+	verifyToken, err := users.SignEmailToken([]byte(ctx.TokenKey), users.PurposeVerifyEmail, user.ID, user.Email, time.Hour)
+	if err != nil {
+		t.Fatalf("error signing token: %v", err)
+	}
+
+	cases := []struct {
+		name    string
+		method  string
+		path    string
+		body    string
+		handler http.HandlerFunc
+	}{
+		{
+			"Change Email",
+			http.MethodPatch,
+			"/v1/users/me",
+			`{"email": "melty@test.com"}`,
+			ctx.EnsureAuth(ctx.SpecificUsersHandler),
+		},
+		{
+			"Confirm Email",
+			http.MethodPatch,
+			"/v1/emailchanges",
+			`{"token": "` + changeToken + `"}`,
+			ctx.EmailChangesHandler,
+		},
+		{
+			"Verify Email",
+			http.MethodPost,
+			"/v1/users/verify",
+			`{"token": "` + verifyToken + `"}`,
+			ctx.VerificationHandler,
+		},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest(c.method, c.path, strings.NewReader(c.body))
+		req.Header.Set(headerContentType, contentTypeJSON)
+		req.Header.Set("Authorization", "Bearer "+sid.String())
+		resp := httptest.NewRecorder()
+		c.handler(resp, req)
+		if resp.Code != http.StatusNotImplemented {
+			t.Errorf("case %s: incorrect status code: expected %d but got %d", c.name, http.StatusNotImplemented, resp.Code)
+		}
+	}
+}
+
+//verifyTokenPattern finds the token in an email verification message
+var verifyTokenPattern = regexp.MustCompile(`verify your email address: (\S+)`)
+
+func TestVerificationHandler(t *testing.T) {
+	ctx, _ := newTestContext(t)
+	sent := &bytes.Buffer{}
+	ctx.Mailer = mailer.NewLogSender(sent)
+	req, _ := http.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(
+		`{"email": "aang@test.com", "password": "AvatarAang!", "passwordConf": "AvatarAang!", "userName": "Aang"}`))
+	req.Header.Set(headerContentType, contentTypeJSON)
+	resp := httptest.NewRecorder()
+	ctx.UsersHandler(resp, req)
+	if resp.Code != http.StatusCreated {
+		t.Fatalf("incorrect status code signing up: expected %d but got %d", http.StatusCreated, resp.Code)
+	}
+	match := verifyTokenPattern.FindStringSubmatch(sent.String())
+	if match == nil || !strings.Contains(sent.String(), "To: aang@test.com") {
+		t.Fatalf("verification token was not emailed to the new user:\n%s", sent.String())
+	}
+	aang, _ := ctx.UserStore.GetByEmail(context.Background(), "aang@test.com")
+	changeToken, _ := users.SignEmailToken([]byte(ctx.TokenKey), users.PurposeChangeEmail, aang.ID, aang.Email, time.Hour)
+
+	cases := []struct {
+		name           string
+		token          string
+		expectedStatus int
+	}{
+		{
+			"Invalid Token",
+			"not a token",
+			http.StatusBadRequest,
+		},
+		{
+			"Email Change Token",
+			changeToken,
+			http.StatusBadRequest,
+		},
+		{
+			"Valid Token",
+			match[1],
+			http.StatusOK,
+		},
+		{
+			"Already Verified",
+			match[1],
+			http.StatusOK,
+		},
+	}
+
+	for _, c := range cases {
+		req, _ := http.NewRequest(http.MethodPost, "/v1/users/verify", strings.NewReader(`{"token": "`+c.token+`"}`))
+		req.Header.Set(headerContentType, contentTypeJSON)
+		resp := httptest.NewRecorder()
+		ctx.VerificationHandler(resp, req)
+		if resp.Code != c.expectedStatus {
+			t.Errorf("case %s: incorrect status code: expected %d but got %d", c.name, c.expectedStatus, resp.Code)
+		}
+		stored, _ := ctx.UserStore.GetByID(context.Background(), aang.ID)
+		if stored.EmailVerified != (c.expectedStatus == http.StatusOK) {
+			t.Errorf("case %s: incorrect verified flag: %v", c.name, stored.EmailVerified)
+		}
+	}
+}
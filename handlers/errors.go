@@ -0,0 +1,143 @@
+package handlers
+
+import (
+	"assignments-jelauria/servers/gateway/models/users"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+)
+
+const headerContentType = "Content-Type"
+const contentTypeJSON = "application/json"
+
+//Error codes sent in the `code` field of an ErrorResponse.
+//Clients should switch on these rather than on the message.
+const (
+	ErrCodeBadRequest           = "bad_request"
+	ErrCodeUnsupportedMediaType = "unsupported_media_type"
+	ErrCodeValidation           = "validation_failed"
+	ErrCodeInvalidCredentials   = "invalid_credentials"
+	ErrCodeUnauthorized         = "unauthorized"
+	ErrCodeForbidden            = "forbidden"
+	ErrCodeEmailUnverified      = "email_unverified"
+	ErrCodeCSRF                 = "csrf_failed"
+	ErrCodeReauthRequired       = "reauthentication_required"
+	ErrCodeNotFound             = "not_found"
+	ErrCodeConflict             = "conflict"
+	ErrCodeMethodNotAllowed     = "method_not_allowed"
+	ErrCodeNotImplemented       = "not_implemented"
+	ErrCodeInternal             = "internal_error"
+	ErrCodeUnavailable          = "service_unavailable"
+)
+
+//Field error codes sent with an ErrCodeConflict response
+const (
+	FieldCodeEmailTaken    = "email_taken"
+	FieldCodeUserNameTaken = "username_taken"
+)
+
+//ErrorResponse is the JSON body sent with every error response
+type ErrorResponse struct {
+	//Code is a machine-readable error code, one of the ErrCode constants
+	Code string `json:"code"`
+	//Message is a human-readable description of the error
+	Message string `json:"message"`
+	//Fields describes problems with individual request fields,
+	//such as those found when validating a new user
+	Fields []*FieldError `json:"fields,omitempty"`
+}
+
+//FieldError describes a problem with one field of a request body
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+//respondJSON writes `value` to the response as JSON with the given status code
+func respondJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set(headerContentType, contentTypeJSON)
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(value); err != nil {
+		log.Printf("error encoding response: %v", err)
+	}
+}
+
+//respondError writes an ErrorResponse with the given status, code and message
+func respondError(w http.ResponseWriter, status int, code string, message string) {
+	respondJSON(w, status, &ErrorResponse{Code: code, Message: message})
+}
+
+//respondInternalError logs `err` and writes a generic internal error
+//response, so that details of the failure are not leaked to the client.
+//If a store gave up because its deadline passed, the response says
+//the service is unavailable instead, so the client can try again, and
+//if the user store lacks a capability the response says so.
+func respondInternalError(w http.ResponseWriter, err error) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		respondUnavailable(w, err)
+		return
+	}
+	if errors.Is(err, users.ErrNotSupported) {
+		respondNotSupported(w)
+		return
+	}
+	log.Printf("internal error: %v", err)
+	respondError(w, http.StatusInternalServerError, ErrCodeInternal, "An unexpected error occurred.")
+}
+
+//respondUnavailable logs `err` and tells the client that a store the
+//service depends on can't be reached right now, so it can try again
+func respondUnavailable(w http.ResponseWriter, err error) {
+	log.Printf("service unavailable: %v", err)
+	respondError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "The service is busy, please try again.")
+}
+
+//respondNotSupported writes the error response for a request
+//the user store doesn't have the capabilities to carry out
+func respondNotSupported(w http.ResponseWriter) {
+	respondError(w, http.StatusNotImplemented, ErrCodeNotImplemented, "This feature is not available.")
+}
+
+//respondMethodNotAllowed writes the error response for an unsupported method
+func respondMethodNotAllowed(w http.ResponseWriter) {
+	respondError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Http method not allowed.")
+}
+
+//respondValidationError writes the error response for a request body
+//that failed validation, with the details of each failing field
+//if `err` is a users.ValidationErrors
+func respondValidationError(w http.ResponseWriter, err error) {
+	errResp := &ErrorResponse{Code: ErrCodeValidation, Message: err.Error()}
+	var valErrs users.ValidationErrors
+	if errors.As(err, &valErrs) {
+		errResp.Message = "Some fields are invalid."
+		for _, ve := range valErrs {
+			errResp.Fields = append(errResp.Fields, &FieldError{ve.Field, ve.Rule, ve.Message})
+		}
+	}
+	respondJSON(w, http.StatusBadRequest, errResp)
+}
+
+//respondConflict writes the error response for a users.ErrEmailTaken
+//or users.ErrUserNameTaken from the user store, naming the field that
+//conflicts with another user. Any other error is an internal error.
+func respondConflict(w http.ResponseWriter, err error) {
+	var fieldErr *FieldError
+	switch err {
+	case users.ErrEmailTaken:
+		fieldErr = &FieldError{"email", FieldCodeEmailTaken, "Email address is already in use."}
+	case users.ErrUserNameTaken:
+		fieldErr = &FieldError{"userName", FieldCodeUserNameTaken, "Username is already taken."}
+	default:
+		respondInternalError(w, err)
+		return
+	}
+	respondJSON(w, http.StatusConflict, &ErrorResponse{
+		Code:    ErrCodeConflict,
+		Message: fieldErr.Message,
+		Fields:  []*FieldError{fieldErr},
+	})
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"assignments-jelauria/servers/gateway/models/users"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestErrorResponses(t *testing.T) {
+	ctx := &Context{}
+	cases := []struct {
+		name           string
+		handler        http.HandlerFunc
+		method         string
+		contentType    string
+		body           string
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			"Users Wrong Method",
+			ctx.UsersHandler,
+			http.MethodGet,
+			"",
+			"",
+			http.StatusMethodNotAllowed,
+			ErrCodeMethodNotAllowed,
+		},
+		{
+			"Users Not JSON",
+			ctx.UsersHandler,
+			http.MethodPost,
+			"text/plain",
+			"hello",
+			http.StatusUnsupportedMediaType,
+			ErrCodeUnsupportedMediaType,
+		},
+		{
+			"Users Malformed JSON",
+			ctx.UsersHandler,
+			http.MethodPost,
+			contentTypeJSON,
+			"{",
+			http.StatusBadRequest,
+			ErrCodeBadRequest,
+		},
+		{
+			"Users Invalid New User",
+			ctx.UsersHandler,
+			http.MethodPost,
+			contentTypeJSON,
+			`{"email": "not an email", "password": "TophRocks1337", "passwordConf": "TophRocks1337", "userName": "TheBlindBandit"}`,
+			http.StatusBadRequest,
+			ErrCodeValidation,
+		},
+		{
+			"Specific User Without Session",
+			ctx.SpecificUsersHandler,
+			http.MethodGet,
+			"",
+			"",
+			http.StatusUnauthorized,
+			ErrCodeUnauthorized,
+		},
+		{
+			"Sessions Not JSON",
+			ctx.SessionsHandler,
+			http.MethodPost,
+			"text/plain",
+			"hello",
+			http.StatusUnsupportedMediaType,
+			ErrCodeUnsupportedMediaType,
+		},
+		{
+			"Summary Without URL",
+			SummaryHandler,
+			http.MethodGet,
+			"",
+			"",
+			http.StatusBadRequest,
+			ErrCodeBadRequest,
+		},
+	}
+
+	for _, c := range cases {
+		req, _ := http.NewRequest(c.method, "/", strings.NewReader(c.body))
+		if len(c.contentType) > 0 {
+			req.Header.Set(headerContentType, c.contentType)
+		}
+		resp := httptest.NewRecorder()
+		c.handler(resp, req)
+
+		if resp.Code != c.expectedStatus {
+			t.Errorf("case %s: incorrect status code: expected %d but got %d", c.name, c.expectedStatus, resp.Code)
+		}
+		if ctype := resp.Header().Get(headerContentType); !strings.HasPrefix(ctype, contentTypeJSON) {
+			t.Errorf("case %s: incorrect `Content-Type` header: expected `%s` but got `%s`", c.name, contentTypeJSON, ctype)
+		}
+		errResp := &ErrorResponse{}
+		if err := json.NewDecoder(resp.Body).Decode(errResp); err != nil {
+			t.Errorf("case %s: error decoding error response: %v", c.name, err)
+			continue
+		}
+		if errResp.Code != c.expectedCode {
+			t.Errorf("case %s: incorrect error code: expected `%s` but got `%s`", c.name, c.expectedCode, errResp.Code)
+		}
+		if len(errResp.Message) == 0 {
+			t.Errorf("case %s: error response has no message", c.name)
+		}
+		if c.expectedCode == ErrCodeValidation {
+			if len(errResp.Fields) != 1 || errResp.Fields[0].Field != "email" || errResp.Fields[0].Code != users.RuleEmailFormat {
+				t.Errorf("case %s: expected a field error for the email address", c.name)
+			}
+		}
+	}
+}
+
+func TestRespondInternalError(t *testing.T) {
+	cases := []struct {
+		name           string
+		err            error
+		expectedStatus int
+		expectedCode   string
+	}{
+		{
+			"Unexpected Error",
+			errors.New("connection refused"),
+			http.StatusInternalServerError,
+			ErrCodeInternal,
+		},
+		{
+			"Store Deadline Passed",
+			fmt.Errorf("getting user: %w", context.DeadlineExceeded),
+			http.StatusServiceUnavailable,
+			ErrCodeUnavailable,
+		},
+		{
+			"Store Lacks Capability",
+			users.ErrNotSupported,
+			http.StatusNotImplemented,
+			ErrCodeNotImplemented,
+		},
+	}
+	for _, c := range cases {
+		resp := httptest.NewRecorder()
+		respondInternalError(resp, c.err)
+		if resp.Code != c.expectedStatus {
+			t.Errorf("case %s: incorrect status code: expected %d but got %d", c.name, c.expectedStatus, resp.Code)
+		}
+		errResp := &ErrorResponse{}
+		if err := json.NewDecoder(resp.Body).Decode(errResp); err != nil {
+			t.Errorf("case %s: error decoding error response: %v", c.name, err)
+			continue
+		}
+		if errResp.Code != c.expectedCode {
+			t.Errorf("case %s: incorrect error code: expected `%s` but got `%s`", c.name, c.expectedCode, errResp.Code)
+		}
+		if strings.Contains(errResp.Message, c.err.Error()) {
+			t.Errorf("case %s: error details were leaked to the client", c.name)
+		}
+	}
+}
@@ -1,6 +1,6 @@
 module github.com/my/repo
 
-go 1.14
+go 1.16
 
 require (
 	github.com/go-redis/redis/v7 v7.2.0 // indirect
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"assignments-jelauria/servers/gateway/mailer"
+	"assignments-jelauria/servers/gateway/models/users"
+	"assignments-jelauria/servers/gateway/sessions"
+	"context"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+//newTestContext returns a Context backed by in-memory stores,
+//holding a single user with the password "TophRocks1337". Until the
+//test ends, passwords are hashed with a low cost to keep tests fast.
+//That swaps the package-global users.DefaultHasher, so tests that
+//use newTestContext must not call t.Parallel.
+func newTestContext(t *testing.T) (*Context, *users.User) {
+	origHasher := users.DefaultHasher
+	users.DefaultHasher = &users.BcryptHasher{Cost: 4}
+	t.Cleanup(func() { users.DefaultHasher = origHasher })
+
+	ctx := &Context{
+		SeshKey:     sessions.SigningKey("test key"),
+		SeshStore:   sessions.NewMemStore(time.Hour, time.Minute),
+		UserStore:   users.NewMemStore(),
+		ResetTokens: users.NewMemTokenStore(),
+		TokenKey:    "test token key",
+		Mailer:      mailer.NewLogSender(ioutil.Discard),
+	}
+	user := &users.User{Email: "toph@test.com", UserName: "TheBlindBandit", FirstName: "Toph"}
+	if err := user.SetPassword("TophRocks1337"); err != nil {
+		t.Fatalf("error setting password: %v", err)
+	}
+	user, err := ctx.UserStore.Insert(context.Background(), user)
+	if err != nil {
+		t.Fatalf("error inserting user: %v", err)
+	}
+	return ctx, user
+}
+
+//newTestSession begins a session for `user`, failing the test if it can't
+func newTestSession(t *testing.T, ctx *Context, user *users.User) sessions.SessionID {
+	sid, err := ctx.beginSession(context.Background(), user, httptest.NewRecorder())
+	if err != nil {
+		t.Fatalf("error beginning session: %v", err)
+	}
+	return sid
+}
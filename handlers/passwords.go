@@ -0,0 +1,192 @@
+package handlers
+
+import (
+	"assignments-jelauria/servers/gateway/mailer"
+	"assignments-jelauria/servers/gateway/models/users"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//ErrCodeInvalidToken is the error code sent when a reset
+//token is unknown, expired or already used
+const ErrCodeInvalidToken = "invalid_token"
+
+//resetTokenTTL is how long a password reset token can be used
+const resetTokenTTL = time.Hour
+
+//PasswordHandler handles requests to change the password of the
+//signed-in user. The current password must be supplied, and all of
+//the user's other sessions and reset tokens are ended once it is changed.
+//It must be wrapped with EnsureAuth.
+func (c *Context) PasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPatch {
+		currState, ok := SessionStateFromContext(r.Context())
+		if !ok {
+			respondError(w, http.StatusUnauthorized, ErrCodeUnauthorized, unauthorizedMsg)
+			return
+		}
+		if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+			respondError(w, http.StatusUnsupportedMediaType, ErrCodeUnsupportedMediaType, "Request body must be in JSON.")
+			return
+		}
+		var change users.PasswordChange
+		if err := json.NewDecoder(r.Body).Decode(&change); err != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeBadRequest, "Request body is not valid JSON.")
+			return
+		}
+		//the session state doesn't include the password hash,
+		//so get the current user from the store
+		user, err := c.UserStore.GetByID(r.Context(), currState.AuthUser.ID)
+		if err != nil {
+			respondInternalError(w, err)
+			return
+		}
+		if err := user.Authenticate(change.CurrentPassword); err != nil {
+			respondError(w, http.StatusForbidden, ErrCodeInvalidCredentials, "Current password is incorrect.")
+			return
+		}
+		if err := user.ValidatePassword(change.Password, change.PasswordConf); err != nil {
+			respondValidationError(w, err)
+			return
+		}
+		if err := c.setPassword(r.Context(), user, change.Password); err != nil {
+			respondInternalError(w, err)
+			return
+		}
+		if err := c.ResetTokens.RevokeAll(r.Context(), user.ID); err != nil {
+			respondInternalError(w, err)
+			return
+		}
+		sid, _ := SessionIDFromContext(r.Context())
+		if err := c.endUserSessions(r.Context(), user.ID, sid); err != nil {
+			respondInternalError(w, err)
+			return
+		}
+		w.Write([]byte("password changed"))
+		return
+	}
+	respondMethodNotAllowed(w)
+	return
+}
+
+//ResetsHandler handles password resets for users who forgot their
+//password. POST requests email a single-use reset token to the
+//address given, and PATCH requests use that token to set a new
+//password, ending all of the user's sessions and revoking their
+//other reset tokens. Tokens of deleted users can't be used.
+func (c *Context) ResetsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPatch {
+		respondMethodNotAllowed(w)
+		return
+	}
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		respondError(w, http.StatusUnsupportedMediaType, ErrCodeUnsupportedMediaType, "Request body must be in JSON.")
+		return
+	}
+	if r.Method == http.MethodPost {
+		var resetReq users.ResetRequest
+		if err := json.NewDecoder(r.Body).Decode(&resetReq); err != nil {
+			respondError(w, http.StatusBadRequest, ErrCodeBadRequest, "Request body is not valid JSON.")
+			return
+		}
+		//respond the same way whether or not there is an account for
+		//the email, so this can't be used to find out who has one
+		if err := c.sendResetToken(r.Context(), resetReq.Email); err != nil {
+			log.Printf("error sending reset token: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+		w.Write([]byte("if an account exists for that email, a reset token has been sent to it"))
+		return
+	}
+
+	var reset users.PasswordReset
+	if err := json.NewDecoder(r.Body).Decode(&reset); err != nil {
+		respondError(w, http.StatusBadRequest, ErrCodeBadRequest, "Request body is not valid JSON.")
+		return
+	}
+	userID, err := c.ResetTokens.Lookup(r.Context(), reset.Token)
+	if err == users.ErrTokenNotFound {
+		respondError(w, http.StatusBadRequest, ErrCodeInvalidToken, "Reset token is invalid or has expired.")
+		return
+	}
+	if err != nil {
+		respondInternalError(w, err)
+		return
+	}
+	user, err := c.UserStore.GetByID(r.Context(), userID)
+	if err == users.ErrUserNotFound || (err == nil && user.DeletedAt != nil) {
+		//the user was deleted after the token was issued
+		respondError(w, http.StatusBadRequest, ErrCodeInvalidToken, "Reset token is invalid or has expired.")
+		return
+	}
+	if err != nil {
+		respondInternalError(w, err)
+		return
+	}
+	if err := user.ValidatePassword(reset.Password, reset.PasswordConf); err != nil {
+		respondValidationError(w, err)
+		return
+	}
+	//only redeem the token once the new password is known to be valid,
+	//so the user can correct it and try again
+	if _, err := c.ResetTokens.Redeem(r.Context(), reset.Token); err != nil {
+		if err == users.ErrTokenNotFound {
+			respondError(w, http.StatusBadRequest, ErrCodeInvalidToken, "Reset token is invalid or has expired.")
+		} else {
+			respondInternalError(w, err)
+		}
+		return
+	}
+	if err := c.setPassword(r.Context(), user, reset.Password); err != nil {
+		respondInternalError(w, err)
+		return
+	}
+	//an older token that leaked must not be able to change
+	//the password again
+	if err := c.ResetTokens.RevokeAll(r.Context(), user.ID); err != nil {
+		respondInternalError(w, err)
+		return
+	}
+	if err := c.endUserSessions(r.Context(), user.ID); err != nil {
+		respondInternalError(w, err)
+		return
+	}
+	w.Write([]byte("password reset"))
+}
+
+//sendResetToken issues a reset token for the user with the given
+//email, if there is one that isn't deleted, and emails it to them
+func (c *Context) sendResetToken(ctx context.Context, email string) error {
+	user, err := c.UserStore.GetByEmail(ctx, email)
+	if err == users.ErrUserNotFound || (err == nil && user.DeletedAt != nil) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	token, err := c.ResetTokens.Issue(ctx, user.ID, resetTokenTTL)
+	if err != nil {
+		return err
+	}
+	return c.Mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Use this code to reset your password: %s\n\n"+
+			"It expires in %v. If you didn't ask to reset your password, you can ignore this email.",
+			token, resetTokenTTL),
+	})
+}
+
+//setPassword sets the password of the user and saves
+//the new password hash in the user store
+func (c *Context) setPassword(ctx context.Context, user *users.User, password string) error {
+	if err := user.SetPassword(password); err != nil {
+		return err
+	}
+	return c.UserStore.SetPassHash(ctx, user.ID, user.PassHash)
+}
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"assignments-jelauria/servers/gateway/mailer"
+	"assignments-jelauria/servers/gateway/models/users"
+	"assignments-jelauria/servers/gateway/sessions"
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPasswordHandler(t *testing.T) {
+	cases := []struct {
+		name             string
+		body             string
+		expectedStatus   int
+		expectedPassword string
+	}{
+		{
+			"Password Changed",
+			`{"currentPassword": "TophRocks1337", "password": "MetalBending!", "passwordConf": "MetalBending!"}`,
+			http.StatusOK,
+			"MetalBending!",
+		},
+		{
+			"Incorrect Current Password",
+			`{"currentPassword": "Aang", "password": "MetalBending!", "passwordConf": "MetalBending!"}`,
+			http.StatusForbidden,
+			"TophRocks1337",
+		},
+		{
+			"Passwords Do Not Match",
+			`{"currentPassword": "TophRocks1337", "password": "MetalBending!", "passwordConf": "EarthBending!"}`,
+			http.StatusBadRequest,
+			"TophRocks1337",
+		},
+		{
+			"Invalid JSON",
+			`{"currentPassword": `,
+			http.StatusBadRequest,
+			"TophRocks1337",
+		},
+	}
+
+	for _, c := range cases {
+		ctx, user := newTestContext(t)
+		sid := newTestSession(t, ctx, user)
+		otherSID := newTestSession(t, ctx, user)
+		token, err := ctx.ResetTokens.Issue(context.Background(), user.ID, time.Hour)
+		if err != nil {
+			t.Fatalf("case %s: error issuing reset token: %v", c.name, err)
+		}
+
+		req, _ := http.NewRequest(http.MethodPatch, "/v1/users/me/password", strings.NewReader(c.body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+sid.String())
+		resp := httptest.NewRecorder()
+		ctx.EnsureAuth(ctx.PasswordHandler)(resp, req)
+
+		if resp.Code != c.expectedStatus {
+			t.Errorf("case %s: incorrect status code: expected %d but got %d", c.name, c.expectedStatus, resp.Code)
+		}
+		stored, _ := ctx.UserStore.GetByID(context.Background(), user.ID)
+		if err := stored.Authenticate(c.expectedPassword); err != nil {
+			t.Errorf("case %s: stored password is not %q", c.name, c.expectedPassword)
+		}
+		state := &SessionState{}
+		if err := ctx.SeshStore.Get(context.Background(), sid, state); err != nil {
+			t.Errorf("case %s: the current session should not be ended", c.name)
+		}
+		otherErr := ctx.SeshStore.Get(context.Background(), otherSID, state)
+		if c.expectedStatus == http.StatusOK && otherErr != sessions.ErrStateNotFound {
+			t.Errorf("case %s: other sessions should be ended when the password changes", c.name)
+		}
+		if c.expectedStatus != http.StatusOK && otherErr != nil {
+			t.Errorf("case %s: other sessions should not be ended when the password is unchanged", c.name)
+		}
+		_, tokenErr := ctx.ResetTokens.Lookup(context.Background(), token)
+		if c.expectedStatus == http.StatusOK && tokenErr != users.ErrTokenNotFound {
+			t.Errorf("case %s: reset tokens should be revoked when the password changes", c.name)
+		}
+		if c.expectedStatus != http.StatusOK && tokenErr != nil {
+			t.Errorf("case %s: reset tokens should not be revoked when the password is unchanged", c.name)
+		}
+	}
+}
+
+//resetTokenPattern finds the token in a password reset message
+var resetTokenPattern = regexp.MustCompile(`reset your password: (\S+)`)
+
+func TestResetsHandler(t *testing.T) {
+	cases := []struct {
+		name             string
+		token            string
+		body             string
+		redeemFirst      bool
+		deleteUser       bool
+		expectedStatus   int
+		expectedPassword string
+	}{
+		{
+			"Password Reset",
+			"",
+			`{"password": "MetalBending!", "passwordConf": "MetalBending!"}`,
+			false,
+			false,
+			http.StatusOK,
+			"MetalBending!",
+		},
+		{
+			"Invalid Token",
+			"not a token",
+			`{"password": "MetalBending!", "passwordConf": "MetalBending!"}`,
+			false,
+			false,
+			http.StatusBadRequest,
+			"TophRocks1337",
+		},
+		{
+			"Bad Password",
+			"",
+			`{"password": "MetalBending!", "passwordConf": "EarthBending!"}`,
+			false,
+			false,
+			http.StatusBadRequest,
+			"TophRocks1337",
+		},
+		{
+			"Reused Token",
+			"",
+			`{"password": "MetalBending!", "passwordConf": "MetalBending!"}`,
+			true,
+			false,
+			http.StatusBadRequest,
+			"SeismicSense!",
+		},
+		{
+			"Deleted User",
+			"",
+			`{"password": "MetalBending!", "passwordConf": "MetalBending!"}`,
+			false,
+			true,
+			http.StatusBadRequest,
+			"",
+		},
+	}
+
+	for _, c := range cases {
+		ctx, user := newTestContext(t)
+		sent := &bytes.Buffer{}
+		ctx.Mailer = mailer.NewLogSender(sent)
+		sid := newTestSession(t, ctx, user)
+
+		//issue a token, which is only emailed to users with an account
+		for _, email := range []string{"nobody@test.com", user.Email} {
+			req, _ := http.NewRequest(http.MethodPost, "/v1/resets", strings.NewReader(`{"email": "`+email+`"}`))
+			req.Header.Set(headerContentType, contentTypeJSON)
+			resp := httptest.NewRecorder()
+			ctx.ResetsHandler(resp, req)
+			if resp.Code != http.StatusAccepted {
+				t.Errorf("case %s: incorrect status code issuing a token for %s: expected %d but got %d", c.name, email, http.StatusAccepted, resp.Code)
+			}
+		}
+		match := resetTokenPattern.FindStringSubmatch(sent.String())
+		if match == nil || strings.Contains(sent.String(), "nobody@test.com") {
+			t.Fatalf("case %s: token was not emailed only to the user:\n%s", c.name, sent.String())
+		}
+		token := match[1]
+		if len(c.token) > 0 {
+			token = c.token
+		}
+
+		redeem := func(body string) int {
+			body = strings.Replace(body, "{", `{"token": "`+token+`", `, 1)
+			req, _ := http.NewRequest(http.MethodPatch, "/v1/resets", strings.NewReader(body))
+			req.Header.Set(headerContentType, contentTypeJSON)
+			resp := httptest.NewRecorder()
+			ctx.ResetsHandler(resp, req)
+			return resp.Code
+		}
+		if c.redeemFirst {
+			if status := redeem(`{"password": "SeismicSense!", "passwordConf": "SeismicSense!"}`); status != http.StatusOK {
+				t.Fatalf("case %s: incorrect status code redeeming the token the first time: expected %d but got %d", c.name, http.StatusOK, status)
+			}
+		}
+		if c.deleteUser {
+			if err := ctx.UserStore.Delete(context.Background(), user.ID); err != nil {
+				t.Fatalf("case %s: error deleting user: %v", c.name, err)
+			}
+		}
+
+		if status := redeem(c.body); status != c.expectedStatus {
+			t.Errorf("case %s: incorrect status code: expected %d but got %d", c.name, c.expectedStatus, status)
+		}
+		if len(c.expectedPassword) > 0 {
+			stored, _ := ctx.UserStore.GetByID(context.Background(), user.ID)
+			if err := stored.Authenticate(c.expectedPassword); err != nil {
+				t.Errorf("case %s: stored password is not %q", c.name, c.expectedPassword)
+			}
+		}
+		sessionErr := ctx.SeshStore.Get(context.Background(), sid, &SessionState{})
+		if c.expectedStatus == http.StatusOK && sessionErr != sessions.ErrStateNotFound {
+			t.Errorf("case %s: sessions should be ended when the password is reset", c.name)
+		}
+		if c.expectedStatus != http.StatusOK && !c.redeemFirst && sessionErr != nil {
+			t.Errorf("case %s: sessions should not be ended when the password is not reset", c.name)
+		}
+	}
+}
+
+//TestResetsHandlerRevokedTokens checks that the user's other reset
+//tokens can't be used once one of them has reset the password, and
+//that the tokens of soft-deleted users can't be used at all
+func TestResetsHandlerRevokedTokens(t *testing.T) {
+	ctx, user := newTestContext(t)
+	redeem := func(token string, password string) int {
+		body := `{"token": "` + token + `", "password": "` + password + `", "passwordConf": "` + password + `"}`
+		req, _ := http.NewRequest(http.MethodPatch, "/v1/resets", strings.NewReader(body))
+		req.Header.Set(headerContentType, contentTypeJSON)
+		resp := httptest.NewRecorder()
+		ctx.ResetsHandler(resp, req)
+		return resp.Code
+	}
+
+	older, err := ctx.ResetTokens.Issue(context.Background(), user.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("error issuing reset token: %v", err)
+	}
+	newer, err := ctx.ResetTokens.Issue(context.Background(), user.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("error issuing reset token: %v", err)
+	}
+	if status := redeem(newer, "MetalBending!"); status != http.StatusOK {
+		t.Fatalf("incorrect status code redeeming a token: expected %d but got %d", http.StatusOK, status)
+	}
+	if status := redeem(older, "SeismicSense!"); status != http.StatusBadRequest {
+		t.Errorf("incorrect status code redeeming a token issued before the password was reset: expected %d but got %d", http.StatusBadRequest, status)
+	}
+
+	token, err := ctx.ResetTokens.Issue(context.Background(), user.ID, time.Hour)
+	if err != nil {
+		t.Fatalf("error issuing reset token: %v", err)
+	}
+	deletedAt := time.Now()
+	if err := ctx.UserStore.(users.SoftDeleteStore).SetDeletedAt(context.Background(), user.ID, &deletedAt); err != nil {
+		t.Fatalf("error soft-deleting user: %v", err)
+	}
+	if status := redeem(token, "SeismicSense!"); status != http.StatusBadRequest {
+		t.Errorf("incorrect status code redeeming a token of a soft-deleted user: expected %d but got %d", http.StatusBadRequest, status)
+	}
+	stored, _ := ctx.UserStore.GetByID(context.Background(), user.ID)
+	if err := stored.Authenticate("MetalBending!"); err != nil {
+		t.Error("password was changed by a revoked token or a token of a soft-deleted user")
+	}
+}
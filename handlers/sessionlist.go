@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"assignments-jelauria/servers/gateway/sessions"
+	"net/http"
+	"path"
+	"sort"
+	"time"
+)
+
+//SessionInfo describes one of the signed-in user's sessions. Its ID is
+//the session's Handle rather than its SessionID, since anyone who saw
+//the SessionID could use it to take over the session.
+type SessionInfo struct {
+	ID        string    `json:"id"`
+	SeshStart time.Time `json:"seshStart"`
+	Current   bool      `json:"current"`
+}
+
+//notIndexedMsg is the response message when the session
+//store can't find the sessions of a user
+const notIndexedMsg = "Sessions can't be listed."
+
+//listSessions responds with the signed-in user's sessions, oldest
+//first. It must be wrapped with EnsureAuth.
+func (c *Context) listSessions(w http.ResponseWriter, r *http.Request) {
+	currState, _ := SessionStateFromContext(r.Context())
+	currSID, _ := SessionIDFromContext(r.Context())
+	indexed, ok := c.SeshStore.(sessions.IndexedStore)
+	if !ok {
+		respondError(w, http.StatusNotImplemented, ErrCodeNotImplemented, notIndexedMsg)
+		return
+	}
+	sids, err := indexed.List(r.Context(), currState.AuthUser.ID)
+	if err != nil {
+		respondUnavailable(w, err)
+		return
+	}
+	infos := []*SessionInfo{}
+	for _, sid := range sids {
+		//peek, so that listing sessions doesn't keep them alive
+		state := &SessionState{}
+		err := indexed.Peek(r.Context(), sid, state)
+		if err == sessions.ErrStateNotFound {
+			//the session ended since it was listed
+			continue
+		}
+		if err != nil {
+			respondUnavailable(w, err)
+			return
+		}
+		infos = append(infos, &SessionInfo{
+			ID:        sid.Handle(),
+			SeshStart: state.SeshStart,
+			Current:   sid == currSID,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].SeshStart.Before(infos[j].SeshStart) })
+	respondJSON(w, http.StatusOK, infos)
+}
+
+//revokeSession ends the signed-in user's session whose Handle is
+//the last element of the request path. It must be wrapped with EnsureAuth.
+func (c *Context) revokeSession(w http.ResponseWriter, r *http.Request) {
+	currState, _ := SessionStateFromContext(r.Context())
+	currSID, _ := SessionIDFromContext(r.Context())
+	indexed, ok := c.SeshStore.(sessions.IndexedStore)
+	if !ok {
+		respondError(w, http.StatusNotImplemented, ErrCodeNotImplemented, notIndexedMsg)
+		return
+	}
+	//only the user's own sessions are listed,
+	//so they can't end anyone else's
+	sids, err := indexed.List(r.Context(), currState.AuthUser.ID)
+	if err != nil {
+		respondUnavailable(w, err)
+		return
+	}
+	handle := path.Base(r.URL.Path)
+	for _, sid := range sids {
+		if sid.Handle() == handle {
+			if err := indexed.Delete(r.Context(), sid); err != nil {
+				respondUnavailable(w, err)
+				return
+			}
+			if sid == currSID {
+				c.transport().Clear(w)
+			}
+			w.Write([]byte("session ended"))
+			return
+		}
+	}
+	respondError(w, http.StatusNotFound, ErrCodeNotFound, "No session with given ID.")
+}
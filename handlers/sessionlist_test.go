@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"assignments-jelauria/servers/gateway/models/users"
+	"assignments-jelauria/servers/gateway/sessions"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+//plainStore hides the index of the sessions.Store it wraps
+type plainStore struct {
+	sessions.Store
+}
+
+func TestListSessions(t *testing.T) {
+	ctx, user := newTestContext(t)
+	var sids []sessions.SessionID
+	for i := 0; i < 3; i++ {
+		sids = append(sids, newTestSession(t, ctx, user))
+	}
+	//a session of another user should not be listed
+	other, _ := ctx.UserStore.Insert(context.Background(), &users.User{Email: "aang@test.com", UserName: "Aang"})
+	newTestSession(t, ctx, other)
+
+	req, _ := http.NewRequest(http.MethodGet, "/v1/sessions", nil)
+	req.Header.Set("Authorization", "Bearer "+sids[1].String())
+	resp := httptest.NewRecorder()
+	ctx.SessionsHandler(resp, req)
+	if resp.Code != http.StatusOK {
+		t.Fatalf("incorrect status code: expected %d but got %d", http.StatusOK, resp.Code)
+	}
+	infos := []*SessionInfo{}
+	if err := json.NewDecoder(resp.Body).Decode(&infos); err != nil {
+		t.Fatalf("error decoding response body: %v", err)
+	}
+	if len(infos) != len(sids) {
+		t.Fatalf("incorrect number of sessions listed: expected %d but got %d", len(sids), len(infos))
+	}
+	for i, info := range infos {
+		if info.ID != sids[i].Handle() {
+			t.Errorf("incorrect session listed at %d: expected %s but got %s", i, sids[i].Handle(), info.ID)
+		}
+		if info.ID == sids[i].String() {
+			t.Errorf("session %d was listed by its SessionID", i)
+		}
+		if info.Current != (i == 1) {
+			t.Errorf("incorrect current flag for session %d: %t", i, info.Current)
+		}
+	}
+
+	ctx.SeshStore = plainStore{ctx.SeshStore}
+	resp = httptest.NewRecorder()
+	ctx.SessionsHandler(resp, req)
+	if resp.Code != http.StatusNotImplemented {
+		t.Errorf("incorrect status code for a store without an index: expected %d but got %d", http.StatusNotImplemented, resp.Code)
+	}
+}
+
+func TestRevokeSession(t *testing.T) {
+	cases := []struct {
+		name           string
+		handle         func(own, other sessions.SessionID) string
+		expectedStatus int
+		expectOwnEnded bool
+	}{
+		{
+			"Own Session",
+			func(own, other sessions.SessionID) string { return own.Handle() },
+			http.StatusOK,
+			true,
+		},
+		{
+			"Other User's Session",
+			func(own, other sessions.SessionID) string { return other.Handle() },
+			http.StatusNotFound,
+			false,
+		},
+		{
+			"Unknown Session",
+			func(own, other sessions.SessionID) string { return "nope" },
+			http.StatusNotFound,
+			false,
+		},
+		{
+			"By SessionID",
+			func(own, other sessions.SessionID) string { return own.String() },
+			http.StatusNotFound,
+			false,
+		},
+	}
+
+	for _, c := range cases {
+		ctx, user := newTestContext(t)
+		sid := newTestSession(t, ctx, user)
+		own := newTestSession(t, ctx, user)
+		otherUser, _ := ctx.UserStore.Insert(context.Background(), &users.User{Email: "aang@test.com", UserName: "Aang"})
+		other := newTestSession(t, ctx, otherUser)
+
+		req, _ := http.NewRequest(http.MethodDelete, "/v1/sessions/"+c.handle(own, other), nil)
+		req.Header.Set("Authorization", "Bearer "+sid.String())
+		resp := httptest.NewRecorder()
+		ctx.SpecificSessionHandler(resp, req)
+		if resp.Code != c.expectedStatus {
+			t.Errorf("case %s: incorrect status code: expected %d but got %d", c.name, c.expectedStatus, resp.Code)
+		}
+		ownErr := ctx.SeshStore.Get(context.Background(), own, &SessionState{})
+		if c.expectOwnEnded && ownErr != sessions.ErrStateNotFound {
+			t.Errorf("case %s: session should be ended", c.name)
+		}
+		if !c.expectOwnEnded && ownErr != nil {
+			t.Errorf("case %s: session should not be ended", c.name)
+		}
+		if err := ctx.SeshStore.Get(context.Background(), other, &SessionState{}); err != nil {
+			t.Errorf("case %s: session of another user should not be ended", c.name)
+		}
+	}
+}
@@ -10,6 +10,21 @@ import (
 //remember that other packages can only see exported fields!
 
 type SessionState struct {
-	SeshStart *time.Time  `json:"seshStart"`
+	SeshStart time.Time   `json:"seshStart"`
 	AuthUser  *users.User `json:"authUser"`
+	//Restricted sessions were begun for a user that the SignInPolicy
+	//restricts, and may only be used for safe methods like GET
+	Restricted bool `json:"restricted"`
+	//CSRFToken must be sent in the X-CSRF-Token header with unsafe
+	//requests when the SessionID is kept in a cookie. See CSRF.
+	CSRFToken string `json:"csrfToken"`
+	//AuthTime is when the user last gave their password in this
+	//session, by signing in or reauthenticating. See EnsureRecentAuth.
+	AuthTime time.Time `json:"authTime"`
+}
+
+//SessionStart returns SeshStart, so that session stores
+//with a MaxAge can end the session once it is too old
+func (s *SessionState) SessionStart() time.Time {
+	return s.SeshStart
 }
@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"assignments-jelauria/servers/gateway/models/users"
+	"context"
+	"net/http"
+)
+
+//SignInDecision is what a SignInPolicy decides about a user signing in
+type SignInDecision int
+
+const (
+	//SignInAllow begins a normal session
+	SignInAllow SignInDecision = iota
+	//SignInRestrict begins a Restricted session, which can
+	//only be used for requests that don't change anything
+	SignInRestrict
+	//SignInReject refuses to begin a session
+	SignInReject
+)
+
+//SignInPolicy decides whether a user who has given the right
+//credentials may sign in. It can be used to hold back accounts
+//whose email address hasn't been verified, to stop abusive sign-ups.
+type SignInPolicy func(user *users.User) SignInDecision
+
+//RestrictUnverified is a SignInPolicy that restricts the sessions
+//of users whose email address hasn't been verified
+func RestrictUnverified(user *users.User) SignInDecision {
+	if !user.EmailVerified {
+		return SignInRestrict
+	}
+	return SignInAllow
+}
+
+//RejectUnverified is a SignInPolicy that doesn't let users sign in
+//until their email address has been verified
+func RejectUnverified(user *users.User) SignInDecision {
+	if !user.EmailVerified {
+		return SignInReject
+	}
+	return SignInAllow
+}
+
+//unverifiedMsg is the response message for requests
+//refused because the user's email isn't verified
+const unverifiedMsg = "Please verify your email address."
+
+//signInDecision returns what the Context's SignInPolicy
+//decides about `user`. Without a policy, everyone is allowed.
+func (c *Context) signInDecision(user *users.User) SignInDecision {
+	if c.SignInPolicy == nil {
+		return SignInAllow
+	}
+	return c.SignInPolicy(user)
+}
+
+//isSafeMethod reports whether requests with the method
+//only read, and so may be made by Restricted sessions
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+//liftRestriction checks whether the SignInPolicy still restricts the
+//user of a Restricted session, for example because they have verified
+//their email since signing in. If not, the restriction is removed from
+//`state`, which the caller should then save, and true is returned.
+func (c *Context) liftRestriction(ctx context.Context, state *SessionState) (bool, error) {
+	user, err := c.UserStore.GetByID(ctx, state.AuthUser.ID)
+	if err != nil {
+		return false, err
+	}
+	if c.signInDecision(user) != SignInAllow {
+		return false, nil
+	}
+	state.AuthUser = user
+	state.Restricted = false
+	return true, nil
+}
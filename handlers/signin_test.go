@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"assignments-jelauria/servers/gateway/models/users"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSignInPolicy(t *testing.T) {
+	cases := []struct {
+		name               string
+		policy             SignInPolicy
+		expectedStatus     int
+		expectedRestricted bool
+	}{
+		{
+			"No Policy",
+			nil,
+			http.StatusCreated,
+			false,
+		},
+		{
+			"Restrict Unverified",
+			RestrictUnverified,
+			http.StatusCreated,
+			true,
+		},
+		{
+			"Reject Unverified",
+			RejectUnverified,
+			http.StatusForbidden,
+			false,
+		},
+	}
+
+	for _, c := range cases {
+		ctx, user := newTestContext(t)
+		ctx.SignInPolicy = c.policy
+		req, _ := http.NewRequest(http.MethodPost, "/v1/sessions", strings.NewReader(`{"email": "toph@test.com", "password": "TophRocks1337"}`))
+		req.Header.Set(headerContentType, contentTypeJSON)
+		resp := httptest.NewRecorder()
+		ctx.SessionsHandler(resp, req)
+		if resp.Code != c.expectedStatus {
+			t.Errorf("case %s: incorrect status code: expected %d but got %d", c.name, c.expectedStatus, resp.Code)
+			continue
+		}
+		if resp.Code != http.StatusCreated {
+			if len(resp.Header().Get("Authorization")) > 0 {
+				t.Errorf("case %s: a session was begun for a rejected user", c.name)
+			}
+			continue
+		}
+
+		auth := resp.Header().Get("Authorization")
+		patchMe := func() int {
+			req, _ := http.NewRequest(http.MethodPatch, "/v1/users/me", strings.NewReader(`{"bio": "Greatest earthbender"}`))
+			req.Header.Set(headerContentType, contentTypeJSON)
+			req.Header.Set("Authorization", auth)
+			resp := httptest.NewRecorder()
+			ctx.EnsureAuth(ctx.SpecificUsersHandler)(resp, req)
+			return resp.Code
+		}
+		getMe := func() int {
+			req, _ := http.NewRequest(http.MethodGet, "/v1/users/me", nil)
+			req.Header.Set("Authorization", auth)
+			resp := httptest.NewRecorder()
+			ctx.EnsureAuth(ctx.SpecificUsersHandler)(resp, req)
+			return resp.Code
+		}
+		if status := getMe(); status != http.StatusOK {
+			t.Errorf("case %s: incorrect status code reading profile: expected %d but got %d", c.name, http.StatusOK, status)
+		}
+		if !c.expectedRestricted {
+			if status := patchMe(); status != http.StatusOK {
+				t.Errorf("case %s: incorrect status code updating profile: expected %d but got %d", c.name, http.StatusOK, status)
+			}
+			continue
+		}
+		if status := patchMe(); status != http.StatusForbidden {
+			t.Errorf("case %s: incorrect status code updating profile before verifying: expected %d but got %d", c.name, http.StatusForbidden, status)
+		}
+		//verifying the email lifts the restriction without signing in again
+		if _, err := ctx.UserStore.(users.VerificationStore).VerifyEmail(context.Background(), user.ID, user.Email); err != nil {
+			t.Fatalf("case %s: error verifying email: %v", c.name, err)
+		}
+		if status := patchMe(); status != http.StatusOK {
+			t.Errorf("case %s: incorrect status code updating profile after verifying: expected %d but got %d", c.name, http.StatusOK, status)
+		}
+	}
+}
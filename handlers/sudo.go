@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"assignments-jelauria/servers/gateway/models/users"
+	"assignments-jelauria/servers/gateway/sessions"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//reauthRequiredMsg is the response body sent when a session
+//must reauthenticate before making a sensitive change
+const reauthRequiredMsg = "Please enter your password again to make this change."
+
+//EnsureRecentAuth is a middleware that only passes the request on to
+//`handler` if the user gave their password within the SudoDuration,
+//by signing in or by reauthenticating with POST /v1/sessions/mine.
+//Other requests are answered with a 403, so that a stolen session
+//can't be used to take over the account. It must be wrapped with
+//EnsureAuth.
+func (c *Context) EnsureRecentAuth(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state, ok := SessionStateFromContext(r.Context())
+		if !ok {
+			respondError(w, http.StatusUnauthorized, ErrCodeUnauthorized, unauthorizedMsg)
+			return
+		}
+		if !c.recentlyAuthenticated(state) {
+			respondReauthRequired(w)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+//recentlyAuthenticated reports whether the user of the
+//session gave their password within the SudoDuration
+func (c *Context) recentlyAuthenticated(state *SessionState) bool {
+	return c.SudoDuration <= 0 || time.Since(state.AuthTime) <= c.SudoDuration
+}
+
+//respondReauthRequired writes the error response for a
+//session that must reauthenticate
+func respondReauthRequired(w http.ResponseWriter) {
+	respondError(w, http.StatusForbidden, ErrCodeReauthRequired, reauthRequiredMsg)
+}
+
+//reauthenticate checks the password of the signed-in user and, if it
+//is correct, records that they just authenticated in the current
+//session. It must be wrapped with EnsureAuth.
+func (c *Context) reauthenticate(w http.ResponseWriter, r *http.Request) {
+	currState, _ := SessionStateFromContext(r.Context())
+	sid, _ := SessionIDFromContext(r.Context())
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		respondError(w, http.StatusUnsupportedMediaType, ErrCodeUnsupportedMediaType, "Request body must be in JSON.")
+		return
+	}
+	var reauth users.Reauthentication
+	if err := json.NewDecoder(r.Body).Decode(&reauth); err != nil {
+		respondError(w, http.StatusBadRequest, ErrCodeBadRequest, "Request body is not valid JSON.")
+		return
+	}
+	//the session state doesn't include the password hash,
+	//so get the current user from the store
+	user, err := c.UserStore.GetByID(r.Context(), currState.AuthUser.ID)
+	if err != nil {
+		respondInternalError(w, err)
+		return
+	}
+	if err := user.Authenticate(reauth.Password); err != nil {
+		respondError(w, http.StatusForbidden, ErrCodeInvalidCredentials, "Password is incorrect.")
+		return
+	}
+	currState.AuthTime = time.Now()
+	if err := c.saveSession(r.Context(), sid, currState); err != nil {
+		if err == sessions.ErrStateNotFound {
+			respondError(w, http.StatusUnauthorized, ErrCodeUnauthorized, unauthorizedMsg)
+		} else {
+			respondUnavailable(w, err)
+		}
+		return
+	}
+	w.Write([]byte("reauthenticated"))
+}
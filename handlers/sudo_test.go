@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"assignments-jelauria/servers/gateway/sessions"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEnsureRecentAuth(t *testing.T) {
+	cases := []struct {
+		name           string
+		sudoDuration   time.Duration
+		authAge        time.Duration
+		expectedStatus int
+	}{
+		{
+			"No Sudo Duration",
+			0,
+			24 * time.Hour,
+			http.StatusOK,
+		},
+		{
+			"Recently Authenticated",
+			15 * time.Minute,
+			time.Minute,
+			http.StatusOK,
+		},
+		{
+			"Authenticated Too Long Ago",
+			15 * time.Minute,
+			time.Hour,
+			http.StatusForbidden,
+		},
+	}
+
+	for _, c := range cases {
+		ctx := &Context{SudoDuration: c.sudoDuration}
+		state := &SessionState{AuthTime: time.Now().Add(-c.authAge)}
+		req := httptest.NewRequest(http.MethodPatch, "/v1/users/me/password", nil)
+		req = req.WithContext(context.WithValue(req.Context(), sessionStateKey, state))
+		resp := httptest.NewRecorder()
+		ctx.EnsureRecentAuth(func(w http.ResponseWriter, r *http.Request) {})(resp, req)
+		if resp.Code != c.expectedStatus {
+			t.Errorf("case %s: incorrect status code: expected %d but got %d", c.name, c.expectedStatus, resp.Code)
+		}
+	}
+}
+
+func TestReauthenticate(t *testing.T) {
+	ctx, user := newTestContext(t)
+	ctx.SudoDuration = 15 * time.Minute
+	sid := newTestSession(t, ctx, user)
+	//pretend the user signed in an hour ago
+	state := &SessionState{}
+	if err := ctx.SeshStore.Get(context.Background(), sid, state); err != nil {
+		t.Fatalf("error getting session state: %v", err)
+	}
+	state.AuthTime = time.Now().Add(-time.Hour)
+	if err := ctx.SeshStore.Save(context.Background(), sid, state); err != nil {
+		t.Fatalf("error saving session state: %v", err)
+	}
+
+	changeEmail := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/v1/users/me", strings.NewReader(`{"email": "toph@beifong.com"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+sid.String())
+		resp := httptest.NewRecorder()
+		ctx.EnsureAuth(ctx.SpecificUsersHandler)(resp, req)
+		return resp
+	}
+	reauthenticate := func(password string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/sessions/mine", strings.NewReader(`{"password": "`+password+`"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+sid.String())
+		resp := httptest.NewRecorder()
+		ctx.SpecificSessionHandler(resp, req)
+		return resp
+	}
+
+	if resp := changeEmail(); resp.Code != http.StatusForbidden {
+		t.Errorf("incorrect status code changing email before reauthenticating: expected %d but got %d", http.StatusForbidden, resp.Code)
+	}
+	if resp := reauthenticate("Aang"); resp.Code != http.StatusForbidden {
+		t.Errorf("incorrect status code reauthenticating with the wrong password: expected %d but got %d", http.StatusForbidden, resp.Code)
+	}
+	if resp := changeEmail(); resp.Code != http.StatusForbidden {
+		t.Errorf("incorrect status code changing email after failing to reauthenticate: expected %d but got %d", http.StatusForbidden, resp.Code)
+	}
+	if resp := reauthenticate("TophRocks1337"); resp.Code != http.StatusOK {
+		t.Fatalf("incorrect status code reauthenticating: expected %d but got %d", http.StatusOK, resp.Code)
+	}
+	if resp := changeEmail(); resp.Code != http.StatusOK {
+		t.Errorf("incorrect status code changing email after reauthenticating: expected %d but got %d", http.StatusOK, resp.Code)
+	}
+
+	//only the current session can be reauthenticated
+	req := httptest.NewRequest(http.MethodPost, "/v1/sessions/"+sid.Handle(), nil)
+	resp := httptest.NewRecorder()
+	ctx.SpecificSessionHandler(resp, req)
+	if resp.Code != http.StatusForbidden {
+		t.Errorf("incorrect status code reauthenticating another session: expected %d but got %d", http.StatusForbidden, resp.Code)
+	}
+}
+
+//TestReauthenticateEndedSession checks that a session ended while
+//a request was reauthenticating it isn't saved again
+func TestReauthenticateEndedSession(t *testing.T) {
+	ctx, user := newTestContext(t)
+	sid := newTestSession(t, ctx, user)
+	//EnsureAuth loads the state before the user's sessions are ended
+	state := &SessionState{}
+	if err := ctx.SeshStore.Get(context.Background(), sid, state); err != nil {
+		t.Fatalf("error getting session state: %v", err)
+	}
+	if err := ctx.endUserSessions(context.Background(), user.ID); err != nil {
+		t.Fatalf("error ending sessions: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/sessions/mine", strings.NewReader(`{"password": "TophRocks1337"}`))
+	req.Header.Set("Content-Type", "application/json")
+	reqCtx := context.WithValue(req.Context(), sessionStateKey, state)
+	reqCtx = context.WithValue(reqCtx, sessionIDKey, sid)
+	resp := httptest.NewRecorder()
+	ctx.reauthenticate(resp, req.WithContext(reqCtx))
+	if resp.Code != http.StatusUnauthorized {
+		t.Errorf("incorrect status code reauthenticating an ended session: expected %d but got %d", http.StatusUnauthorized, resp.Code)
+	}
+	if err := ctx.SeshStore.Get(context.Background(), sid, &SessionState{}); err != sessions.ErrStateNotFound {
+		t.Errorf("incorrect error getting the state of an ended session: expected %v but got %v", sessions.ErrStateNotFound, err)
+	}
+}
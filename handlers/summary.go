@@ -12,9 +12,6 @@ import (
 	"golang.org/x/net/html"
 )
 
-const headerCORS = "Access-Control-Allow-Origin"
-const corsAnyOrig = "*"
-
 //PreviewImage represents a preview image for a page
 type PreviewImage struct {
 	URL       string `json:"url,omitempty"`
@@ -44,25 +41,23 @@ type PageSummary struct {
 //a JSON-encoded PageSummary struct containing the page summary
 //meta-data.
 func SummaryHandler(w http.ResponseWriter, r *http.Request) {
-	w.Header().Add(headerCORS, corsAnyOrig)
-
 	rQuery := r.URL.Query()
 	url := rQuery.Get("url")
 
 	if len(url) == 0 {
-		http.Error(w, "Url not supplied", http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, ErrCodeBadRequest, "Url not supplied")
 		return
 	}
 
 	stream, err := fetchHTML(url)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		respondError(w, http.StatusBadRequest, ErrCodeBadRequest, err.Error())
 		return
 	}
 
 	sumData, err := extractSummary(url, stream)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondInternalError(w, err)
 		return
 	}
 
@@ -70,7 +65,7 @@ func SummaryHandler(w http.ResponseWriter, r *http.Request) {
 
 	finSum, err := json.Marshal(sumData)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		respondInternalError(w, err)
 		return
 	}
 
@@ -0,0 +1,68 @@
+package mailer
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/smtp"
+	"strings"
+)
+
+//Message represents an email message to send
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+//Sender represents something that can send email messages.
+//This is an abstract interface so that messages can be
+//delivered over SMTP in production, but simply logged
+//during local development and testing.
+type Sender interface {
+	//Send sends the message, returning an error if it could not be sent
+	Send(msg *Message) error
+}
+
+//LogSender is a Sender that writes messages to a log
+//instead of delivering them. This should be used only for
+//local development and testing.
+type LogSender struct {
+	logger *log.Logger
+}
+
+//NewLogSender constructs a new LogSender that writes messages to `w`,
+//which may be a file, os.Stdout, or a buffer in a test
+func NewLogSender(w io.Writer) *LogSender {
+	return &LogSender{log.New(w, "", log.LstdFlags)}
+}
+
+//Send writes the message to the log
+func (ls *LogSender) Send(msg *Message) error {
+	ls.logger.Printf("To: %s\nSubject: %s\n\n%s\n", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+//SMTPSender is a Sender that delivers messages through an SMTP server
+type SMTPSender struct {
+	//Addr is the host:port of the SMTP server
+	Addr string
+	//From is the address messages are sent from
+	From string
+	//Auth is used to authenticate with the server, and may be nil
+	Auth smtp.Auth
+}
+
+//NewSMTPSender constructs a new SMTPSender
+func NewSMTPSender(addr string, from string, auth smtp.Auth) *SMTPSender {
+	return &SMTPSender{addr, from, auth}
+}
+
+//Send delivers the message through the SMTP server
+func (ss *SMTPSender) Send(msg *Message) error {
+	if strings.ContainsAny(msg.To, "\r\n") || strings.ContainsAny(msg.Subject, "\r\n") {
+		return fmt.Errorf("mailer: message headers may not contain line breaks")
+	}
+	data := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\n\r\n%s\r\n", ss.From, msg.To, msg.Subject, msg.Body)
+	return smtp.SendMail(ss.Addr, ss.Auth, ss.From, []string{msg.To}, []byte(data))
+}
@@ -0,0 +1,37 @@
+package mailer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestLogSender(t *testing.T) {
+	buf := &bytes.Buffer{}
+	sender := NewLogSender(buf)
+	msg := &Message{
+		To:      "jelauria@uw.edu",
+		Subject: "Reset your password",
+		Body:    "Use this code to reset your password: abc123",
+	}
+	if err := sender.Send(msg); err != nil {
+		t.Fatalf("unexpected error sending message: %v", err)
+	}
+	logged := buf.String()
+	for _, expected := range []string{msg.To, msg.Subject, msg.Body} {
+		if !strings.Contains(logged, expected) {
+			t.Errorf("logged message does not contain `%s`:\n%s", expected, logged)
+		}
+	}
+}
+
+func TestSMTPSenderRejectsHeaderInjection(t *testing.T) {
+	sender := NewSMTPSender("127.0.0.1:0", "noreply@example.com", nil)
+	msg := &Message{
+		To:      "jelauria@uw.edu\r\nBcc: everyone@example.com",
+		Subject: "Reset your password",
+	}
+	if err := sender.Send(msg); err == nil {
+		t.Error("expected error when sending a message with a line break in a header")
+	}
+}
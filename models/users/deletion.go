@@ -0,0 +1,54 @@
+package users
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+//AccountDeletion represents a user asking to delete their account.
+//They must give their password again, so that a stolen session
+//isn't enough to delete an account.
+type AccountDeletion struct {
+	Password string `json:"password"`
+}
+
+//Purger permanently deletes the users in Store who soft-deleted
+//their account more than GracePeriod ago, checking every Interval
+type Purger struct {
+	Store       SoftDeleteStore
+	GracePeriod time.Duration
+	Interval    time.Duration
+}
+
+//NewPurger constructs a Purger for `store`
+func NewPurger(store SoftDeleteStore, gracePeriod time.Duration, interval time.Duration) *Purger {
+	return &Purger{store, gracePeriod, interval}
+}
+
+//Purge deletes the users whose grace period is over at `now`,
+//and returns how many users were deleted
+func (p *Purger) Purge(ctx context.Context, now time.Time) (int64, error) {
+	return p.Store.PurgeDeleted(ctx, now.Add(-p.GracePeriod))
+}
+
+//Run purges users right away and then every Interval, until
+//`ctx` is done. Errors are logged, and purging tried again
+//at the next interval.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+	for {
+		purged, err := p.Purge(ctx, time.Now())
+		if err != nil {
+			log.Printf("error purging deleted users: %v", err)
+		} else if purged > 0 {
+			log.Printf("purged %d deleted users", purged)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
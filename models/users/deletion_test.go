@@ -0,0 +1,83 @@
+package users
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestPurger(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name         string
+		deletedAt    *time.Time
+		expectPurged bool
+	}{
+		{
+			"Not Deleted",
+			nil,
+			false,
+		},
+		{
+			"In Grace Period",
+			timePtr(now.Add(-time.Hour)),
+			false,
+		},
+		{
+			"Grace Period Over",
+			timePtr(now.Add(-48 * time.Hour)),
+			true,
+		},
+	}
+
+	for _, c := range cases {
+		store := NewMemStore()
+		user, _ := store.Insert(context.Background(), &User{Email: "toph@test.com", UserName: "TheBlindBandit"})
+		if err := store.SetDeletedAt(context.Background(), user.ID, c.deletedAt); err != nil {
+			t.Fatalf("case %s: unexpected error soft-deleting user: %v", c.name, err)
+		}
+		purger := NewPurger(store, 24*time.Hour, time.Hour)
+		purged, err := purger.Purge(context.Background(), now)
+		if err != nil {
+			t.Errorf("case %s: unexpected error purging users: %v", c.name, err)
+			continue
+		}
+		if (purged == 1) != c.expectPurged {
+			t.Errorf("case %s: incorrect number of users purged: %d", c.name, purged)
+		}
+		if _, err := store.GetByID(context.Background(), user.ID); (err == ErrUserNotFound) != c.expectPurged {
+			t.Errorf("case %s: incorrect error getting user after purge: %v", c.name, err)
+		}
+	}
+}
+
+func TestPurgerRun(t *testing.T) {
+	store := NewMemStore()
+	user, _ := store.Insert(context.Background(), &User{Email: "toph@test.com", UserName: "TheBlindBandit"})
+	store.SetDeletedAt(context.Background(), user.ID, timePtr(time.Now().Add(-time.Minute)))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		NewPurger(store, time.Millisecond, time.Millisecond).Run(ctx)
+		close(done)
+	}()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := store.GetByID(context.Background(), user.ID); err == ErrUserNotFound {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Errorf("user was not purged by Run")
+}
+
+//timePtr returns a pointer to `t`, for setting DeletedAt
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,217 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+//Dialect hides the differences between the SQL databases a SQLStore
+//can use. Queries are written once with MySQL-style `?` placeholders,
+//and the Dialect adapts them for its database.
+type Dialect interface {
+	//Name returns the name of the database/sql driver for the dialect,
+	//which is also the directory holding the dialect's migrations
+	Name() string
+
+	//Rebind rewrites the `?` placeholders in `query` into
+	//the dialect's placeholder style
+	Rebind(query string) string
+
+	//Insert runs the insert statement `query` with `args`
+	//and returns the ID of the newly-inserted row
+	Insert(ctx context.Context, db *sql.DB, query string, args ...interface{}) (int64, error)
+
+	//DuplicateColumn returns the column of the unique index violated
+	//by `err`, or false if `err` isn't a duplicate-key error
+	DuplicateColumn(err error) (string, bool)
+
+	//LockMigrations takes a lock on `conn` that only one Migrator
+	//can hold at a time, and returns a function that releases it
+	LockMigrations(ctx context.Context, conn *sql.Conn) (func() error, error)
+}
+
+//Dialects supported by SQLStore
+var (
+	MySQL    Dialect = mysqlDialect{}
+	Postgres Dialect = postgresDialect{}
+	SQLite   Dialect = sqliteDialect{}
+)
+
+//DialectFor returns the Dialect for the database/sql driver
+//named `driverName`: "mysql", "postgres" or "sqlite3"
+func DialectFor(driverName string) (Dialect, error) {
+	for _, dialect := range []Dialect{MySQL, Postgres, SQLite} {
+		if dialect.Name() == driverName {
+			return dialect, nil
+		}
+	}
+	return nil, fmt.Errorf("unsupported database driver %q: must be mysql, postgres or sqlite3", driverName)
+}
+
+//insertWithLastInsertID runs an insert statement and gets the
+//new row's ID from the driver, for databases that report it
+func insertWithLastInsertID(ctx context.Context, db *sql.DB, query string, args ...interface{}) (int64, error) {
+	res, err := db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+//migrationLockName names the lock taken by LockMigrations, and
+//migrationLockKey is the same for databases that key locks by number
+const (
+	migrationLockName = "schema_migrations"
+	migrationLockKey  = 7253841069
+)
+
+//migrationLockTimeout is how many seconds LockMigrations
+//waits for another Migrator to release the lock
+const migrationLockTimeout = 60
+
+//ErrMigrationLocked is returned when another Migrator
+//held the migration lock for too long
+var ErrMigrationLocked = errors.New("timed out waiting for another migration to finish")
+
+//mysqlErrDuplicateEntry is the MySQL error number
+//for a violated unique index
+const mysqlErrDuplicateEntry = 1062
+
+//mysqlDialect is the Dialect for MySQL
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string { return "mysql" }
+
+func (mysqlDialect) Rebind(query string) string { return query }
+
+func (mysqlDialect) Insert(ctx context.Context, db *sql.DB, query string, args ...interface{}) (int64, error) {
+	return insertWithLastInsertID(ctx, db, query, args...)
+}
+
+//DuplicateColumn reads the name of the index from the end of the
+//error message, which MySQL 8 prefixes with the table name, e.g.
+//  Duplicate entry 'toph@test.com' for key 'users.email'
+//Unique indexes are named after their column in the migrations.
+func (mysqlDialect) DuplicateColumn(err error) (string, bool) {
+	mysqlErr, ok := err.(*mysql.MySQLError)
+	if !ok || mysqlErr.Number != mysqlErrDuplicateEntry {
+		return "", false
+	}
+	key := strings.TrimSuffix(mysqlErr.Message, "'")
+	key = key[strings.LastIndexAny(key, "'.")+1:]
+	return key, true
+}
+
+//LockMigrations takes a named lock with GET_LOCK,
+//which is released when `conn` is closed if not before
+func (mysqlDialect) LockMigrations(ctx context.Context, conn *sql.Conn) (func() error, error) {
+	var locked sql.NullInt64
+	if err := conn.QueryRowContext(ctx, "select get_lock(?, ?)", migrationLockName, migrationLockTimeout).Scan(&locked); err != nil {
+		return nil, err
+	}
+	if !locked.Valid || locked.Int64 != 1 {
+		return nil, ErrMigrationLocked
+	}
+	return func() error {
+		_, err := conn.ExecContext(context.Background(), "select release_lock(?)", migrationLockName)
+		return err
+	}, nil
+}
+
+//postgresErrUniqueViolation is the Postgres SQLSTATE
+//for a violated unique constraint
+const postgresErrUniqueViolation = "23505"
+
+//postgresDialect is the Dialect for PostgreSQL
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return "postgres" }
+
+//Rebind numbers the placeholders, as in `where id=$1`
+func (postgresDialect) Rebind(query string) string {
+	var rebound strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			rebound.WriteString("$" + strconv.Itoa(n))
+		} else {
+			rebound.WriteRune(r)
+		}
+	}
+	return rebound.String()
+}
+
+//Insert asks for the new ID with `returning id`,
+//since lib/pq doesn't support LastInsertId
+func (pd postgresDialect) Insert(ctx context.Context, db *sql.DB, query string, args ...interface{}) (int64, error) {
+	var id int64
+	err := db.QueryRowContext(ctx, pd.Rebind(query)+" returning id", args...).Scan(&id)
+	return id, err
+}
+
+//DuplicateColumn reads the column from the name of the violated
+//constraint, which Postgres names like `users_email_key`
+func (postgresDialect) DuplicateColumn(err error) (string, bool) {
+	pqErr, ok := err.(*pq.Error)
+	if !ok || pqErr.Code != postgresErrUniqueViolation {
+		return "", false
+	}
+	column := strings.TrimPrefix(pqErr.Constraint, pqErr.Table+"_")
+	return strings.TrimSuffix(column, "_key"), true
+}
+
+//LockMigrations takes a session-level advisory lock, which is
+//released when `conn` is closed if not before. It waits for as long
+//as `ctx` allows.
+func (postgresDialect) LockMigrations(ctx context.Context, conn *sql.Conn) (func() error, error) {
+	if _, err := conn.ExecContext(ctx, "select pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return nil, err
+	}
+	return func() error {
+		_, err := conn.ExecContext(context.Background(), "select pg_advisory_unlock($1)", migrationLockKey)
+		return err
+	}, nil
+}
+
+//sqliteDialect is the Dialect for SQLite
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string { return "sqlite3" }
+
+func (sqliteDialect) Rebind(query string) string { return query }
+
+func (sqliteDialect) Insert(ctx context.Context, db *sql.DB, query string, args ...interface{}) (int64, error) {
+	return insertWithLastInsertID(ctx, db, query, args...)
+}
+
+//sqliteUniqueFailed begins the message of the
+//error for a violated unique constraint in SQLite
+const sqliteUniqueFailed = "UNIQUE constraint failed: "
+
+//DuplicateColumn reads the column from the end of the error message, e.g.
+//  UNIQUE constraint failed: users.email
+//The message is matched rather than the driver's error type, which
+//only exists when the driver is built with cgo, so that the package
+//still builds without cgo for the other databases.
+func (sqliteDialect) DuplicateColumn(err error) (string, bool) {
+	msg := err.Error()
+	if !strings.HasPrefix(msg, sqliteUniqueFailed) {
+		return "", false
+	}
+	return msg[strings.LastIndex(msg, ".")+1:], true
+}
+
+//LockMigrations takes no lock, since SQLite has no locks that outlast
+//a transaction. SQLite databases are files used by a single server,
+//so only run one migrate command against each one at a time.
+func (sqliteDialect) LockMigrations(ctx context.Context, conn *sql.Conn) (func() error, error) {
+	return func() error { return nil }, nil
+}
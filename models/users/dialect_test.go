@@ -0,0 +1,112 @@
+package users
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+)
+
+func TestRebind(t *testing.T) {
+	query := "update users set first_name=?, last_name=? where id=?"
+	cases := []struct {
+		name     string
+		dialect  Dialect
+		expected string
+	}{
+		{
+			"MySQL",
+			MySQL,
+			query,
+		},
+		{
+			"Postgres",
+			Postgres,
+			"update users set first_name=$1, last_name=$2 where id=$3",
+		},
+		{
+			"SQLite",
+			SQLite,
+			query,
+		},
+	}
+	for _, c := range cases {
+		if rebound := c.dialect.Rebind(query); rebound != c.expected {
+			t.Errorf("case %s: incorrect query: expected %q but got %q", c.name, c.expected, rebound)
+		}
+	}
+}
+
+func TestDuplicateColumn(t *testing.T) {
+	cases := []struct {
+		name           string
+		dialect        Dialect
+		err            error
+		expectedColumn string
+		expectedOK     bool
+	}{
+		{
+			"MySQL Email",
+			MySQL,
+			&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'test@test.com' for key 'email'"},
+			"email",
+			true,
+		},
+		{
+			"MySQL 8 UserName",
+			MySQL,
+			&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'username' for key 'users.username'"},
+			"username",
+			true,
+		},
+		{
+			"MySQL Other Error",
+			MySQL,
+			&mysql.MySQLError{Number: 1406, Message: "Data too long for column 'first_name' at row 1"},
+			"",
+			false,
+		},
+		{
+			"Postgres Email",
+			Postgres,
+			&pq.Error{Code: "23505", Table: "users", Constraint: "users_email_key"},
+			"email",
+			true,
+		},
+		{
+			"Postgres Other Error",
+			Postgres,
+			&pq.Error{Code: "23502", Table: "users", Column: "email"},
+			"",
+			false,
+		},
+		{
+			"Not A Driver Error",
+			Postgres,
+			errors.New("Duplicate entry"),
+			"",
+			false,
+		},
+		{
+			"SQLite Email",
+			SQLite,
+			errors.New("UNIQUE constraint failed: users.email"),
+			"email",
+			true,
+		},
+		{
+			"SQLite Other Error",
+			SQLite,
+			errors.New("NOT NULL constraint failed: users.email"),
+			"",
+			false,
+		},
+	}
+	for _, c := range cases {
+		column, ok := c.dialect.DuplicateColumn(c.err)
+		if column != c.expectedColumn || ok != c.expectedOK {
+			t.Errorf("case %s: expected (%q, %t) but got (%q, %t)", c.name, c.expectedColumn, c.expectedOK, column, ok)
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package users
+
+import (
+	"errors"
+)
+
+//ErrNoPendingEmail is returned when confirming a change to an email
+//address that is not the user's pending email, for example because
+//it was already confirmed or another change was asked for since
+var ErrNoPendingEmail = errors.New("email address is not pending confirmation")
+
+//EmailConfirmation represents a user confirming an email address
+//using the token that was emailed to it, either when signing up or
+//after asking to change their email address
+type EmailConfirmation struct {
+	Token string `json:"token"`
+}
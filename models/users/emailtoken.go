@@ -0,0 +1,80 @@
+package users
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+//ErrInvalidToken is returned when a signed token is malformed,
+//has been tampered with, has expired, or is for another purpose
+var ErrInvalidToken = errors.New("token is invalid or has expired")
+
+//Purposes of the signed tokens emailed to users. A token
+//can only be used for the purpose it was signed for.
+const (
+	PurposeVerifyEmail = "verify_email"
+	PurposeChangeEmail = "change_email"
+)
+
+//EmailTokenClaims are the contents of a signed email token
+type EmailTokenClaims struct {
+	Purpose string `json:"pur"`
+	UserID  int64  `json:"uid"`
+	Email   string `json:"email"`
+	Expires int64  `json:"exp"`
+}
+
+//SignEmailToken returns a token confirming that the user with the
+//given ID controls `email`, for the given purpose. The token is
+//signed with `key`, so it needn't be stored, and expires after `ttl`.
+//It has the form <base64 claims>.<base64 HMAC of the claims>
+func SignEmailToken(key []byte, purpose string, userID int64, email string, ttl time.Duration) (string, error) {
+	if len(key) == 0 {
+		return "", errors.New("signing key may not be empty")
+	}
+	claims, err := json.Marshal(&EmailTokenClaims{purpose, userID, email, time.Now().Add(ttl).Unix()})
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(claims)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(signToken(key, payload)), nil
+}
+
+//VerifyEmailToken checks the signature, expiry and purpose of a token
+//made by SignEmailToken, and returns its claims. ErrInvalidToken is
+//returned if the token can't be trusted.
+func VerifyEmailToken(key []byte, purpose string, token string) (*EmailTokenClaims, error) {
+	sep := strings.IndexByte(token, '.')
+	if sep < 0 {
+		return nil, ErrInvalidToken
+	}
+	payload := token[:sep]
+	sig, err := base64.RawURLEncoding.DecodeString(token[sep+1:])
+	if err != nil || !hmac.Equal(sig, signToken(key, payload)) {
+		return nil, ErrInvalidToken
+	}
+	claimsJSON, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	claims := &EmailTokenClaims{}
+	if err := json.Unmarshal(claimsJSON, claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if claims.Purpose != purpose || time.Now().Unix() >= claims.Expires {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+//signToken returns the HMAC-SHA256 of the token's payload
+func signToken(key []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
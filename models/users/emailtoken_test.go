@@ -0,0 +1,91 @@
+package users
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEmailToken(t *testing.T) {
+	key := []byte("test key")
+	token, err := SignEmailToken(key, PurposeChangeEmail, 42, "toph@test.com", time.Hour)
+	if err != nil {
+		t.Fatalf("error signing token: %v", err)
+	}
+	expired, err := SignEmailToken(key, PurposeChangeEmail, 42, "toph@test.com", -time.Second)
+	if err != nil {
+		t.Fatalf("error signing token: %v", err)
+	}
+	otherPurpose, err := SignEmailToken(key, PurposeVerifyEmail, 42, "toph@test.com", time.Hour)
+	if err != nil {
+		t.Fatalf("error signing token: %v", err)
+	}
+	//swap in the claims of another token, keeping the signature
+	other, _ := SignEmailToken(key, PurposeChangeEmail, 43, "aang@test.com", time.Hour)
+	forged := other[:strings.IndexByte(other, '.')] + token[strings.IndexByte(token, '.'):]
+
+	cases := []struct {
+		name        string
+		key         []byte
+		token       string
+		expectError bool
+	}{
+		{
+			"Valid Token",
+			key,
+			token,
+			false,
+		},
+		{
+			"Wrong Key",
+			[]byte("other key"),
+			token,
+			true,
+		},
+		{
+			"Expired Token",
+			key,
+			expired,
+			true,
+		},
+		{
+			"Other Purpose",
+			key,
+			otherPurpose,
+			true,
+		},
+		{
+			"Forged Claims",
+			key,
+			forged,
+			true,
+		},
+		{
+			"Malformed Token",
+			key,
+			"not a token",
+			true,
+		},
+	}
+
+	for _, c := range cases {
+		claims, err := VerifyEmailToken(c.key, PurposeChangeEmail, c.token)
+		if c.expectError {
+			if err != ErrInvalidToken {
+				t.Errorf("case %s: incorrect error: expected %v but got %v", c.name, ErrInvalidToken, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case %s: unexpected error: %v", c.name, err)
+			continue
+		}
+		if claims.UserID != 42 || claims.Email != "toph@test.com" {
+			t.Errorf("case %s: incorrect claims: %+v", c.name, claims)
+		}
+	}
+
+	if _, err := SignEmailToken(nil, PurposeChangeEmail, 42, "toph@test.com", time.Hour); err == nil {
+		t.Error("expected error when signing with an empty key")
+	}
+}
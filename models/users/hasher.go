@@ -0,0 +1,192 @@
+package users
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+//ErrUnknownHashFormat is returned when a password hash wasn't
+//produced by any of the known PasswordHashers
+var ErrUnknownHashFormat = errors.New("password hash format not recognized")
+
+//ErrPasswordMismatch is returned when a password doesn't match its hash
+var ErrPasswordMismatch = errors.New("password does not match")
+
+//PasswordHasher hashes passwords and verifies passwords against
+//hashes. Hashes must be self-describing, recording the algorithm and
+//parameters used to produce them, so that hashes produced by
+//different hashers can be told apart and live side by side.
+type PasswordHasher interface {
+	//Hash returns a new hash of the password
+	Hash(password string) ([]byte, error)
+
+	//Recognizes reports whether the hash was produced
+	//by this hasher's algorithm
+	Recognizes(hash []byte) bool
+
+	//Verify returns nil if the password matches the hash,
+	//or an error if it doesn't
+	Verify(hash []byte, password string) error
+
+	//NeedsRehash reports whether a hash this hasher recognizes
+	//was produced with weaker parameters than its current ones
+	NeedsRehash(hash []byte) bool
+}
+
+//DefaultHasher is the PasswordHasher used to hash new passwords.
+//Changing it at startup migrates users to the new algorithm or
+//parameters as they sign in.
+var DefaultHasher PasswordHasher = &BcryptHasher{Cost: bcryptCost}
+
+//hasherFor returns the hasher that recognizes the hash, preferring
+//DefaultHasher, or nil if none of the known hashers do
+func hasherFor(hash []byte) PasswordHasher {
+	known := []PasswordHasher{DefaultHasher, &BcryptHasher{Cost: bcryptCost}, NewArgon2idHasher()}
+	for _, hasher := range known {
+		if hasher.Recognizes(hash) {
+			return hasher
+		}
+	}
+	return nil
+}
+
+//BcryptHasher is a PasswordHasher using bcrypt
+type BcryptHasher struct {
+	//Cost is the bcrypt cost used for new hashes
+	Cost int
+}
+
+//Hash returns a new bcrypt hash of the password
+func (bh *BcryptHasher) Hash(password string) ([]byte, error) {
+	return bcrypt.GenerateFromPassword([]byte(password), bh.Cost)
+}
+
+//Recognizes reports whether the hash is a bcrypt hash
+func (bh *BcryptHasher) Recognizes(hash []byte) bool {
+	_, err := bcrypt.Cost(hash)
+	return err == nil
+}
+
+//Verify returns nil if the password matches the bcrypt hash
+func (bh *BcryptHasher) Verify(hash []byte, password string) error {
+	err := bcrypt.CompareHashAndPassword(hash, []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return ErrPasswordMismatch
+	}
+	return err
+}
+
+//NeedsRehash reports whether the bcrypt hash used a lower cost
+//than the hasher's current cost
+func (bh *BcryptHasher) NeedsRehash(hash []byte) bool {
+	cost, err := bcrypt.Cost(hash)
+	return err != nil || cost < bh.Cost
+}
+
+//argon2idPrefix starts every hash produced by an Argon2idHasher
+const argon2idPrefix = "$argon2id$"
+
+//Argon2idHasher is a PasswordHasher using argon2id. Its hashes
+//use the PHC string format, for example:
+//  $argon2id$v=19$m=65536,t=1,p=4$<base64 salt>$<base64 key>
+type Argon2idHasher struct {
+	//Time is the number of passes over the memory
+	Time uint32
+	//Memory is the amount of memory used, in KiB
+	Memory uint32
+	//Threads is the degree of parallelism
+	Threads uint8
+	//SaltLength and KeyLength are the lengths, in bytes,
+	//of the random salt and the derived key
+	SaltLength uint32
+	KeyLength  uint32
+}
+
+//NewArgon2idHasher constructs an Argon2idHasher with
+//the parameters recommended by RFC 9106
+func NewArgon2idHasher() *Argon2idHasher {
+	return &Argon2idHasher{
+		Time:       1,
+		Memory:     64 * 1024,
+		Threads:    4,
+		SaltLength: 16,
+		KeyLength:  32,
+	}
+}
+
+//Hash returns a new argon2id hash of the password
+func (ah *Argon2idHasher) Hash(password string) ([]byte, error) {
+	salt := make([]byte, ah.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	key := argon2.IDKey([]byte(password), salt, ah.Time, ah.Memory, ah.Threads, ah.KeyLength)
+	encoded := fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version,
+		ah.Memory, ah.Time, ah.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+	return []byte(encoded), nil
+}
+
+//Recognizes reports whether the hash is an argon2id hash
+func (ah *Argon2idHasher) Recognizes(hash []byte) bool {
+	return bytes.HasPrefix(hash, []byte(argon2idPrefix))
+}
+
+//Verify returns nil if the password matches the argon2id hash
+func (ah *Argon2idHasher) Verify(hash []byte, password string) error {
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return err
+	}
+	other := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrPasswordMismatch
+	}
+	return nil
+}
+
+//NeedsRehash reports whether the argon2id hash used weaker
+//parameters than the hasher's current ones
+func (ah *Argon2idHasher) NeedsRehash(hash []byte) bool {
+	params, salt, key, err := decodeArgon2id(hash)
+	if err != nil {
+		return true
+	}
+	return params.Time < ah.Time || params.Memory < ah.Memory || params.Threads < ah.Threads ||
+		uint32(len(salt)) < ah.SaltLength || uint32(len(key)) < ah.KeyLength
+}
+
+//decodeArgon2id parses an argon2id hash in the PHC string format
+//into its parameters, salt and key
+func decodeArgon2id(hash []byte) (*Argon2idHasher, []byte, []byte, error) {
+	parts := strings.Split(string(hash), "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, nil, nil, ErrUnknownHashFormat
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, ErrUnknownHashFormat
+	}
+	params := &Argon2idHasher{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil ||
+		params.Time < 1 || params.Threads < 1 {
+		return nil, nil, nil, ErrUnknownHashFormat
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, ErrUnknownHashFormat
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return nil, nil, nil, ErrUnknownHashFormat
+	}
+	return params, salt, key, nil
+}
@@ -0,0 +1,138 @@
+package users
+
+import (
+	"testing"
+)
+
+//fastArgon2idHasher returns an Argon2idHasher with
+//cheap parameters so that tests run quickly
+func fastArgon2idHasher() *Argon2idHasher {
+	return &Argon2idHasher{Time: 1, Memory: 1024, Threads: 1, SaltLength: 16, KeyLength: 32}
+}
+
+func TestPasswordHashers(t *testing.T) {
+	cases := []struct {
+		name   string
+		hasher PasswordHasher
+		other  PasswordHasher
+	}{
+		{
+			"Bcrypt",
+			&BcryptHasher{Cost: 4},
+			fastArgon2idHasher(),
+		},
+		{
+			"Argon2id",
+			fastArgon2idHasher(),
+			&BcryptHasher{Cost: 4},
+		},
+	}
+
+	for _, c := range cases {
+		hash, err := c.hasher.Hash("Toph!Rocks!#1337")
+		if err != nil {
+			t.Fatalf("case %s: unexpected error hashing password: %v", c.name, err)
+		}
+		if !c.hasher.Recognizes(hash) {
+			t.Errorf("case %s: hasher does not recognize its own hash", c.name)
+		}
+		if c.other.Recognizes(hash) {
+			t.Errorf("case %s: a different hasher recognizes the hash", c.name)
+		}
+		if err := c.hasher.Verify(hash, "Toph!Rocks!#1337"); err != nil {
+			t.Errorf("case %s: unexpected error verifying correct password: %v", c.name, err)
+		}
+		if err := c.hasher.Verify(hash, "Toh!Rocks!#1337"); err != ErrPasswordMismatch {
+			t.Errorf("case %s: incorrect error verifying incorrect password: expected %v but got %v", c.name, ErrPasswordMismatch, err)
+		}
+		if c.hasher.NeedsRehash(hash) {
+			t.Errorf("case %s: hash made with current parameters should not need rehashing", c.name)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	cheapBcrypt := &BcryptHasher{Cost: 4}
+	strongBcrypt := &BcryptHasher{Cost: 5}
+	cheapArgon := fastArgon2idHasher()
+	strongArgon := fastArgon2idHasher()
+	strongArgon.Time = 2
+
+	bcryptHash, _ := cheapBcrypt.Hash("TophRocks1337")
+	argonHash, _ := cheapArgon.Hash("TophRocks1337")
+
+	origHasher := DefaultHasher
+	defer func() { DefaultHasher = origHasher }()
+
+	cases := []struct {
+		name          string
+		defaultHasher PasswordHasher
+		passHash      []byte
+		expected      bool
+	}{
+		{
+			"Current Bcrypt Cost",
+			cheapBcrypt,
+			bcryptHash,
+			false,
+		},
+		{
+			"Outdated Bcrypt Cost",
+			strongBcrypt,
+			bcryptHash,
+			true,
+		},
+		{
+			"Bcrypt Hash With Argon2id Default",
+			cheapArgon,
+			bcryptHash,
+			true,
+		},
+		{
+			"Current Argon2id Parameters",
+			cheapArgon,
+			argonHash,
+			false,
+		},
+		{
+			"Outdated Argon2id Parameters",
+			strongArgon,
+			argonHash,
+			true,
+		},
+		{
+			"Argon2id Hash With Bcrypt Default",
+			cheapBcrypt,
+			argonHash,
+			true,
+		},
+	}
+
+	for _, c := range cases {
+		DefaultHasher = c.defaultHasher
+		u := &User{PassHash: c.passHash}
+		if err := u.Authenticate("TophRocks1337"); err != nil {
+			t.Errorf("case %s: unexpected error authenticating: %v", c.name, err)
+		}
+		if result := u.NeedsRehash(); result != c.expected {
+			t.Errorf("case %s: incorrect NeedsRehash result: expected %t but got %t", c.name, c.expected, result)
+		}
+	}
+}
+
+func TestAuthenticateUnknownHash(t *testing.T) {
+	cases := []struct {
+		name     string
+		passHash []byte
+	}{
+		{"No Hash", nil},
+		{"Plain Text", []byte("TophRocks1337")},
+		{"Malformed Argon2id", []byte("$argon2id$v=19$m=1024,t=0,p=1$c2FsdA$a2V5")},
+	}
+	for _, c := range cases {
+		u := &User{PassHash: c.passHash}
+		if err := u.Authenticate("TophRocks1337"); err == nil {
+			t.Errorf("case %s: expected error but did not get one", c.name)
+		}
+	}
+}
@@ -0,0 +1,219 @@
+package users
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+//MemStore is a Store kept in process memory. It enforces the same
+//uniqueness rules as the database, so it can stand in for a SQLStore
+//in tests and local development. Users are lost when the process
+//restarts, so it should never be used in production. It never
+//blocks on I/O, so it ignores the contexts passed to its methods.
+type MemStore struct {
+	mx     sync.RWMutex
+	nextID int64
+	users  map[int64]*User
+}
+
+//NewMemStore constructs and returns a new, empty MemStore
+func NewMemStore() *MemStore {
+	return &MemStore{nextID: 1, users: map[int64]*User{}}
+}
+
+//GetByID returns the User with the given ID
+func (ms *MemStore) GetByID(ctx context.Context, id int64) (*User, error) {
+	ms.mx.RLock()
+	defer ms.mx.RUnlock()
+	user, found := ms.users[id]
+	if !found {
+		return nil, ErrUserNotFound
+	}
+	return copyUser(user), nil
+}
+
+//GetByEmail returns the User with the given email
+func (ms *MemStore) GetByEmail(ctx context.Context, email string) (*User, error) {
+	ms.mx.RLock()
+	defer ms.mx.RUnlock()
+	return ms.find(func(u *User) bool { return u.Email == email })
+}
+
+//GetByUserName returns the User with the given Username
+func (ms *MemStore) GetByUserName(ctx context.Context, username string) (*User, error) {
+	ms.mx.RLock()
+	defer ms.mx.RUnlock()
+	return ms.find(func(u *User) bool { return u.UserName == username })
+}
+
+//Insert inserts the user into the store, and returns
+//the newly-inserted User, complete with its assigned ID
+func (ms *MemStore) Insert(ctx context.Context, user *User) (*User, error) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	if _, err := ms.find(func(u *User) bool { return u.Email == user.Email }); err == nil {
+		return nil, ErrEmailTaken
+	}
+	if _, err := ms.find(func(u *User) bool { return u.UserName == user.UserName }); err == nil {
+		return nil, ErrUserNameTaken
+	}
+	user.ID = ms.nextID
+	ms.nextID++
+	ms.users[user.ID] = copyUser(user)
+	return user, nil
+}
+
+//Update applies UserUpdates to the given user ID
+//and returns the newly-updated user
+func (ms *MemStore) Update(ctx context.Context, id int64, updates *Updates) (*User, error) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	user, found := ms.users[id]
+	if !found {
+		return nil, ErrUserNotFound
+	}
+	if updates.Email != nil {
+		email := strings.TrimSpace(*updates.Email)
+		if _, err := ms.find(func(u *User) bool { return u.ID != id && u.Email == email }); err == nil {
+			return nil, ErrEmailTaken
+		}
+	}
+	if updates.UserName != nil {
+		if _, err := ms.find(func(u *User) bool { return u.ID != id && u.UserName == *updates.UserName }); err == nil {
+			return nil, ErrUserNameTaken
+		}
+	}
+	updates.apply(user)
+	return copyUser(user), nil
+}
+
+//SetPassHash replaces the password hash of the user with the given ID
+func (ms *MemStore) SetPassHash(ctx context.Context, id int64, passHash []byte) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	user, found := ms.users[id]
+	if !found {
+		return ErrUserNotFound
+	}
+	user.PassHash = append([]byte(nil), passHash...)
+	return nil
+}
+
+//SetPendingEmail saves `email` as the address the user with
+//the given ID wants to change to, until they confirm it
+func (ms *MemStore) SetPendingEmail(ctx context.Context, id int64, email string) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	user, found := ms.users[id]
+	if !found {
+		return ErrUserNotFound
+	}
+	user.PendingEmail = email
+	return nil
+}
+
+//ConfirmEmail makes `email` the email address of the user with the
+//given ID, as long as it is still their pending email
+func (ms *MemStore) ConfirmEmail(ctx context.Context, id int64, email string) (*User, error) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	user, found := ms.users[id]
+	if !found {
+		return nil, ErrUserNotFound
+	}
+	if len(email) == 0 || user.PendingEmail != email {
+		return nil, ErrNoPendingEmail
+	}
+	if _, err := ms.find(func(u *User) bool { return u.ID != id && u.Email == email }); err == nil {
+		return nil, ErrEmailTaken
+	}
+	user.Email = email
+	user.EmailVerified = true
+	user.PendingEmail = ""
+	if !user.HasCustomPhoto() {
+		user.PhotoURL = gravatarURL(email)
+	}
+	return copyUser(user), nil
+}
+
+//VerifyEmail marks the email address of the user with the given
+//ID as verified, as long as their email is still `email`
+func (ms *MemStore) VerifyEmail(ctx context.Context, id int64, email string) (*User, error) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	user, found := ms.users[id]
+	if !found {
+		return nil, ErrUserNotFound
+	}
+	if user.Email != email {
+		return nil, ErrInvalidToken
+	}
+	user.EmailVerified = true
+	return copyUser(user), nil
+}
+
+//Delete deletes the user with the given ID
+func (ms *MemStore) Delete(ctx context.Context, id int64) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	delete(ms.users, id)
+	return nil
+}
+
+//SetDeletedAt soft-deletes the user with the given ID,
+//or restores them if `deletedAt` is nil
+func (ms *MemStore) SetDeletedAt(ctx context.Context, id int64, deletedAt *time.Time) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	user, found := ms.users[id]
+	if !found {
+		return ErrUserNotFound
+	}
+	user.DeletedAt = copyTime(deletedAt)
+	return nil
+}
+
+//PurgeDeleted deletes every user soft-deleted at or before `before`
+func (ms *MemStore) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	var purged int64
+	for id, user := range ms.users {
+		if user.DeletedAt != nil && !user.DeletedAt.After(before) {
+			delete(ms.users, id)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+//find returns a copy of the first user matching `match`,
+//or ErrUserNotFound. The caller must hold the lock.
+func (ms *MemStore) find(match func(u *User) bool) (*User, error) {
+	for _, user := range ms.users {
+		if match(user) {
+			return copyUser(user), nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+//copyUser returns a deep copy of the user, so that callers
+//can't change the users held in a MemStore
+func copyUser(user *User) *User {
+	userCopy := *user
+	userCopy.PassHash = append([]byte(nil), user.PassHash...)
+	userCopy.DeletedAt = copyTime(user.DeletedAt)
+	return &userCopy
+}
+
+//copyTime returns a copy of the time `t` points to, or nil
+func copyTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	tCopy := *t
+	return &tCopy
+}
@@ -0,0 +1,178 @@
+package users
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestMemStore(t *testing.T) {
+	store := NewMemStore()
+	toph := &User{Email: "toph@test.com", PassHash: []byte("passhash123"), UserName: "TheBlindBandit", FirstName: "Toph"}
+	inserted, err := store.Insert(context.Background(), toph)
+	if err != nil {
+		t.Fatalf("unexpected error inserting user: %v", err)
+	}
+	if inserted.ID == 0 {
+		t.Errorf("inserted user was not assigned an ID")
+	}
+
+	cases := []struct {
+		name        string
+		get         func() (*User, error)
+		expectError error
+	}{
+		{
+			"Get By ID",
+			func() (*User, error) { return store.GetByID(context.Background(), inserted.ID) },
+			nil,
+		},
+		{
+			"Get By Email",
+			func() (*User, error) { return store.GetByEmail(context.Background(), "toph@test.com") },
+			nil,
+		},
+		{
+			"Get By UserName",
+			func() (*User, error) { return store.GetByUserName(context.Background(), "TheBlindBandit") },
+			nil,
+		},
+		{
+			"ID Not Found",
+			func() (*User, error) { return store.GetByID(context.Background(), inserted.ID+1) },
+			ErrUserNotFound,
+		},
+		{
+			"Email Not Found",
+			func() (*User, error) { return store.GetByEmail(context.Background(), "aang@test.com") },
+			ErrUserNotFound,
+		},
+		{
+			"UserName Not Found",
+			func() (*User, error) { return store.GetByUserName(context.Background(), "Aang") },
+			ErrUserNotFound,
+		},
+	}
+
+	for _, c := range cases {
+		user, err := c.get()
+		if err != c.expectError {
+			t.Errorf("case %s: incorrect error: expected %v but got %v", c.name, c.expectError, err)
+			continue
+		}
+		if err == nil && (user.ID != inserted.ID || user.UserName != toph.UserName || string(user.PassHash) != "passhash123") {
+			t.Errorf("case %s: incorrect user returned: %+v", c.name, user)
+		}
+	}
+}
+
+func TestMemStoreUniqueness(t *testing.T) {
+	store := NewMemStore()
+	if _, err := store.Insert(context.Background(), &User{Email: "toph@test.com", UserName: "TheBlindBandit"}); err != nil {
+		t.Fatalf("unexpected error inserting user: %v", err)
+	}
+
+	cases := []struct {
+		name        string
+		user        *User
+		expectError error
+	}{
+		{
+			"Email Taken",
+			&User{Email: "toph@test.com", UserName: "MeltyBlob"},
+			ErrEmailTaken,
+		},
+		{
+			"UserName Taken",
+			&User{Email: "toph@beifong.com", UserName: "TheBlindBandit"},
+			ErrUserNameTaken,
+		},
+		{
+			"Both Unique",
+			&User{Email: "aang@test.com", UserName: "Aang"},
+			nil,
+		},
+	}
+
+	for _, c := range cases {
+		if _, err := store.Insert(context.Background(), c.user); err != c.expectError {
+			t.Errorf("case %s: incorrect error: expected %v but got %v", c.name, c.expectError, err)
+		}
+	}
+}
+
+func TestMemStoreUpdateAndDelete(t *testing.T) {
+	store := NewMemStore()
+	user, _ := store.Insert(context.Background(), &User{Email: "toph@test.com", UserName: "TheBlindBandit", FirstName: "Toph"})
+
+	//changing the returned user should not change the stored one
+	user.FirstName = "Changed"
+	if stored, _ := store.GetByID(context.Background(), user.ID); stored.FirstName != "Toph" {
+		t.Errorf("store shares its users with callers")
+	}
+
+	updated, err := store.Update(context.Background(), user.ID, &Updates{FirstName: stringPtr("Toph"), LastName: stringPtr("Beifong")})
+	if err != nil {
+		t.Fatalf("unexpected error updating user: %v", err)
+	}
+	if updated.LastName != "Beifong" {
+		t.Errorf("incorrect last name after update: expected Beifong but got %s", updated.LastName)
+	}
+	if err := store.SetPassHash(context.Background(), user.ID, []byte("newhash")); err != nil {
+		t.Errorf("unexpected error setting password hash: %v", err)
+	}
+	if stored, _ := store.GetByID(context.Background(), user.ID); string(stored.PassHash) != "newhash" {
+		t.Errorf("password hash was not replaced")
+	}
+
+	if err := store.Delete(context.Background(), user.ID); err != nil {
+		t.Errorf("unexpected error deleting user: %v", err)
+	}
+	if _, err := store.GetByID(context.Background(), user.ID); err != ErrUserNotFound {
+		t.Errorf("incorrect error getting deleted user: expected %v but got %v", ErrUserNotFound, err)
+	}
+	if _, err := store.Update(context.Background(), user.ID, &Updates{}); err != ErrUserNotFound {
+		t.Errorf("incorrect error updating deleted user: expected %v but got %v", ErrUserNotFound, err)
+	}
+	if err := store.SetPassHash(context.Background(), user.ID, []byte("newhash")); err != ErrUserNotFound {
+		t.Errorf("incorrect error setting password hash of deleted user: expected %v but got %v", ErrUserNotFound, err)
+	}
+}
+
+func TestMemStoreConcurrentInserts(t *testing.T) {
+	store := NewMemStore()
+	const numUsers = 50
+	var wg sync.WaitGroup
+	for i := 0; i < numUsers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			store.Insert(context.Background(), &User{Email: fmt.Sprintf("user%d@test.com", i), UserName: fmt.Sprintf("user%d", i)})
+			//every goroutine also tries to claim the same username
+			store.Insert(context.Background(), &User{Email: fmt.Sprintf("dup%d@test.com", i), UserName: "taken"})
+		}(i)
+	}
+	wg.Wait()
+
+	ids := map[int64]bool{}
+	for i := 0; i < numUsers; i++ {
+		user, err := store.GetByUserName(context.Background(), fmt.Sprintf("user%d", i))
+		if err != nil {
+			t.Fatalf("unexpected error getting user %d: %v", i, err)
+		}
+		if ids[user.ID] {
+			t.Errorf("ID %d was assigned more than once", user.ID)
+		}
+		ids[user.ID] = true
+	}
+	taken := 0
+	for i := 0; i < numUsers; i++ {
+		if _, err := store.GetByEmail(context.Background(), fmt.Sprintf("dup%d@test.com", i)); err == nil {
+			taken++
+		}
+	}
+	if taken != 1 {
+		t.Errorf("incorrect number of users with a duplicate username: expected 1 but got %d", taken)
+	}
+}
@@ -0,0 +1,299 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//migrationFiles holds the SQL migrations for the users database,
+//in a directory for each Dialect named after the Dialect. Each
+//migration is a pair of files named like
+//  0001_create_users.up.sql
+//  0001_create_users.down.sql
+//where the number is the migration's version. Versions must be
+//unique and are applied in increasing order. Statements within
+//a file are separated by a semicolon at the end of a line.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+//migrationsDir is the directory of migrationFiles holding
+//the directory of migrations for each Dialect
+const migrationsDir = "migrations"
+
+//Migration is one versioned change to the users database schema
+type Migration struct {
+	Version int
+	Name    string
+	//Up applies the change, and Down reverts it
+	Up   string
+	Down string
+}
+
+//MigrationStatus reports whether a Migration has been applied
+type MigrationStatus struct {
+	*Migration
+	Applied bool
+	//AppliedAt is when the migration was applied, as reported by the
+	//database, or empty if it hasn't been
+	AppliedAt string
+}
+
+//Migrator applies Migrations to a database, recording the version
+//of each applied migration in the schema_migrations table. Up and Down
+//hold the Dialect's migration lock while they run, so that migrators
+//started at the same time, for example by several replicas, don't
+//apply the same migration twice.
+type Migrator struct {
+	DB         *sql.DB
+	Dialect    Dialect
+	Migrations []*Migration
+}
+
+//NewMigrator constructs a Migrator for the embedded
+//migrations of the database's Dialect
+func NewMigrator(db *sql.DB, dialect Dialect) (*Migrator, error) {
+	migrations, err := LoadMigrations(migrationFiles, path.Join(migrationsDir, dialect.Name()))
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db, dialect, migrations}, nil
+}
+
+//LoadMigrations reads the migrations in directory `dir` of `fsys`,
+//returning them sorted by version
+func LoadMigrations(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		fileName := entry.Name()
+		var direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+		base := strings.TrimSuffix(fileName, "."+direction+".sql")
+		sep := strings.Index(base, "_")
+		if sep < 0 {
+			return nil, fmt.Errorf("migration %s: name must start with a version followed by '_'", fileName)
+		}
+		version, err := strconv.Atoi(base[:sep])
+		if err != nil || version < 1 {
+			return nil, fmt.Errorf("migration %s: invalid version %q", fileName, base[:sep])
+		}
+		contents, err := fs.ReadFile(fsys, path.Join(dir, fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, found := byVersion[version]
+		if !found {
+			migration = &Migration{Version: version, Name: base[sep+1:]}
+			byVersion[version] = migration
+		} else if migration.Name != base[sep+1:] {
+			return nil, fmt.Errorf("migration %s: version %d is already used by %s", fileName, version, migration.Name)
+		}
+		if direction == "up" {
+			migration.Up = string(contents)
+		} else {
+			migration.Down = string(contents)
+		}
+	}
+
+	migrations := make([]*Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if len(migration.Up) == 0 || len(migration.Down) == 0 {
+			return nil, fmt.Errorf("migration %04d_%s: needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+//Up applies every migration that hasn't been applied yet, in order,
+//and returns the ones it applied. Each migration is applied in its own
+//transaction, so a failed migration doesn't leave a partial version
+//recorded. Note that MySQL commits DDL statements like `create table`
+//implicitly, unlike Postgres and SQLite, so a migration that fails
+//partway may need cleaning up by hand; keep each migration to a single
+//schema change where possible.
+func (m *Migrator) Up() ([]*Migration, error) {
+	ctx := context.Background()
+	conn, unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	defer unlock()
+	statuses, err := m.status(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	var applied []*Migration
+	for _, status := range statuses {
+		if status.Applied {
+			continue
+		}
+		err := m.inTx(ctx, conn, status.Migration.Up, "insert into schema_migrations(version, name) values (?,?)",
+			status.Version, status.Name)
+		if err != nil {
+			return applied, fmt.Errorf("applying migration %04d_%s: %v", status.Version, status.Name, err)
+		}
+		applied = append(applied, status.Migration)
+	}
+	return applied, nil
+}
+
+//Down reverts the most recently applied migration and returns it,
+//or returns nil if no migrations have been applied
+func (m *Migrator) Down() (*Migration, error) {
+	ctx := context.Background()
+	conn, unlock, err := m.lock(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	defer unlock()
+	statuses, err := m.status(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(statuses) - 1; i >= 0; i-- {
+		status := statuses[i]
+		if !status.Applied {
+			continue
+		}
+		if err := m.inTx(ctx, conn, status.Migration.Down, "delete from schema_migrations where version=?", status.Version); err != nil {
+			return nil, fmt.Errorf("reverting migration %04d_%s: %v", status.Version, status.Name, err)
+		}
+		return status.Migration, nil
+	}
+	return nil, nil
+}
+
+//Status reports whether each migration has been applied,
+//creating the schema_migrations table if it doesn't exist yet
+func (m *Migrator) Status() ([]*MigrationStatus, error) {
+	return m.status(context.Background(), m.DB)
+}
+
+//migrationConn is implemented by both *sql.DB and *sql.Conn, so
+//that Up and Down can run on the connection holding the migration lock
+type migrationConn interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+//lock takes the migration lock on a connection of its own,
+//returning the connection and a function that releases the lock.
+//Close the connection once the lock is released.
+func (m *Migrator) lock(ctx context.Context) (*sql.Conn, func() error, error) {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	unlock, err := m.Dialect.LockMigrations(ctx, conn)
+	if err != nil {
+		conn.Close()
+		return nil, nil, fmt.Errorf("taking migration lock: %w", err)
+	}
+	return conn, unlock, nil
+}
+
+//status reports whether each migration has been applied,
+//using `c` to read and create the schema_migrations table
+func (m *Migrator) status(ctx context.Context, c migrationConn) ([]*MigrationStatus, error) {
+	createq := "create table if not exists schema_migrations (" +
+		"version bigint not null primary key, " +
+		"name varchar(255) not null, " +
+		"applied_at timestamp not null default current_timestamp)"
+	if _, err := c.ExecContext(ctx, createq); err != nil {
+		return nil, err
+	}
+	rows, err := c.QueryContext(ctx, "select version, applied_at from schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	appliedAt := map[int]string{}
+	for rows.Next() {
+		var version int
+		var at string
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		appliedAt[version] = at
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]*MigrationStatus, len(m.Migrations))
+	for i, migration := range m.Migrations {
+		at, applied := appliedAt[migration.Version]
+		statuses[i] = &MigrationStatus{migration, applied, at}
+	}
+	return statuses, nil
+}
+
+//inTx runs the statements in `script` followed by the statement
+//`record` with `args`, all in one transaction on `c`
+func (m *Migrator) inTx(ctx context.Context, c migrationConn, script string, record string, args ...interface{}) error {
+	tx, err := c.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, stmt := range splitStatements(script) {
+		if _, err := tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	if _, err := tx.Exec(m.Dialect.Rebind(record), args...); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+//splitStatements splits a migration script into its statements,
+//which are separated by a semicolon at the end of a line, because
+//database drivers generally run only one statement per Exec
+func splitStatements(script string) []string {
+	var stmts []string
+	var current strings.Builder
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+		current.WriteString(line)
+		current.WriteString("\n")
+		if strings.HasSuffix(trimmed, ";") {
+			if stmt := strings.TrimSuffix(strings.TrimSpace(current.String()), ";"); len(stmt) > 0 {
+				stmts = append(stmts, stmt)
+			}
+			current.Reset()
+		}
+	}
+	if stmt := strings.TrimSpace(current.String()); len(stmt) > 0 {
+		stmts = append(stmts, stmt)
+	}
+	return stmts
+}
@@ -0,0 +1,204 @@
+package users
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"testing/fstest"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	cases := []struct {
+		name             string
+		files            fstest.MapFS
+		expectedVersions []int
+		expectError      bool
+	}{
+		{
+			"Sorted By Version",
+			fstest.MapFS{
+				"m/0010_add_bio.up.sql":        {Data: []byte("alter table users add bio text;")},
+				"m/0010_add_bio.down.sql":      {Data: []byte("alter table users drop bio;")},
+				"m/0002_create_users.up.sql":   {Data: []byte("create table users (id bigint);")},
+				"m/0002_create_users.down.sql": {Data: []byte("drop table users;")},
+				"m/README.md":                  {Data: []byte("not a migration")},
+			},
+			[]int{2, 10},
+			false,
+		},
+		{
+			"Missing Down",
+			fstest.MapFS{
+				"m/0001_create_users.up.sql": {Data: []byte("create table users (id bigint);")},
+			},
+			nil,
+			true,
+		},
+		{
+			"Duplicate Version",
+			fstest.MapFS{
+				"m/0001_create_users.up.sql":   {Data: []byte("create table users (id bigint);")},
+				"m/0001_create_users.down.sql": {Data: []byte("drop table users;")},
+				"m/0001_add_bio.up.sql":        {Data: []byte("alter table users add bio text;")},
+				"m/0001_add_bio.down.sql":      {Data: []byte("alter table users drop bio;")},
+			},
+			nil,
+			true,
+		},
+		{
+			"Invalid Version",
+			fstest.MapFS{
+				"m/first_create_users.up.sql":   {Data: []byte("create table users (id bigint);")},
+				"m/first_create_users.down.sql": {Data: []byte("drop table users;")},
+			},
+			nil,
+			true,
+		},
+	}
+
+	for _, c := range cases {
+		migrations, err := LoadMigrations(c.files, "m")
+		if c.expectError {
+			if err == nil {
+				t.Errorf("case %s: expected error but did not get one", c.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("case %s: unexpected error: %v", c.name, err)
+			continue
+		}
+		var versions []int
+		for _, migration := range migrations {
+			versions = append(versions, migration.Version)
+		}
+		if !reflect.DeepEqual(versions, c.expectedVersions) {
+			t.Errorf("case %s: incorrect versions: expected %v but got %v", c.name, c.expectedVersions, versions)
+		}
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	for _, dialect := range []Dialect{MySQL, Postgres, SQLite} {
+		migrator, err := NewMigrator(nil, dialect)
+		if err != nil {
+			t.Errorf("dialect %s: error loading embedded migrations: %v", dialect.Name(), err)
+			continue
+		}
+		if len(migrator.Migrations) == 0 || migrator.Migrations[0].Name != "create_users" {
+			t.Errorf("dialect %s: the first migration should create the users table", dialect.Name())
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	cases := []struct {
+		name     string
+		script   string
+		expected []string
+	}{
+		{
+			"Single Statement",
+			"drop table users;\n",
+			[]string{"drop table users"},
+		},
+		{
+			"Multi-Line Statements And Comments",
+			"-- add a column\nalter table users\n  add bio text;\n\ncreate index bio on users (bio);\n",
+			[]string{"alter table users\n  add bio text", "create index bio on users (bio)"},
+		},
+		{
+			"No Trailing Semicolon",
+			"drop table users",
+			[]string{"drop table users"},
+		},
+	}
+	for _, c := range cases {
+		if stmts := splitStatements(c.script); !reflect.DeepEqual(stmts, c.expected) {
+			t.Errorf("case %s: incorrect statements: expected %q but got %q", c.name, c.expected, stmts)
+		}
+	}
+}
+
+func TestMigratorUpAndDown(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("There was a problem opening a database connection: [%v]", err)
+	}
+	defer db.Close()
+
+	migrator := &Migrator{db, MySQL, []*Migration{
+		{Version: 1, Name: "create_users", Up: "create table users (id bigint);", Down: "drop table users;"},
+		{Version: 2, Name: "add_bio", Up: "alter table users add bio text;", Down: "alter table users drop bio;"},
+	}}
+	versionRows := func(versions ...int64) *sqlmock.Rows {
+		rows := mock.NewRows([]string{"version", "applied_at"})
+		for _, version := range versions {
+			rows.AddRow(version, "2020-05-01 12:00:00")
+		}
+		return rows
+	}
+
+	//migration 1 is already applied, so only 2 should be,
+	//all while holding the migration lock
+	mock.ExpectQuery("select get_lock").WithArgs("schema_migrations", 60).WillReturnRows(mock.NewRows([]string{"locked"}).AddRow(1))
+	mock.ExpectExec("create table if not exists schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("select version, applied_at from schema_migrations").WillReturnRows(versionRows(1))
+	mock.ExpectBegin()
+	mock.ExpectExec("alter table users add bio text").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("insert into schema_migrations").WithArgs(2, "add_bio").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("select release_lock").WithArgs("schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	applied, err := migrator.Up()
+	if err != nil {
+		t.Fatalf("unexpected error migrating up: %v", err)
+	}
+	if len(applied) != 1 || applied[0].Version != 2 {
+		t.Errorf("incorrect migrations applied: expected only version 2 but got %v", applied)
+	}
+
+	//down reverts the most recent migration
+	mock.ExpectQuery("select get_lock").WithArgs("schema_migrations", 60).WillReturnRows(mock.NewRows([]string{"locked"}).AddRow(1))
+	mock.ExpectExec("create table if not exists schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("select version, applied_at from schema_migrations").WillReturnRows(versionRows(1, 2))
+	mock.ExpectBegin()
+	mock.ExpectExec("alter table users drop bio").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("delete from schema_migrations").WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectExec("select release_lock").WithArgs("schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	reverted, err := migrator.Down()
+	if err != nil {
+		t.Fatalf("unexpected error migrating down: %v", err)
+	}
+	if reverted == nil || reverted.Version != 2 {
+		t.Errorf("incorrect migration reverted: expected version 2 but got %v", reverted)
+	}
+
+	//a failing migration is rolled back and not recorded
+	mock.ExpectQuery("select get_lock").WithArgs("schema_migrations", 60).WillReturnRows(mock.NewRows([]string{"locked"}).AddRow(1))
+	mock.ExpectExec("create table if not exists schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("select version, applied_at from schema_migrations").WillReturnRows(versionRows(1))
+	mock.ExpectBegin()
+	mock.ExpectExec("alter table users add bio text").WillReturnError(ErrUserNotFound)
+	mock.ExpectRollback()
+	mock.ExpectExec("select release_lock").WithArgs("schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if _, err := migrator.Up(); err == nil {
+		t.Errorf("expected error from failing migration but did not get one")
+	}
+
+	//nothing is read or applied while another migrator holds the lock
+	mock.ExpectQuery("select get_lock").WithArgs("schema_migrations", 60).WillReturnRows(mock.NewRows([]string{"locked"}).AddRow(0))
+
+	if _, err := migrator.Up(); !errors.Is(err, ErrMigrationLocked) {
+		t.Errorf("incorrect error migrating while locked: expected %v but got %v", ErrMigrationLocked, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("There were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,11 @@
+create table if not exists users (
+    id bigint not null auto_increment primary key,
+    email varchar(254) not null,
+    pass_hash varbinary(255) not null,
+    username varchar(255) not null,
+    first_name varchar(64) not null default '',
+    last_name varchar(128) not null default '',
+    photo_url varchar(2083) not null default '',
+    unique key email (email),
+    unique key username (username)
+);
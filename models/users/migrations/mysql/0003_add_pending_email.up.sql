@@ -0,0 +1 @@
+alter table users add column pending_email varchar(254) not null default '';
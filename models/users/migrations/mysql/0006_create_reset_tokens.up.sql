@@ -0,0 +1,6 @@
+create table if not exists reset_tokens (
+    token_hash char(64) not null primary key,
+    user_id bigint not null,
+    expires_at bigint not null,
+    key user_id (user_id)
+);
@@ -0,0 +1,4 @@
+alter table users
+    drop column bio,
+    drop column display_name,
+    drop column locale;
@@ -0,0 +1,4 @@
+alter table users
+    add column bio varchar(500) not null default '',
+    add column display_name varchar(64) not null default '',
+    add column locale varchar(35) not null default '';
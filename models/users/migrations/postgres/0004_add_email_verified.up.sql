@@ -0,0 +1 @@
+alter table users add column email_verified boolean not null default false;
@@ -0,0 +1,6 @@
+create table if not exists reset_tokens (
+    token_hash char(64) primary key,
+    user_id bigint not null,
+    expires_at bigint not null
+);
+create index if not exists reset_tokens_user_id on reset_tokens (user_id);
@@ -0,0 +1,9 @@
+create table if not exists users (
+    id integer primary key autoincrement,
+    email text not null unique,
+    pass_hash blob not null,
+    username text not null unique,
+    first_name text not null default '',
+    last_name text not null default '',
+    photo_url text not null default ''
+);
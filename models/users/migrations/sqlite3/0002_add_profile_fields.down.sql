@@ -0,0 +1,3 @@
+alter table users drop column bio;
+alter table users drop column display_name;
+alter table users drop column locale;
@@ -0,0 +1,3 @@
+alter table users add column bio text not null default '';
+alter table users add column display_name text not null default '';
+alter table users add column locale text not null default '';
@@ -0,0 +1 @@
+alter table users add column pending_email text not null default '';
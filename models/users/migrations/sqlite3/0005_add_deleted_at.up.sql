@@ -0,0 +1 @@
+alter table users add column deleted_at timestamp null;
@@ -0,0 +1,6 @@
+create table if not exists reset_tokens (
+    token_hash text primary key,
+    user_id integer not null,
+    expires_at integer not null
+);
+create index if not exists reset_tokens_user_id on reset_tokens (user_id);
@@ -1,102 +1,224 @@
 package users
 
 import (
+	"context"
 	"database/sql"
-
-	_ "github.com/go-sql-driver/mysql"
+	"strings"
+	"time"
 )
 
-type SQLStore struct{ DB *sql.DB }
+//SQLStore is a Store backed by a SQL database. Its queries are
+//adapted to the database by its Dialect, which is MySQL unless
+//it is constructed with NewPostgresStore or NewSQLiteStore.
+type SQLStore struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+//userColumns are the columns selected for a User, in the
+//order they are scanned by getBy
+const userColumns = "id,email,pass_hash,username,first_name,last_name,photo_url,bio,display_name,locale,email_verified,pending_email,deleted_at"
 
+//NewSQLStore constructs a SQLStore for a MySQL database. Open `db`
+//with parseTime=true in its DSN, so that timestamps can be scanned.
 func NewSQLStore(db *sql.DB) *SQLStore {
-	return &SQLStore{db}
+	return &SQLStore{db, MySQL}
 }
 
 //GetByID returns the User with the given ID
-func (ss *SQLStore) GetByID(id int64) (*User, error) {
-	rows, err1 := ss.DB.Query("select id,email,pass_hash,username,first_name,last_name,photo_url from users where id=?", id)
-	if err1 != nil {
-		return nil, err1
-	}
-	defer rows.Close()
-	user := User{}
-	for rows.Next() {
-		if err := rows.Scan(&user.ID, &user.Email, &user.PassHash, &user.UserName,
-			&user.FirstName, &user.LastName, &user.PhotoURL); err != nil {
-			return nil, err
-		}
-	}
-	return &user, nil
+func (ss *SQLStore) GetByID(ctx context.Context, id int64) (*User, error) {
+	return ss.getBy(ctx, "select "+userColumns+" from users where id=?", id)
 }
 
 //GetByEmail returns the User with the given email
-func (ss *SQLStore) GetByEmail(email string) (*User, error) {
-	rows, err1 := ss.DB.Query("select id,email,pass_hash,username,first_name,last_name,photo_url from users where email=?", email)
-	if err1 != nil {
-		return nil, err1
-	}
-	defer rows.Close()
-	user := User{}
-	for rows.Next() {
-		if err := rows.Scan(&user.ID, &user.Email, &user.PassHash, &user.UserName,
-			&user.FirstName, &user.LastName, &user.PhotoURL); err != nil {
-			return nil, err
-		}
-	}
-	return &user, nil
+func (ss *SQLStore) GetByEmail(ctx context.Context, email string) (*User, error) {
+	return ss.getBy(ctx, "select "+userColumns+" from users where email=?", email)
 }
 
 //GetByUserName returns the User with the given Username
-func (ss *SQLStore) GetByUserName(username string) (*User, error) {
-	rows, err1 := ss.DB.Query("select id,email,pass_hash,username,first_name,last_name,photo_url from users where username=?", username)
-	if err1 != nil {
-		return nil, err1
-	}
-	defer rows.Close()
+func (ss *SQLStore) GetByUserName(ctx context.Context, username string) (*User, error) {
+	return ss.getBy(ctx, "select "+userColumns+" from users where username=?", username)
+}
+
+//getBy runs a query selecting at most one user,
+//returning ErrUserNotFound if there is none
+func (ss *SQLStore) getBy(ctx context.Context, query string, arg interface{}) (*User, error) {
 	user := User{}
-	for rows.Next() {
-		if err := rows.Scan(&user.ID, &user.Email, &user.PassHash, &user.UserName,
-			&user.FirstName, &user.LastName, &user.PhotoURL); err != nil {
-			return nil, err
-		}
+	deletedAt := sql.NullTime{}
+	err := ss.DB.QueryRowContext(ctx, ss.Dialect.Rebind(query), arg).Scan(&user.ID, &user.Email, &user.PassHash, &user.UserName,
+		&user.FirstName, &user.LastName, &user.PhotoURL, &user.Bio, &user.DisplayName, &user.Locale, &user.EmailVerified, &user.PendingEmail,
+		&deletedAt)
+	if err == sql.ErrNoRows {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if deletedAt.Valid {
+		user.DeletedAt = &deletedAt.Time
 	}
 	return &user, nil
 }
 
 //Insert inserts the user into the database, and returns
 //the newly-inserted User, complete with the DBMS-assigned ID
-func (ss *SQLStore) Insert(user *User) (*User, error) {
-	insq := "insert into users(email, pass_hash, username, first_name, last_name, photo_url) values (?,?,?,?,?,?)"
-	res, err1 := ss.DB.Exec(insq, user.Email, user.PassHash, user.UserName, user.FirstName, user.LastName, user.PhotoURL)
-	if err1 != nil {
-		return nil, err1
-	}
-	id, err2 := res.LastInsertId()
-	if err2 != nil {
-		return nil, err2
+func (ss *SQLStore) Insert(ctx context.Context, user *User) (*User, error) {
+	insq := "insert into users(email, pass_hash, username, first_name, last_name, photo_url, bio, display_name, locale, email_verified) " +
+		"values (?,?,?,?,?,?,?,?,?,?)"
+	id, err := ss.Dialect.Insert(ctx, ss.DB, insq, user.Email, user.PassHash, user.UserName, user.FirstName, user.LastName, user.PhotoURL,
+		user.Bio, user.DisplayName, user.Locale, user.EmailVerified)
+	if err != nil {
+		return nil, ss.translateDuplicate(err)
 	}
 	user.ID = id
 	return user, nil
 }
 
 //Update applies UserUpdates to the given user ID
-//and returns the newly-updated user
-func (ss *SQLStore) Update(id int64, updates *Updates) (*User, error) {
-	insq := "update users set first_name=?, last_name=? where id=?"
-	_, err1 := ss.DB.Exec(insq, updates.FirstName, updates.LastName, id)
-	if err1 != nil {
-		return nil, err1
-	}
-	user, err := ss.GetByID(id)
+//and returns the newly-updated user. Only the columns
+//of the fields set in `updates` are changed.
+func (ss *SQLStore) Update(ctx context.Context, id int64, updates *Updates) (*User, error) {
+	cols, args := updates.columns()
+	if len(cols) > 0 {
+		insq := "update users set " + strings.Join(cols, "=?, ") + "=? where id=?"
+		_, err1 := ss.DB.ExecContext(ctx, ss.Dialect.Rebind(insq), append(args, id)...)
+		if err1 != nil {
+			return nil, ss.translateDuplicate(err1)
+		}
+	}
+	user, err := ss.GetByID(ctx, id)
 	if err != nil {
 		return nil, err
 	}
 	return user, nil
 }
 
+//SetPassHash replaces the password hash of the user with the given ID
+func (ss *SQLStore) SetPassHash(ctx context.Context, id int64, passHash []byte) error {
+	insq := "update users set pass_hash=? where id=?"
+	res, err := ss.DB.ExecContext(ctx, ss.Dialect.Rebind(insq), passHash, id)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected > 0 {
+		return err
+	}
+	return ss.userExists(ctx, id)
+}
+
+//SetPendingEmail saves `email` as the address the user with
+//the given ID wants to change to, until they confirm it
+func (ss *SQLStore) SetPendingEmail(ctx context.Context, id int64, email string) error {
+	insq := "update users set pending_email=? where id=?"
+	res, err := ss.DB.ExecContext(ctx, ss.Dialect.Rebind(insq), email, id)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected > 0 {
+		return err
+	}
+	return ss.userExists(ctx, id)
+}
+
+//ConfirmEmail makes `email` the email address of the user with the
+//given ID, as long as it is still their pending email. The email,
+//pending email and Gravatar PhotoURL are changed in one statement.
+func (ss *SQLStore) ConfirmEmail(ctx context.Context, id int64, email string) (*User, error) {
+	insq := "update users set email=?, email_verified=?, pending_email='', " +
+		"photo_url=case when photo_url='' or photo_url like ? then ? else photo_url end " +
+		"where id=? and pending_email=? and pending_email<>''"
+	res, err := ss.DB.ExecContext(ctx, ss.Dialect.Rebind(insq), email, true, gravatarBasePhotoURL+"%", gravatarURL(email), id, email)
+	if err != nil {
+		return nil, ss.translateDuplicate(err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+	if affected == 0 {
+		if err := ss.userExists(ctx, id); err != nil {
+			return nil, err
+		}
+		return nil, ErrNoPendingEmail
+	}
+	return ss.GetByID(ctx, id)
+}
+
+//VerifyEmail marks the email address of the user with the given
+//ID as verified, as long as their email is still `email`
+func (ss *SQLStore) VerifyEmail(ctx context.Context, id int64, email string) (*User, error) {
+	insq := "update users set email_verified=? where id=? and email=?"
+	if _, err := ss.DB.ExecContext(ctx, ss.Dialect.Rebind(insq), true, id, email); err != nil {
+		return nil, err
+	}
+	//MySQL reports no rows affected for an already-verified email,
+	//so check the email of the user instead
+	user, err := ss.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if user.Email != email {
+		return nil, ErrInvalidToken
+	}
+	return user, nil
+}
+
+//userExists returns nil if there is a user with the given ID,
+//or ErrUserNotFound. It's used to tell why an update changed no rows,
+//since MySQL doesn't count rows that already held the new values.
+func (ss *SQLStore) userExists(ctx context.Context, id int64) error {
+	_, err := ss.GetByID(ctx, id)
+	return err
+}
+
 //Delete deletes the user with the given ID
-func (ss *SQLStore) Delete(id int64) error {
+func (ss *SQLStore) Delete(ctx context.Context, id int64) error {
 	insq := "delete from users where id=?"
-	_, err := ss.DB.Exec(insq, id)
+	_, err := ss.DB.ExecContext(ctx, ss.Dialect.Rebind(insq), id)
+	return err
+}
+
+//SetDeletedAt soft-deletes the user with the given ID,
+//or restores them if `deletedAt` is nil
+func (ss *SQLStore) SetDeletedAt(ctx context.Context, id int64, deletedAt *time.Time) error {
+	var at interface{}
+	if deletedAt != nil {
+		at = deletedAt.UTC()
+	}
+	insq := "update users set deleted_at=? where id=?"
+	res, err := ss.DB.ExecContext(ctx, ss.Dialect.Rebind(insq), at, id)
+	if err != nil {
+		return err
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected > 0 {
+		return err
+	}
+	return ss.userExists(ctx, id)
+}
+
+//PurgeDeleted deletes every user soft-deleted at or before `before`
+func (ss *SQLStore) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	insq := "delete from users where deleted_at is not null and deleted_at<=?"
+	res, err := ss.DB.ExecContext(ctx, ss.Dialect.Rebind(insq), before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+//translateDuplicate returns ErrEmailTaken or ErrUserNameTaken if `err`
+//is a duplicate-key error on the email or username unique index,
+//or `err` unchanged otherwise
+func (ss *SQLStore) translateDuplicate(err error) error {
+	column, ok := ss.Dialect.DuplicateColumn(err)
+	if !ok {
+		return err
+	}
+	switch column {
+	case "email":
+		return ErrEmailTaken
+	case "username":
+		return ErrUserNameTaken
+	}
 	return err
 }
@@ -1,10 +1,13 @@
 package users
 
 import (
+	"context"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/go-sql-driver/mysql"
 )
 
 // TestGetByID is a test function for the SQLStore's GetByID
@@ -19,13 +22,13 @@ func TestGetByID(t *testing.T) {
 		{
 			"User Found",
 			&User{
-				1,
-				"test@test.com",
-				[]byte("passhash123"),
-				"username",
-				"firstname",
-				"lastname",
-				"photourl",
+				ID:        1,
+				Email:     "test@test.com",
+				PassHash:  []byte("passhash123"),
+				UserName:  "username",
+				FirstName: "firstname",
+				LastName:  "lastname",
+				PhotoURL:  "photourl",
 			},
 			1,
 			false,
@@ -39,13 +42,13 @@ func TestGetByID(t *testing.T) {
 		{
 			"User With Large ID Found",
 			&User{
-				1234567890,
-				"test@test.com",
-				[]byte("passhash123"),
-				"username",
-				"firstname",
-				"lastname",
-				"photourl",
+				ID:        1234567890,
+				Email:     "test@test.com",
+				PassHash:  []byte("passhash123"),
+				UserName:  "username",
+				FirstName: "firstname",
+				LastName:  "lastname",
+				PhotoURL:  "photourl",
 			},
 			1234567890,
 			false,
@@ -70,7 +73,13 @@ func TestGetByID(t *testing.T) {
 			"UserName",
 			"FirstName",
 			"LastName",
-			"PhotoURL"},
+			"PhotoURL",
+			"Bio",
+			"DisplayName",
+			"Locale",
+			"EmailVerified",
+			"PendingEmail",
+			"DeletedAt"},
 		).AddRow(
 			c.expectedUser.ID,
 			c.expectedUser.Email,
@@ -79,16 +88,22 @@ func TestGetByID(t *testing.T) {
 			c.expectedUser.FirstName,
 			c.expectedUser.LastName,
 			c.expectedUser.PhotoURL,
+			c.expectedUser.Bio,
+			c.expectedUser.DisplayName,
+			c.expectedUser.Locale,
+			c.expectedUser.EmailVerified,
+			c.expectedUser.PendingEmail,
+			nil,
 		)
 
-		query := "select id,email,pass_hash,username,first_name,last_name,photo_url from users where id=?"
+		query := "select id,email,pass_hash,username,first_name,last_name,photo_url,bio,display_name,locale,email_verified,pending_email,deleted_at from users where id=?"
 
 		if c.expectError {
 			// Set up expected query that will expect an error
 			mock.ExpectQuery(query).WithArgs(c.idToGet).WillReturnError(ErrUserNotFound)
 
 			// Test GetByID()
-			user, err := mainSQLStore.GetByID(c.idToGet)
+			user, err := mainSQLStore.GetByID(context.Background(), c.idToGet)
 			if user != nil || err == nil {
 				t.Errorf("Expected error [%v] but got [%v] instead", ErrUserNotFound, err)
 			}
@@ -97,7 +112,7 @@ func TestGetByID(t *testing.T) {
 			mock.ExpectQuery(query).WithArgs(c.idToGet).WillReturnRows(row)
 
 			// Test GetByID()
-			user, err := mainSQLStore.GetByID(c.idToGet)
+			user, err := mainSQLStore.GetByID(context.Background(), c.idToGet)
 			if err != nil {
 				t.Errorf("Unexpected error on successful test [%s]: %v", c.name, err)
 			}
@@ -125,13 +140,13 @@ func TestGetByEmail(t *testing.T) {
 		{
 			"User with Plain Email Found",
 			&User{
-				1,
-				"test@test.com",
-				[]byte("passhash123"),
-				"username",
-				"firstname",
-				"lastname",
-				"photourl",
+				ID:        1,
+				Email:     "test@test.com",
+				PassHash:  []byte("passhash123"),
+				UserName:  "username",
+				FirstName: "firstname",
+				LastName:  "lastname",
+				PhotoURL:  "photourl",
 			},
 			"test@test.com",
 			false,
@@ -145,13 +160,13 @@ func TestGetByEmail(t *testing.T) {
 		{
 			"User With Mixed Case Email Found",
 			&User{
-				1234567890,
-				"Testing2@test.com",
-				[]byte("passhash123"),
-				"username",
-				"firstname",
-				"lastname",
-				"photourl",
+				ID:        1234567890,
+				Email:     "Testing2@test.com",
+				PassHash:  []byte("passhash123"),
+				UserName:  "username",
+				FirstName: "firstname",
+				LastName:  "lastname",
+				PhotoURL:  "photourl",
 			},
 			"Testing2@test.com",
 			false,
@@ -176,7 +191,13 @@ func TestGetByEmail(t *testing.T) {
 			"UserName",
 			"FirstName",
 			"LastName",
-			"PhotoURL"},
+			"PhotoURL",
+			"Bio",
+			"DisplayName",
+			"Locale",
+			"EmailVerified",
+			"PendingEmail",
+			"DeletedAt"},
 		).AddRow(
 			c.expectedUser.ID,
 			c.expectedUser.Email,
@@ -185,16 +206,22 @@ func TestGetByEmail(t *testing.T) {
 			c.expectedUser.FirstName,
 			c.expectedUser.LastName,
 			c.expectedUser.PhotoURL,
+			c.expectedUser.Bio,
+			c.expectedUser.DisplayName,
+			c.expectedUser.Locale,
+			c.expectedUser.EmailVerified,
+			c.expectedUser.PendingEmail,
+			nil,
 		)
 
-		query := "select id,email,pass_hash,username,first_name,last_name,photo_url from users where email=?"
+		query := "select id,email,pass_hash,username,first_name,last_name,photo_url,bio,display_name,locale,email_verified,pending_email,deleted_at from users where email=?"
 
 		if c.expectError {
 			// Set up expected query that will expect an error
 			mock.ExpectQuery(query).WithArgs(c.emailToGet).WillReturnError(ErrUserNotFound)
 
 			// Test GetByEmail()
-			user, err := mainSQLStore.GetByEmail(c.emailToGet)
+			user, err := mainSQLStore.GetByEmail(context.Background(), c.emailToGet)
 			if user != nil || err == nil {
 				t.Errorf("Expected error [%v] but got [%v] instead", ErrUserNotFound, err)
 			}
@@ -203,7 +230,7 @@ func TestGetByEmail(t *testing.T) {
 			mock.ExpectQuery(query).WithArgs(c.emailToGet).WillReturnRows(row)
 
 			// Test GetByEmail()
-			user, err := mainSQLStore.GetByEmail(c.emailToGet)
+			user, err := mainSQLStore.GetByEmail(context.Background(), c.emailToGet)
 			if err != nil {
 				t.Errorf("Unexpected error on successful test [%s]: %v", c.name, err)
 			}
@@ -231,13 +258,13 @@ func TestGetByUserName(t *testing.T) {
 		{
 			"User with Plain Username Found",
 			&User{
-				1,
-				"test@test.com",
-				[]byte("passhash123"),
-				"username",
-				"firstname",
-				"lastname",
-				"photourl",
+				ID:        1,
+				Email:     "test@test.com",
+				PassHash:  []byte("passhash123"),
+				UserName:  "username",
+				FirstName: "firstname",
+				LastName:  "lastname",
+				PhotoURL:  "photourl",
 			},
 			"username",
 			false,
@@ -251,13 +278,13 @@ func TestGetByUserName(t *testing.T) {
 		{
 			"User With Mixed Case Username Found",
 			&User{
-				1234567890,
-				"test@test.com",
-				[]byte("passhash123"),
-				"uSeRnaMe1",
-				"firstname",
-				"lastname",
-				"photourl",
+				ID:        1234567890,
+				Email:     "test@test.com",
+				PassHash:  []byte("passhash123"),
+				UserName:  "uSeRnaMe1",
+				FirstName: "firstname",
+				LastName:  "lastname",
+				PhotoURL:  "photourl",
 			},
 			"uSeRnaMe1",
 			false,
@@ -282,7 +309,13 @@ func TestGetByUserName(t *testing.T) {
 			"UserName",
 			"FirstName",
 			"LastName",
-			"PhotoURL"},
+			"PhotoURL",
+			"Bio",
+			"DisplayName",
+			"Locale",
+			"EmailVerified",
+			"PendingEmail",
+			"DeletedAt"},
 		).AddRow(
 			c.expectedUser.ID,
 			c.expectedUser.Email,
@@ -291,16 +324,22 @@ func TestGetByUserName(t *testing.T) {
 			c.expectedUser.FirstName,
 			c.expectedUser.LastName,
 			c.expectedUser.PhotoURL,
+			c.expectedUser.Bio,
+			c.expectedUser.DisplayName,
+			c.expectedUser.Locale,
+			c.expectedUser.EmailVerified,
+			c.expectedUser.PendingEmail,
+			nil,
 		)
 
-		query := "select id,email,pass_hash,username,first_name,last_name,photo_url from users where username=?"
+		query := "select id,email,pass_hash,username,first_name,last_name,photo_url,bio,display_name,locale,email_verified,pending_email,deleted_at from users where username=?"
 
 		if c.expectError {
 			// Set up expected query that will expect an error
 			mock.ExpectQuery(query).WithArgs(c.unameToGet).WillReturnError(ErrUserNotFound)
 
 			// Test GetByUserName()
-			user, err := mainSQLStore.GetByUserName(c.unameToGet)
+			user, err := mainSQLStore.GetByUserName(context.Background(), c.unameToGet)
 			if user != nil || err == nil {
 				t.Errorf("Expected error [%v] but got [%v] instead", ErrUserNotFound, err)
 			}
@@ -309,7 +348,7 @@ func TestGetByUserName(t *testing.T) {
 			mock.ExpectQuery(query).WithArgs(c.unameToGet).WillReturnRows(row)
 
 			// Test GetByUserName()
-			user, err := mainSQLStore.GetByUserName(c.unameToGet)
+			user, err := mainSQLStore.GetByUserName(context.Background(), c.unameToGet)
 			if err != nil {
 				t.Errorf("Unexpected error on successful test [%s]: %v", c.name, err)
 			}
@@ -339,26 +378,26 @@ func TestUpdate(t *testing.T) {
 		{
 			"Normal Case",
 			&User{
-				1,
-				"test@test.com",
-				[]byte("passhash123"),
-				"username",
-				"firstname",
-				"lastname",
-				"photourl",
+				ID:        1,
+				Email:     "test@test.com",
+				PassHash:  []byte("passhash123"),
+				UserName:  "username",
+				FirstName: "firstname",
+				LastName:  "lastname",
+				PhotoURL:  "photourl",
 			},
 			&User{
-				1,
-				"test@test.com",
-				[]byte("passhash123"),
-				"username",
-				"firstname1",
-				"lastname1",
-				"photourl",
+				ID:        1,
+				Email:     "test@test.com",
+				PassHash:  []byte("passhash123"),
+				UserName:  "username",
+				FirstName: "firstname1",
+				LastName:  "lastname1",
+				PhotoURL:  "photourl",
 			},
 			&Updates{
-				"firstname1",
-				"lastname1",
+				FirstName: stringPtr("firstname1"),
+				LastName:  stringPtr("lastname1"),
 			},
 			1,
 			false,
@@ -366,18 +405,18 @@ func TestUpdate(t *testing.T) {
 		{
 			"Incorrect User ID",
 			&User{
-				1,
-				"test@test.com",
-				[]byte("passhash123"),
-				"username",
-				"firstname",
-				"lastname",
-				"photourl",
+				ID:        1,
+				Email:     "test@test.com",
+				PassHash:  []byte("passhash123"),
+				UserName:  "username",
+				FirstName: "firstname",
+				LastName:  "lastname",
+				PhotoURL:  "photourl",
 			},
 			&User{},
 			&Updates{
-				"firstname1",
-				"lastname1",
+				FirstName: stringPtr("firstname1"),
+				LastName:  stringPtr("lastname1"),
 			},
 			2,
 			true,
@@ -402,7 +441,13 @@ func TestUpdate(t *testing.T) {
 			"UserName",
 			"FirstName",
 			"LastName",
-			"PhotoURL"},
+			"PhotoURL",
+			"Bio",
+			"DisplayName",
+			"Locale",
+			"EmailVerified",
+			"PendingEmail",
+			"DeletedAt"},
 		).AddRow(
 			c.ogUser.ID,
 			c.ogUser.Email,
@@ -411,6 +456,12 @@ func TestUpdate(t *testing.T) {
 			c.ogUser.FirstName,
 			c.ogUser.LastName,
 			c.ogUser.PhotoURL,
+			c.ogUser.Bio,
+			c.ogUser.DisplayName,
+			c.ogUser.Locale,
+			c.ogUser.EmailVerified,
+			c.ogUser.PendingEmail,
+			nil,
 		)
 		// Create row detailing the updated user
 		row := mock.NewRows([]string{
@@ -420,7 +471,13 @@ func TestUpdate(t *testing.T) {
 			"UserName",
 			"FirstName",
 			"LastName",
-			"PhotoURL"},
+			"PhotoURL",
+			"Bio",
+			"DisplayName",
+			"Locale",
+			"EmailVerified",
+			"PendingEmail",
+			"DeletedAt"},
 		).AddRow(
 			c.expectedUser.ID,
 			c.expectedUser.Email,
@@ -429,32 +486,38 @@ func TestUpdate(t *testing.T) {
 			c.expectedUser.FirstName,
 			c.expectedUser.LastName,
 			c.expectedUser.PhotoURL,
+			c.expectedUser.Bio,
+			c.expectedUser.DisplayName,
+			c.expectedUser.Locale,
+			c.expectedUser.EmailVerified,
+			c.expectedUser.PendingEmail,
+			nil,
 		)
 
 		query := "update users set first_name=?, last_name=? where id=?"
 
 		if c.expectError {
 			mock.ExpectPrepare("update").ExpectExec().WithArgs(
-				c.givenUpdates.FirstName,
-				c.givenUpdates.LastName,
+				*c.givenUpdates.FirstName,
+				*c.givenUpdates.LastName,
 				c.submittedID,
 			)
 			db.Prepare(query)
 			// Test Update()
-			result, err := mainSQLStore.Update(c.submittedID, c.givenUpdates)
+			result, err := mainSQLStore.Update(context.Background(), c.submittedID, c.givenUpdates)
 			if result != nil || err == nil {
 				t.Errorf("Expected error but did not get one.")
 			}
 		} else {
 			mock.ExpectPrepare("update").ExpectExec().WithArgs(
-				c.givenUpdates.FirstName,
-				c.givenUpdates.LastName,
+				*c.givenUpdates.FirstName,
+				*c.givenUpdates.LastName,
 				c.submittedID,
 			).WillReturnResult(sqlmock.NewResult(1, 1))
-			mock.ExpectQuery("select id,email,pass_hash,username,first_name,last_name,photo_url from users where id=?").WithArgs(c.submittedID).WillReturnRows(row)
+			mock.ExpectQuery("select id,email,pass_hash,username,first_name,last_name,photo_url,bio,display_name,locale,email_verified,pending_email,deleted_at from users where id=?").WithArgs(c.submittedID).WillReturnRows(row)
 			db.Prepare(query)
 			// Test Update()
-			result, err := mainSQLStore.Update(c.submittedID, c.givenUpdates)
+			result, err := mainSQLStore.Update(context.Background(), c.submittedID, c.givenUpdates)
 			if err != nil {
 				t.Errorf("Unexpected error on successful test [%s]: %v", c.name, err)
 			}
@@ -480,13 +543,13 @@ func TestInsert(t *testing.T) {
 		{
 			"Normal User",
 			&User{
-				1,
-				"test@test.com",
-				[]byte("passhash123"),
-				"username",
-				"firstname",
-				"lastname",
-				"photourl",
+				ID:        1,
+				Email:     "test@test.com",
+				PassHash:  []byte("passhash123"),
+				UserName:  "username",
+				FirstName: "firstname",
+				LastName:  "lastname",
+				PhotoURL:  "photourl",
 			},
 			false,
 		},
@@ -498,13 +561,13 @@ func TestInsert(t *testing.T) {
 		{
 			"User With Mixed Case Params",
 			&User{
-				1234567890,
-				"test@test.com",
-				[]byte("passhash123"),
-				"uSeRnaMe1",
-				"firstname",
-				"lastname",
-				"photourl",
+				ID:        1234567890,
+				Email:     "test@test.com",
+				PassHash:  []byte("passhash123"),
+				UserName:  "uSeRnaMe1",
+				FirstName: "firstname",
+				LastName:  "lastname",
+				PhotoURL:  "photourl",
 			},
 			false,
 		},
@@ -520,7 +583,7 @@ func TestInsert(t *testing.T) {
 
 		mainSQLStore := NewSQLStore(db)
 
-		query := "insert into users(email, pass_hash, username, first_name, last_name, photo_url) values (?,?,?,?,?,?)"
+		query := "insert into users(email, pass_hash, username, first_name, last_name, photo_url, bio, display_name, locale, email_verified) values (?,?,?,?,?,?,?,?,?,?)"
 
 		if c.expectError {
 			mock.ExpectPrepare("insert into").ExpectExec().WithArgs(
@@ -530,10 +593,14 @@ func TestInsert(t *testing.T) {
 				c.insertedUser.FirstName,
 				c.insertedUser.LastName,
 				c.insertedUser.PhotoURL,
+				c.insertedUser.Bio,
+				c.insertedUser.DisplayName,
+				c.insertedUser.Locale,
+				c.insertedUser.EmailVerified,
 			)
 			db.Prepare(query)
 			// Test Insert()
-			result, err := mainSQLStore.Insert(c.insertedUser)
+			result, err := mainSQLStore.Insert(context.Background(), c.insertedUser)
 			if result != nil || err == nil {
 				t.Errorf("Expected error but did not get one.")
 			}
@@ -545,10 +612,14 @@ func TestInsert(t *testing.T) {
 				c.insertedUser.FirstName,
 				c.insertedUser.LastName,
 				c.insertedUser.PhotoURL,
+				c.insertedUser.Bio,
+				c.insertedUser.DisplayName,
+				c.insertedUser.Locale,
+				c.insertedUser.EmailVerified,
 			).WillReturnResult(sqlmock.NewResult(1, 1))
 			db.Prepare(query)
 			// Test Insert()
-			result, err := mainSQLStore.Insert(c.insertedUser)
+			result, err := mainSQLStore.Insert(context.Background(), c.insertedUser)
 			if err != nil {
 				t.Errorf("Unexpected error on successful test [%s]: %v", c.name, err)
 			}
@@ -576,13 +647,13 @@ func TestDelete(t *testing.T) {
 		{
 			"Normal User",
 			&User{
-				1,
-				"test@test.com",
-				[]byte("passhash123"),
-				"username",
-				"firstname",
-				"lastname",
-				"photourl",
+				ID:        1,
+				Email:     "test@test.com",
+				PassHash:  []byte("passhash123"),
+				UserName:  "username",
+				FirstName: "firstname",
+				LastName:  "lastname",
+				PhotoURL:  "photourl",
 			},
 			1,
 			false,
@@ -590,13 +661,13 @@ func TestDelete(t *testing.T) {
 		{
 			"Incorrect User ID",
 			&User{
-				1234567890,
-				"test@test.com",
-				[]byte("passhash123"),
-				"uSeRnaMe1",
-				"firstname",
-				"lastname",
-				"photourl",
+				ID:        1234567890,
+				Email:     "test@test.com",
+				PassHash:  []byte("passhash123"),
+				UserName:  "uSeRnaMe1",
+				FirstName: "firstname",
+				LastName:  "lastname",
+				PhotoURL:  "photourl",
 			},
 			2,
 			false,
@@ -620,7 +691,13 @@ func TestDelete(t *testing.T) {
 			"UserName",
 			"FirstName",
 			"LastName",
-			"PhotoURL"},
+			"PhotoURL",
+			"Bio",
+			"DisplayName",
+			"Locale",
+			"EmailVerified",
+			"PendingEmail",
+			"DeletedAt"},
 		).AddRow(
 			c.ogUser.ID,
 			c.ogUser.Email,
@@ -629,6 +706,12 @@ func TestDelete(t *testing.T) {
 			c.ogUser.FirstName,
 			c.ogUser.LastName,
 			c.ogUser.PhotoURL,
+			c.ogUser.Bio,
+			c.ogUser.DisplayName,
+			c.ogUser.Locale,
+			c.ogUser.EmailVerified,
+			c.ogUser.PendingEmail,
+			nil,
 		)
 
 		query := "delete from users where id=?"
@@ -637,7 +720,7 @@ func TestDelete(t *testing.T) {
 			mock.ExpectPrepare("delete").ExpectExec().WithArgs(c.submittedID)
 			db.Prepare(query)
 			// Test Delete()
-			err := mainSQLStore.Delete(c.submittedID)
+			err := mainSQLStore.Delete(context.Background(), c.submittedID)
 			if err == nil {
 				t.Errorf("Expected error but did not get one.")
 			}
@@ -645,7 +728,7 @@ func TestDelete(t *testing.T) {
 			mock.ExpectPrepare("delete").ExpectExec().WithArgs(c.submittedID).WillReturnResult(sqlmock.NewResult(1, 1))
 			db.Prepare(query)
 			// Test Delete()
-			err := mainSQLStore.Delete(c.submittedID)
+			err := mainSQLStore.Delete(context.Background(), c.submittedID)
 			if err != nil {
 				t.Errorf("Unexpected error on successful test [%s]: %v", c.name, err)
 			}
@@ -656,3 +739,201 @@ func TestDelete(t *testing.T) {
 
 	}
 }
+
+//TestSetPassHash is a test function for the SQLStore's SetPassHash
+func TestSetPassHash(t *testing.T) {
+	cases := []struct {
+		name         string
+		submittedID  int64
+		rowsAffected int64
+		userExists   bool
+		expectError  bool
+	}{
+		{
+			"Normal User",
+			1,
+			1,
+			true,
+			false,
+		},
+		{
+			//MySQL doesn't count rows that already held the new value
+			"Unchanged Hash",
+			1,
+			0,
+			true,
+			false,
+		},
+		{
+			"Incorrect User ID",
+			2,
+			0,
+			false,
+			true,
+		},
+	}
+
+	for _, c := range cases {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("There was a problem opening a database connection: [%v]", err)
+		}
+		defer db.Close()
+
+		mainSQLStore := NewSQLStore(db)
+		passHash := []byte("newpasshash")
+
+		mock.ExpectExec("update users set pass_hash").WithArgs(passHash, c.submittedID).
+			WillReturnResult(sqlmock.NewResult(0, c.rowsAffected))
+		if c.rowsAffected == 0 {
+			rows := mock.NewRows([]string{"ID", "Email", "PassHash", "UserName", "FirstName", "LastName", "PhotoURL", "Bio", "DisplayName", "Locale", "EmailVerified", "PendingEmail", "DeletedAt"})
+			if c.userExists {
+				rows.AddRow(c.submittedID, "test@test.com", passHash, "username", "firstname", "lastname", "photourl", "", "", "", false, "", nil)
+			}
+			mock.ExpectQuery("where id=?").WithArgs(c.submittedID).WillReturnRows(rows)
+		}
+
+		err = mainSQLStore.SetPassHash(context.Background(), c.submittedID, passHash)
+		if c.expectError && err != ErrUserNotFound {
+			t.Errorf("Expected error [%v] but got [%v] instead", ErrUserNotFound, err)
+		}
+		if !c.expectError && err != nil {
+			t.Errorf("Unexpected error on successful test [%s]: %v", c.name, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("There were unfulfilled expectations: %s", err)
+		}
+	}
+}
+
+//TestGetNoRows checks that the SQLStore's getters return
+//ErrUserNotFound when no user matches
+func TestGetNoRows(t *testing.T) {
+	cases := []struct {
+		name  string
+		query string
+		arg   interface{}
+		get   func(ss *SQLStore) (*User, error)
+	}{
+		{
+			"GetByID",
+			"where id=?",
+			int64(2),
+			func(ss *SQLStore) (*User, error) { return ss.GetByID(context.Background(), 2) },
+		},
+		{
+			"GetByEmail",
+			"where email=?",
+			"test@test.com",
+			func(ss *SQLStore) (*User, error) { return ss.GetByEmail(context.Background(), "test@test.com") },
+		},
+		{
+			"GetByUserName",
+			"where username=?",
+			"username",
+			func(ss *SQLStore) (*User, error) { return ss.GetByUserName(context.Background(), "username") },
+		},
+	}
+
+	for _, c := range cases {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("There was a problem opening a database connection: [%v]", err)
+		}
+		defer db.Close()
+
+		mainSQLStore := NewSQLStore(db)
+		emptyRows := mock.NewRows([]string{"ID", "Email", "PassHash", "UserName", "FirstName", "LastName", "PhotoURL", "Bio", "DisplayName", "Locale", "EmailVerified", "PendingEmail", "DeletedAt"})
+		mock.ExpectQuery(c.query).WithArgs(c.arg).WillReturnRows(emptyRows)
+
+		user, err := c.get(mainSQLStore)
+		if user != nil || err != ErrUserNotFound {
+			t.Errorf("case %s: expected error [%v] but got [%v] instead", c.name, ErrUserNotFound, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("There were unfulfilled expectations: %s", err)
+		}
+	}
+}
+
+//TestInsertDuplicate checks that the SQLStore's Insert translates
+//duplicate-key errors from MySQL into typed errors
+func TestInsertDuplicate(t *testing.T) {
+	cases := []struct {
+		name        string
+		dbErr       error
+		expectError error
+	}{
+		{
+			"Email Taken",
+			&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'test@test.com' for key 'email'"},
+			ErrEmailTaken,
+		},
+		{
+			"Email Taken MySQL 8",
+			&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'test@test.com' for key 'users.email'"},
+			ErrEmailTaken,
+		},
+		{
+			"UserName Taken",
+			&mysql.MySQLError{Number: 1062, Message: "Duplicate entry 'username' for key 'username'"},
+			ErrUserNameTaken,
+		},
+		{
+			"Other MySQL Error",
+			&mysql.MySQLError{Number: 1406, Message: "Data too long for column 'first_name' at row 1"},
+			nil,
+		},
+	}
+
+	for _, c := range cases {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("There was a problem opening a database connection: [%v]", err)
+		}
+		defer db.Close()
+
+		mainSQLStore := NewSQLStore(db)
+		mock.ExpectExec("insert into users").WillReturnError(c.dbErr)
+
+		user, err := mainSQLStore.Insert(context.Background(), &User{Email: "test@test.com", UserName: "username"})
+		if user != nil {
+			t.Errorf("case %s: expected no user but got %+v", c.name, user)
+		}
+		if c.expectError != nil && err != c.expectError {
+			t.Errorf("case %s: expected error [%v] but got [%v] instead", c.name, c.expectError, err)
+		}
+		if c.expectError == nil && err != c.dbErr {
+			t.Errorf("case %s: expected the original error [%v] but got [%v] instead", c.name, c.dbErr, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("There were unfulfilled expectations: %s", err)
+		}
+	}
+}
+
+//TestGetByIDCanceled checks that the SQLStore stops waiting
+//on a slow query once its context is done
+func TestGetByIDCanceled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("There was a problem opening a database connection: [%v]", err)
+	}
+	defer db.Close()
+
+	mainSQLStore := NewSQLStore(db)
+	row := mock.NewRows([]string{"ID", "Email", "PassHash", "UserName", "FirstName", "LastName", "PhotoURL", "Bio", "DisplayName", "Locale", "EmailVerified", "PendingEmail", "DeletedAt"}).
+		AddRow(1, "test@test.com", []byte("passhash123"), "username", "firstname", "lastname", "photourl", "", "", "", false, "", nil)
+	mock.ExpectQuery("where id=?").WithArgs(1).WillDelayFor(time.Second).WillReturnRows(row)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	user, err := mainSQLStore.GetByID(ctx, 1)
+	if user != nil || err == nil {
+		t.Errorf("Expected error from canceled query but got user [%v]", user)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Query was not canceled: took %v", elapsed)
+	}
+}
@@ -0,0 +1,153 @@
+package users
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//Rule codes reported by PasswordPolicy.Check
+const (
+	RulePasswordTooLong     = "password_too_long"
+	RulePasswordCharClasses = "password_character_classes"
+	RulePasswordIdentity    = "password_matches_identity"
+	RulePasswordBreached    = "password_breached"
+)
+
+//bcryptMaxPasswordLength is the number of password bytes bcrypt
+//actually hashes; anything after that is silently ignored
+const bcryptMaxPasswordLength = 72
+
+//PasswordPolicy represents the rules a password must satisfy
+type PasswordPolicy struct {
+	//MinLength is the minimum number of characters
+	MinLength int
+	//MaxLength is the maximum number of bytes. It should be no more
+	//than 72, since bcrypt ignores everything after the 72nd byte.
+	MaxLength int
+	//RequireUpper, RequireLower, RequireDigit and RequireSymbol
+	//require at least one character of the given class
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	//RejectIdentity rejects passwords equal to the user name,
+	//email address, or the local part of the email address
+	RejectIdentity bool
+	//breached is the set of lower-cased common or breached passwords
+	breached map[string]struct{}
+}
+
+//DefaultPasswordPolicy is the policy consulted when validating
+//new users and password changes. Adjust it at startup to change
+//the rules for the whole server.
+var DefaultPasswordPolicy = &PasswordPolicy{
+	MinLength:      6,
+	MaxLength:      bcryptMaxPasswordLength,
+	RejectIdentity: true,
+}
+
+//LoadBreachedPasswords reads a list of common or breached passwords
+//from the file at `path`, one per line, and rejects them in future
+//checks. Blank lines and lines starting with # are ignored.
+//Comparisons against the list are case-insensitive.
+func (pp *PasswordPolicy) LoadBreachedPasswords(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if pp.breached == nil {
+		pp.breached = map[string]struct{}{}
+	}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pp.breached[strings.ToLower(line)] = struct{}{}
+	}
+	return scanner.Err()
+}
+
+//Check checks `password` against the policy for the user with the
+//given `userName` and `email`, and returns every rule it fails,
+//or nil if it satisfies the policy
+func (pp *PasswordPolicy) Check(password string, userName string, email string) ValidationErrors {
+	var errs ValidationErrors
+	if utf8.RuneCountInString(password) < pp.MinLength {
+		errs.add("password", RulePasswordLength, fmt.Sprintf("Password must be at least %d characters long.", pp.MinLength))
+	}
+	if pp.MaxLength > 0 && len(password) > pp.MaxLength {
+		errs.add("password", RulePasswordTooLong, fmt.Sprintf("Password may be at most %d bytes long.", pp.MaxLength))
+	}
+	if !pp.hasCharClasses(password) {
+		errs.add("password", RulePasswordCharClasses, "Password must contain "+pp.charClassesDescription()+".")
+	}
+	if pp.RejectIdentity && matchesIdentity(password, userName, email) {
+		errs.add("password", RulePasswordIdentity, "Password may not be the same as your username or email.")
+	}
+	if _, found := pp.breached[strings.ToLower(password)]; found {
+		errs.add("password", RulePasswordBreached, "Password is too common, please choose another.")
+	}
+	return errs
+}
+
+//hasCharClasses reports whether `password` contains
+//every required class of character
+func (pp *PasswordPolicy) hasCharClasses(password string) bool {
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r) && !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	return (upper || !pp.RequireUpper) && (lower || !pp.RequireLower) &&
+		(digit || !pp.RequireDigit) && (symbol || !pp.RequireSymbol)
+}
+
+//charClassesDescription describes the required classes of character
+func (pp *PasswordPolicy) charClassesDescription() string {
+	var classes []string
+	if pp.RequireUpper {
+		classes = append(classes, "an uppercase letter")
+	}
+	if pp.RequireLower {
+		classes = append(classes, "a lowercase letter")
+	}
+	if pp.RequireDigit {
+		classes = append(classes, "a digit")
+	}
+	if pp.RequireSymbol {
+		classes = append(classes, "a symbol")
+	}
+	return strings.Join(classes, ", ")
+}
+
+//matchesIdentity reports whether `password` is the same as the
+//user name, email address or local part of the email address
+func matchesIdentity(password string, userName string, email string) bool {
+	email = strings.TrimSpace(email)
+	localPart := email
+	if at := strings.LastIndex(email, "@"); at >= 0 {
+		localPart = email[:at]
+	}
+	for _, ident := range []string{userName, email, localPart} {
+		if len(ident) > 0 && strings.EqualFold(password, ident) {
+			return true
+		}
+	}
+	return false
+}
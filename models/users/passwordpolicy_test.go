@@ -0,0 +1,116 @@
+package users
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPasswordPolicyCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "passwordpolicy")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	listPath := filepath.Join(dir, "breached.txt")
+	list := "# common passwords\npassword1\n\nLetMeIn123\n"
+	if err := ioutil.WriteFile(listPath, []byte(list), 0600); err != nil {
+		t.Fatalf("error writing breached password list: %v", err)
+	}
+
+	strict := &PasswordPolicy{
+		MinLength:      8,
+		MaxLength:      bcryptMaxPasswordLength,
+		RequireUpper:   true,
+		RequireLower:   true,
+		RequireDigit:   true,
+		RequireSymbol:  true,
+		RejectIdentity: true,
+	}
+	if err := strict.LoadBreachedPasswords(listPath); err != nil {
+		t.Fatalf("error loading breached password list: %v", err)
+	}
+
+	cases := []struct {
+		name          string
+		policy        *PasswordPolicy
+		password      string
+		expectedRules []string
+	}{
+		{
+			"Valid Under Default Policy",
+			DefaultPasswordPolicy,
+			"TophRocks1337",
+			nil,
+		},
+		{
+			"Too Short",
+			DefaultPasswordPolicy,
+			"Rocks",
+			[]string{RulePasswordLength},
+		},
+		{
+			"Multibyte Characters Count Once",
+			DefaultPasswordPolicy,
+			"土土土土土土",
+			nil,
+		},
+		{
+			"Too Long For Bcrypt",
+			DefaultPasswordPolicy,
+			"TophRocks1337TophRocks1337TophRocks1337TophRocks1337TophRocks1337TophRocks1337",
+			[]string{RulePasswordTooLong},
+		},
+		{
+			"Same As Username",
+			DefaultPasswordPolicy,
+			"theblindbandit",
+			[]string{RulePasswordIdentity},
+		},
+		{
+			"Same As Email Local Part",
+			DefaultPasswordPolicy,
+			"jelauria",
+			[]string{RulePasswordIdentity},
+		},
+		{
+			"Missing Character Classes",
+			strict,
+			"tophrocks",
+			[]string{RulePasswordCharClasses},
+		},
+		{
+			"Breached Password",
+			strict,
+			"letmein123",
+			[]string{RulePasswordCharClasses, RulePasswordBreached},
+		},
+		{
+			"Valid Under Strict Policy",
+			strict,
+			"Toph!Rocks!#1337",
+			nil,
+		},
+	}
+
+	for _, c := range cases {
+		errs := c.policy.Check(c.password, "TheBlindBandit", "jelauria@uw.edu")
+		if len(errs) != len(c.expectedRules) {
+			t.Errorf("case %s: expected %d failures but got %d: %v", c.name, len(c.expectedRules), len(errs), errs)
+			continue
+		}
+		for i, ve := range errs {
+			if ve.Field != "password" || ve.Rule != c.expectedRules[i] {
+				t.Errorf("case %s: expected rule '%s' but got '%s' for field '%s'", c.name, c.expectedRules[i], ve.Rule, ve.Field)
+			}
+		}
+	}
+}
+
+func TestLoadBreachedPasswordsMissingFile(t *testing.T) {
+	pp := &PasswordPolicy{}
+	if err := pp.LoadBreachedPasswords(filepath.Join(os.TempDir(), "does-not-exist.txt")); err == nil {
+		t.Error("expected error when loading a breached password list that doesn't exist")
+	}
+}
@@ -0,0 +1,16 @@
+package users
+
+import (
+	"database/sql"
+)
+
+//PostgresStore is a Store backed by a PostgreSQL database
+type PostgresStore struct {
+	*SQLStore
+}
+
+//NewPostgresStore constructs a PostgresStore. Open `db` with
+//the "postgres" driver, which is registered by this package.
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{&SQLStore{db, Postgres}}
+}
@@ -0,0 +1,24 @@
+package users
+
+import (
+	"database/sql"
+
+	//registers the "sqlite3" driver
+	_ "github.com/mattn/go-sqlite3"
+)
+
+//SQLiteStore is a Store backed by a SQLite database. It is handy
+//for hermetic tests and local development, since the database is
+//just a file. SQLite allows only one writer at a time, so call
+//db.SetMaxOpenConns(1) when the store is used concurrently.
+type SQLiteStore struct {
+	*SQLStore
+}
+
+//NewSQLiteStore constructs a SQLiteStore. Open `db` with
+//the "sqlite3" driver, which is registered by this package.
+//The driver needs cgo: in builds without it, the package still
+//builds, but opening a SQLite database fails.
+func NewSQLiteStore(db *sql.DB) *SQLiteStore {
+	return &SQLiteStore{&SQLStore{db, SQLite}}
+}
@@ -0,0 +1,93 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+//SQLTokenStore is a TokenStore backed by the reset_tokens table of
+//the users database, so that tokens survive restarts and can be
+//redeemed on any instance of the server. As with MemTokenStore, only
+//hashes of the tokens are kept. Expiry times are stored as
+//milliseconds since the Unix epoch and compared with the server's
+//clock, so they mean the same thing in every Dialect.
+type SQLTokenStore struct {
+	DB      *sql.DB
+	Dialect Dialect
+}
+
+//NewSQLTokenStore constructs a SQLTokenStore for `db`, whose
+//queries are adapted to the database by `dialect`
+func NewSQLTokenStore(db *sql.DB, dialect Dialect) *SQLTokenStore {
+	return &SQLTokenStore{db, dialect}
+}
+
+//Issue creates and returns a new token for the user with the
+//given ID, which expires after `ttl`. Expired tokens are deleted
+//at the same time.
+func (ts *SQLTokenStore) Issue(ctx context.Context, userID int64, ttl time.Duration) (string, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	if _, err := ts.DB.ExecContext(ctx, ts.Dialect.Rebind("delete from reset_tokens where expires_at<=?"), unixMillis(now)); err != nil {
+		return "", err
+	}
+	insq := "insert into reset_tokens(token_hash, user_id, expires_at) values (?,?,?)"
+	if _, err := ts.DB.ExecContext(ctx, ts.Dialect.Rebind(insq), hashToken(token), userID, unixMillis(now.Add(ttl))); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+//Lookup returns the ID of the user the token belongs to,
+//without redeeming it
+func (ts *SQLTokenStore) Lookup(ctx context.Context, token string) (int64, error) {
+	var userID int64
+	selq := "select user_id from reset_tokens where token_hash=? and expires_at>?"
+	err := ts.DB.QueryRowContext(ctx, ts.Dialect.Rebind(selq), hashToken(token), unixMillis(time.Now())).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return 0, ErrTokenNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return userID, nil
+}
+
+//Redeem returns the ID of the user the token belongs to and
+//removes it from the store, so it can't be used again. The token is
+//only redeemed by whichever request manages to delete it, so two
+//requests racing with the same token can't both redeem it.
+func (ts *SQLTokenStore) Redeem(ctx context.Context, token string) (int64, error) {
+	userID, err := ts.Lookup(ctx, token)
+	if err != nil {
+		return 0, err
+	}
+	delq := "delete from reset_tokens where token_hash=? and expires_at>?"
+	res, err := ts.DB.ExecContext(ctx, ts.Dialect.Rebind(delq), hashToken(token), unixMillis(time.Now()))
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if deleted == 0 {
+		return 0, ErrTokenNotFound
+	}
+	return userID, nil
+}
+
+//RevokeAll removes every token of the user with the given ID
+func (ts *SQLTokenStore) RevokeAll(ctx context.Context, userID int64) error {
+	_, err := ts.DB.ExecContext(ctx, ts.Dialect.Rebind("delete from reset_tokens where user_id=?"), userID)
+	return err
+}
+
+//unixMillis returns `t` in milliseconds since the Unix epoch
+func unixMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
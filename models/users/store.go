@@ -1,31 +1,96 @@
 package users
 
 import (
+	"context"
 	"errors"
+	"time"
 )
 
 //ErrUserNotFound is returned when the user can't be found
 var ErrUserNotFound = errors.New("user not found")
 
-//Store represents a store for Users
+//ErrEmailTaken is returned when another user already has the email
+var ErrEmailTaken = errors.New("email already taken")
+
+//ErrUserNameTaken is returned when another user already has the username
+var ErrUserNameTaken = errors.New("username already taken")
+
+//ErrNotSupported is returned by a TimeoutStore when the Store
+//it wraps doesn't implement the capability being used
+var ErrNotSupported = errors.New("not supported by the user store")
+
+//Store represents a store for Users. Every method takes a context,
+//and implementations backed by a database server should give up
+//and return the context's error once it is done.
 type Store interface {
 	//GetByID returns the User with the given ID
-	GetByID(id int64) (*User, error)
+	GetByID(ctx context.Context, id int64) (*User, error)
 
 	//GetByEmail returns the User with the given email
-	GetByEmail(email string) (*User, error)
+	GetByEmail(ctx context.Context, email string) (*User, error)
 
 	//GetByUserName returns the User with the given Username
-	GetByUserName(username string) (*User, error)
+	GetByUserName(ctx context.Context, username string) (*User, error)
 
 	//Insert inserts the user into the database, and returns
 	//the newly-inserted User, complete with the DBMS-assigned ID
-	Insert(user *User) (*User, error)
+	Insert(ctx context.Context, user *User) (*User, error)
 
 	//Update applies UserUpdates to the given user ID
 	//and returns the newly-updated user
-	Update(id int64, updates *Updates) (*User, error)
+	Update(ctx context.Context, id int64, updates *Updates) (*User, error)
+
+	//SetPassHash replaces the password hash of the user with the given ID
+	SetPassHash(ctx context.Context, id int64, passHash []byte) error
 
 	//Delete deletes the user with the given ID
-	Delete(id int64) error
+	Delete(ctx context.Context, id int64) error
+}
+
+//EmailChangeStore is a Store that can change the email addresses of
+//users once they confirm the new address.
+//Check for it with a type assertion on a Store.
+type EmailChangeStore interface {
+	Store
+
+	//SetPendingEmail saves `email` as the address the user with
+	//the given ID wants to change to, until they confirm it
+	SetPendingEmail(ctx context.Context, id int64, email string) error
+
+	//ConfirmEmail makes `email` the email address of the user with the
+	//given ID, as long as it is still their pending email, and returns
+	//the updated user. Unless the user has a custom photo, their
+	//PhotoURL is changed to the Gravatar image for the new address.
+	//Both changes are made at once, and ErrNoPendingEmail is returned
+	//if `email` is not pending. The new address is also marked verified.
+	ConfirmEmail(ctx context.Context, id int64, email string) (*User, error)
+}
+
+//VerificationStore is a Store that can record that users have
+//verified their email address.
+//Check for it with a type assertion on a Store.
+type VerificationStore interface {
+	Store
+
+	//VerifyEmail marks the email address of the user with the given ID
+	//as verified and returns the updated user, as long as their email
+	//is still `email`. ErrInvalidToken is returned if it has changed.
+	VerifyEmail(ctx context.Context, id int64, email string) (*User, error)
+}
+
+//SoftDeleteStore is a Store that can soft-delete users, keeping their
+//account for a grace period in which they can restore it.
+//Check for it with a type assertion on a Store.
+type SoftDeleteStore interface {
+	Store
+
+	//SetDeletedAt soft-deletes the user with the given ID, recording
+	//`deletedAt` as when they deleted their account. Passing nil
+	//restores the account. Soft-deleted users are still returned by
+	//the Get methods, with DeletedAt set, until they are purged.
+	SetDeletedAt(ctx context.Context, id int64, deletedAt *time.Time) error
+
+	//PurgeDeleted deletes every user soft-deleted at or before
+	//`before`, and returns how many users were deleted
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
 }
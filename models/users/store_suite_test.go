@@ -0,0 +1,94 @@
+package users_test
+
+import (
+	"assignments-jelauria/servers/gateway/models/users"
+	"assignments-jelauria/servers/gateway/models/users/userstest"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMemStoreSuite(t *testing.T) {
+	userstest.RunStoreSuite(t, func(t *testing.T) users.Store {
+		return users.NewMemStore()
+	})
+}
+
+//TestSQLStoreSuite runs the suite against the MySQL database at
+//TESTDSN, which must set parseTime=true, and is skipped if that isn't
+//set. The migrations are applied first, and every user in that
+//database is deleted before each test, so never point it at a
+//database holding real users.
+func TestSQLStoreSuite(t *testing.T) {
+	dsn := os.Getenv("TESTDSN")
+	if len(dsn) == 0 {
+		t.Skip("set TESTDSN to run the suite against MySQL")
+	}
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("error opening database: %v", err)
+	}
+	defer db.Close()
+	migrateUp(t, db, users.MySQL)
+
+	userstest.RunStoreSuite(t, func(t *testing.T) users.Store {
+		if _, err := db.Exec("delete from users"); err != nil {
+			t.Fatalf("error emptying users table: %v", err)
+		}
+		return users.NewSQLStore(db)
+	})
+}
+
+//TestSQLiteStoreSuite runs the suite against a new SQLite
+//database for each test, created by the migrations. It is skipped
+//if the sqlite3 driver can't open databases, as in builds without cgo.
+func TestSQLiteStoreSuite(t *testing.T) {
+	userstest.RunStoreSuite(t, func(t *testing.T) users.Store {
+		db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "users.db"))
+		if err != nil {
+			t.Fatalf("error opening database: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		if err := db.Ping(); err != nil {
+			t.Skipf("sqlite3 driver is not available: %v", err)
+		}
+		db.SetMaxOpenConns(1)
+		migrateUp(t, db, users.SQLite)
+		return users.NewSQLiteStore(db)
+	})
+}
+
+//TestPostgresStoreSuite runs the suite against the Postgres database
+//at TESTPGDSN, and is skipped if that isn't set. As with TESTDSN, every
+//user in that database is deleted before each test.
+func TestPostgresStoreSuite(t *testing.T) {
+	dsn := os.Getenv("TESTPGDSN")
+	if len(dsn) == 0 {
+		t.Skip("set TESTPGDSN to run the suite against Postgres")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("error opening database: %v", err)
+	}
+	defer db.Close()
+	migrateUp(t, db, users.Postgres)
+
+	userstest.RunStoreSuite(t, func(t *testing.T) users.Store {
+		if _, err := db.Exec("delete from users"); err != nil {
+			t.Fatalf("error emptying users table: %v", err)
+		}
+		return users.NewPostgresStore(db)
+	})
+}
+
+//migrateUp applies the migrations for `dialect` to `db`
+func migrateUp(t *testing.T, db *sql.DB, dialect users.Dialect) {
+	migrator, err := users.NewMigrator(db, dialect)
+	if err != nil {
+		t.Fatalf("error loading migrations: %v", err)
+	}
+	if _, err := migrator.Up(); err != nil {
+		t.Fatalf("error migrating database: %v", err)
+	}
+}
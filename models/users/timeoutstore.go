@@ -0,0 +1,179 @@
+package users
+
+import (
+	"context"
+	"time"
+)
+
+//TimeoutStore wraps a Store, giving each call to it a deadline, so
+//that a slow database can't hold up a request for long. Calls also
+//end early if the context passed to them is canceled, for example
+//because the client went away.
+//
+//TimeoutStore implements EmailChangeStore, VerificationStore and
+//SoftDeleteStore, and its methods from those interfaces return
+//ErrNotSupported if the Store it wraps doesn't implement them.
+type TimeoutStore struct {
+	Store   Store
+	Timeout time.Duration
+}
+
+//NewTimeoutStore constructs a TimeoutStore that allows each
+//call to `store` at most `timeout` to complete
+func NewTimeoutStore(store Store, timeout time.Duration) *TimeoutStore {
+	return &TimeoutStore{store, timeout}
+}
+
+//GetByID returns the User with the given ID
+func (ts *TimeoutStore) GetByID(ctx context.Context, id int64) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, ts.Timeout)
+	defer cancel()
+	return ts.Store.GetByID(ctx, id)
+}
+
+//GetByEmail returns the User with the given email
+func (ts *TimeoutStore) GetByEmail(ctx context.Context, email string) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, ts.Timeout)
+	defer cancel()
+	return ts.Store.GetByEmail(ctx, email)
+}
+
+//GetByUserName returns the User with the given Username
+func (ts *TimeoutStore) GetByUserName(ctx context.Context, username string) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, ts.Timeout)
+	defer cancel()
+	return ts.Store.GetByUserName(ctx, username)
+}
+
+//Insert inserts the user into the store, and returns
+//the newly-inserted User, complete with its assigned ID
+func (ts *TimeoutStore) Insert(ctx context.Context, user *User) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, ts.Timeout)
+	defer cancel()
+	return ts.Store.Insert(ctx, user)
+}
+
+//Update applies UserUpdates to the given user ID
+//and returns the newly-updated user
+func (ts *TimeoutStore) Update(ctx context.Context, id int64, updates *Updates) (*User, error) {
+	ctx, cancel := context.WithTimeout(ctx, ts.Timeout)
+	defer cancel()
+	return ts.Store.Update(ctx, id, updates)
+}
+
+//SetPassHash replaces the password hash of the user with the given ID
+func (ts *TimeoutStore) SetPassHash(ctx context.Context, id int64, passHash []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, ts.Timeout)
+	defer cancel()
+	return ts.Store.SetPassHash(ctx, id, passHash)
+}
+
+//SetPendingEmail saves `email` as the address the user with
+//the given ID wants to change to, until they confirm it
+func (ts *TimeoutStore) SetPendingEmail(ctx context.Context, id int64, email string) error {
+	store, ok := ts.Store.(EmailChangeStore)
+	if !ok {
+		return ErrNotSupported
+	}
+	ctx, cancel := context.WithTimeout(ctx, ts.Timeout)
+	defer cancel()
+	return store.SetPendingEmail(ctx, id, email)
+}
+
+//ConfirmEmail makes `email` the email address of the user with the
+//given ID, as long as it is still their pending email
+func (ts *TimeoutStore) ConfirmEmail(ctx context.Context, id int64, email string) (*User, error) {
+	store, ok := ts.Store.(EmailChangeStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	ctx, cancel := context.WithTimeout(ctx, ts.Timeout)
+	defer cancel()
+	return store.ConfirmEmail(ctx, id, email)
+}
+
+//VerifyEmail marks the email address of the user with the given
+//ID as verified, as long as their email is still `email`
+func (ts *TimeoutStore) VerifyEmail(ctx context.Context, id int64, email string) (*User, error) {
+	store, ok := ts.Store.(VerificationStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	ctx, cancel := context.WithTimeout(ctx, ts.Timeout)
+	defer cancel()
+	return store.VerifyEmail(ctx, id, email)
+}
+
+//Delete deletes the user with the given ID
+func (ts *TimeoutStore) Delete(ctx context.Context, id int64) error {
+	ctx, cancel := context.WithTimeout(ctx, ts.Timeout)
+	defer cancel()
+	return ts.Store.Delete(ctx, id)
+}
+
+//SetDeletedAt soft-deletes the user with the given ID,
+//or restores them if `deletedAt` is nil
+func (ts *TimeoutStore) SetDeletedAt(ctx context.Context, id int64, deletedAt *time.Time) error {
+	store, ok := ts.Store.(SoftDeleteStore)
+	if !ok {
+		return ErrNotSupported
+	}
+	ctx, cancel := context.WithTimeout(ctx, ts.Timeout)
+	defer cancel()
+	return store.SetDeletedAt(ctx, id, deletedAt)
+}
+
+//PurgeDeleted deletes every user soft-deleted at or before `before`
+func (ts *TimeoutStore) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	store, ok := ts.Store.(SoftDeleteStore)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	ctx, cancel := context.WithTimeout(ctx, ts.Timeout)
+	defer cancel()
+	return store.PurgeDeleted(ctx, before)
+}
+
+//TimeoutTokenStore wraps a TokenStore, giving each call to it a
+//deadline in the same way as TimeoutStore
+type TimeoutTokenStore struct {
+	TokenStore TokenStore
+	Timeout    time.Duration
+}
+
+//NewTimeoutTokenStore constructs a TimeoutTokenStore that allows
+//each call to `store` at most `timeout` to complete
+func NewTimeoutTokenStore(store TokenStore, timeout time.Duration) *TimeoutTokenStore {
+	return &TimeoutTokenStore{store, timeout}
+}
+
+//Issue creates and returns a new token for the user with the
+//given ID, which expires after `ttl`
+func (ts *TimeoutTokenStore) Issue(ctx context.Context, userID int64, ttl time.Duration) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, ts.Timeout)
+	defer cancel()
+	return ts.TokenStore.Issue(ctx, userID, ttl)
+}
+
+//Lookup returns the ID of the user the token belongs to,
+//without redeeming it
+func (ts *TimeoutTokenStore) Lookup(ctx context.Context, token string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, ts.Timeout)
+	defer cancel()
+	return ts.TokenStore.Lookup(ctx, token)
+}
+
+//Redeem returns the ID of the user the token belongs to and
+//removes it from the store, so it can't be used again
+func (ts *TimeoutTokenStore) Redeem(ctx context.Context, token string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, ts.Timeout)
+	defer cancel()
+	return ts.TokenStore.Redeem(ctx, token)
+}
+
+//RevokeAll removes every token of the user with the given ID
+func (ts *TimeoutTokenStore) RevokeAll(ctx context.Context, userID int64) error {
+	ctx, cancel := context.WithTimeout(ctx, ts.Timeout)
+	defer cancel()
+	return ts.TokenStore.RevokeAll(ctx, userID)
+}
@@ -0,0 +1,95 @@
+package users
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+//slowStore is a Store whose GetByID blocks until its context is done
+type slowStore struct {
+	*MemStore
+}
+
+func (ss *slowStore) GetByID(ctx context.Context, id int64) (*User, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+//plainStore hides the optional capabilities of the Store it wraps
+type plainStore struct {
+	Store
+}
+
+func TestTimeoutStore(t *testing.T) {
+	store := NewTimeoutStore(&slowStore{NewMemStore()}, 10*time.Millisecond)
+
+	cases := []struct {
+		name        string
+		ctx         func() (context.Context, context.CancelFunc)
+		expectError error
+	}{
+		{
+			"Deadline Passes",
+			func() (context.Context, context.CancelFunc) { return context.WithCancel(context.Background()) },
+			context.DeadlineExceeded,
+		},
+		{
+			"Caller Cancels",
+			func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx, cancel
+			},
+			context.Canceled,
+		},
+	}
+
+	for _, c := range cases {
+		ctx, cancel := c.ctx()
+		start := time.Now()
+		if _, err := store.GetByID(ctx, 1); err != c.expectError {
+			t.Errorf("case %s: incorrect error: expected %v but got %v", c.name, c.expectError, err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("case %s: call took %v, longer than the timeout", c.name, elapsed)
+		}
+		cancel()
+	}
+
+	//calls that finish in time are passed through
+	user, err := store.Insert(context.Background(), &User{Email: "toph@test.com", UserName: "TheBlindBandit"})
+	if err != nil {
+		t.Fatalf("unexpected error inserting user: %v", err)
+	}
+	if _, err := store.GetByEmail(context.Background(), user.Email); err != nil {
+		t.Errorf("unexpected error getting user: %v", err)
+	}
+}
+
+func TestTimeoutStoreNotSupported(t *testing.T) {
+	store := NewTimeoutStore(plainStore{NewMemStore()}, time.Second)
+	user, err := store.Insert(context.Background(), &User{Email: "toph@test.com", UserName: "TheBlindBandit"})
+	if err != nil {
+		t.Fatalf("unexpected error inserting user: %v", err)
+	}
+	if err := store.SetPendingEmail(context.Background(), user.ID, "toph@beifong.com"); err != ErrNotSupported {
+		t.Errorf("incorrect error setting pending email: expected %v but got %v", ErrNotSupported, err)
+	}
+	if _, err := store.VerifyEmail(context.Background(), user.ID, user.Email); err != ErrNotSupported {
+		t.Errorf("incorrect error verifying email: expected %v but got %v", ErrNotSupported, err)
+	}
+	if err := store.SetDeletedAt(context.Background(), user.ID, nil); err != ErrNotSupported {
+		t.Errorf("incorrect error restoring user: expected %v but got %v", ErrNotSupported, err)
+	}
+
+	//stores that have the capabilities are passed through
+	store.Store = NewMemStore()
+	user, err = store.Insert(context.Background(), &User{Email: "toph@test.com", UserName: "TheBlindBandit"})
+	if err != nil {
+		t.Fatalf("unexpected error inserting user: %v", err)
+	}
+	if _, err := store.VerifyEmail(context.Background(), user.ID, user.Email); err != nil {
+		t.Errorf("unexpected error verifying email: %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+package users
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+)
+
+//tokenLength is the number of random bytes in a token
+const tokenLength = 32
+
+//ErrTokenNotFound is returned when a token is unknown,
+//has expired, or has already been redeemed
+var ErrTokenNotFound = errors.New("token not found or expired")
+
+//TokenStore represents a store of single-use, expiring tokens,
+//such as the ones emailed to users who forgot their password.
+//Each token belongs to a single user. As with Store, every method
+//takes a context, and stores backed by a server should give up and
+//return the context's error once it is done.
+type TokenStore interface {
+	//Issue creates and returns a new token for the user with the
+	//given ID, which expires after `ttl`
+	Issue(ctx context.Context, userID int64, ttl time.Duration) (string, error)
+
+	//Lookup returns the ID of the user the token belongs to,
+	//without redeeming it
+	Lookup(ctx context.Context, token string) (int64, error)
+
+	//Redeem returns the ID of the user the token belongs to and
+	//removes it from the store, so it can't be used again
+	Redeem(ctx context.Context, token string) (int64, error)
+
+	//RevokeAll removes every token of the user with the given ID,
+	//for example once one of them has been used to reset the
+	//user's password
+	RevokeAll(ctx context.Context, userID int64) error
+}
+
+//tokenEntry is a token saved in a MemTokenStore
+type tokenEntry struct {
+	userID  int64
+	expires time.Time
+}
+
+//MemTokenStore is a TokenStore kept in process memory.
+//Tokens are lost when the process restarts, and aren't
+//shared between instances of the server, so it should be used
+//only for testing and local development. Use a SQLTokenStore
+//in production. It never blocks on I/O, so it ignores the
+//contexts passed to its methods.
+type MemTokenStore struct {
+	mx      sync.Mutex
+	entries map[string]*tokenEntry
+}
+
+//NewMemTokenStore constructs and returns a new MemTokenStore
+func NewMemTokenStore() *MemTokenStore {
+	return &MemTokenStore{entries: map[string]*tokenEntry{}}
+}
+
+//Issue creates and returns a new token for the user with the
+//given ID, which expires after `ttl`
+func (ts *MemTokenStore) Issue(ctx context.Context, userID int64, ttl time.Duration) (string, error) {
+	token, err := newToken()
+	if err != nil {
+		return "", err
+	}
+
+	ts.mx.Lock()
+	defer ts.mx.Unlock()
+	ts.purgeExpired()
+	ts.entries[hashToken(token)] = &tokenEntry{userID, time.Now().Add(ttl)}
+	return token, nil
+}
+
+//Lookup returns the ID of the user the token belongs to,
+//without redeeming it
+func (ts *MemTokenStore) Lookup(ctx context.Context, token string) (int64, error) {
+	ts.mx.Lock()
+	defer ts.mx.Unlock()
+	entry, found := ts.entries[hashToken(token)]
+	if !found || time.Now().After(entry.expires) {
+		return 0, ErrTokenNotFound
+	}
+	return entry.userID, nil
+}
+
+//Redeem returns the ID of the user the token belongs to and
+//removes it from the store, so it can't be used again
+func (ts *MemTokenStore) Redeem(ctx context.Context, token string) (int64, error) {
+	ts.mx.Lock()
+	defer ts.mx.Unlock()
+	key := hashToken(token)
+	entry, found := ts.entries[key]
+	if !found {
+		return 0, ErrTokenNotFound
+	}
+	delete(ts.entries, key)
+	if time.Now().After(entry.expires) {
+		return 0, ErrTokenNotFound
+	}
+	return entry.userID, nil
+}
+
+//RevokeAll removes every token of the user with the given ID
+func (ts *MemTokenStore) RevokeAll(ctx context.Context, userID int64) error {
+	ts.mx.Lock()
+	defer ts.mx.Unlock()
+	for key, entry := range ts.entries {
+		if entry.userID == userID {
+			delete(ts.entries, key)
+		}
+	}
+	return nil
+}
+
+//purgeExpired removes expired tokens. The caller must hold the lock.
+func (ts *MemTokenStore) purgeExpired() {
+	now := time.Now()
+	for key, entry := range ts.entries {
+		if now.After(entry.expires) {
+			delete(ts.entries, key)
+		}
+	}
+}
+
+//newToken returns a new random token
+func newToken() (string, error) {
+	randomBytes := make([]byte, tokenLength)
+	if _, err := rand.Read(randomBytes); err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(randomBytes), nil
+}
+
+//hashToken returns the key a token is stored under. Only hashes of
+//tokens are kept, so the contents of the store can't be used to
+//redeem them.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
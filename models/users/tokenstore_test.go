@@ -0,0 +1,132 @@
+package users
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMemTokenStore(t *testing.T) {
+	testTokenStore(t, func(t *testing.T) TokenStore {
+		return NewMemTokenStore()
+	})
+}
+
+//TestSQLTokenStore runs the TokenStore tests against a new SQLite
+//database for each test, created by the migrations. It is skipped
+//if the sqlite3 driver can't open databases, as in builds without cgo.
+func TestSQLTokenStore(t *testing.T) {
+	testTokenStore(t, func(t *testing.T) TokenStore {
+		db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "users.db"))
+		if err != nil {
+			t.Fatalf("error opening database: %v", err)
+		}
+		t.Cleanup(func() { db.Close() })
+		if err := db.Ping(); err != nil {
+			t.Skipf("sqlite3 driver is not available: %v", err)
+		}
+		migrator, err := NewMigrator(db, SQLite)
+		if err != nil {
+			t.Fatalf("error loading migrations: %v", err)
+		}
+		if _, err := migrator.Up(); err != nil {
+			t.Fatalf("error migrating database: %v", err)
+		}
+		return NewSQLTokenStore(db, SQLite)
+	})
+}
+
+//testTokenStore runs the TokenStore tests against the stores
+//returned by `newStore`, each test getting a new store
+func testTokenStore(t *testing.T, newStore func(t *testing.T) TokenStore) {
+	t.Run("IssueAndRedeem", func(t *testing.T) {
+		testTokenStoreIssueAndRedeem(t, newStore(t))
+	})
+	t.Run("RevokeAll", func(t *testing.T) {
+		testTokenStoreRevokeAll(t, newStore(t))
+	})
+}
+
+func testTokenStoreIssueAndRedeem(t *testing.T, store TokenStore) {
+	ctx := context.Background()
+	var userID int64 = 42
+
+	if _, err := store.Lookup(ctx, "never issued"); err != ErrTokenNotFound {
+		t.Errorf("incorrect error when looking up a token that was never issued: expected %v but got %v", ErrTokenNotFound, err)
+	}
+
+	token, err := store.Issue(ctx, userID, time.Hour)
+	if err != nil {
+		t.Fatalf("error issuing token: %v", err)
+	}
+	if len(token) == 0 {
+		t.Fatal("issued token is zero-length")
+	}
+
+	//looking up the token should not use it up
+	for i := 0; i < 2; i++ {
+		id, err := store.Lookup(ctx, token)
+		if err != nil {
+			t.Fatalf("error looking up token: %v", err)
+		}
+		if id != userID {
+			t.Errorf("incorrect user ID for token: expected %d but got %d", userID, id)
+		}
+	}
+
+	id, err := store.Redeem(ctx, token)
+	if err != nil {
+		t.Fatalf("error redeeming token: %v", err)
+	}
+	if id != userID {
+		t.Errorf("incorrect user ID for redeemed token: expected %d but got %d", userID, id)
+	}
+	if _, err := store.Redeem(ctx, token); err != ErrTokenNotFound {
+		t.Errorf("incorrect error when redeeming a token twice: expected %v but got %v", ErrTokenNotFound, err)
+	}
+	if _, err := store.Lookup(ctx, token); err != ErrTokenNotFound {
+		t.Errorf("incorrect error when looking up a redeemed token: expected %v but got %v", ErrTokenNotFound, err)
+	}
+
+	expired, err := store.Issue(ctx, userID, -time.Second)
+	if err != nil {
+		t.Fatalf("error issuing token: %v", err)
+	}
+	if _, err := store.Lookup(ctx, expired); err != ErrTokenNotFound {
+		t.Errorf("incorrect error when looking up an expired token: expected %v but got %v", ErrTokenNotFound, err)
+	}
+	if _, err := store.Redeem(ctx, expired); err != ErrTokenNotFound {
+		t.Errorf("incorrect error when redeeming an expired token: expected %v but got %v", ErrTokenNotFound, err)
+	}
+}
+
+func testTokenStoreRevokeAll(t *testing.T, store TokenStore) {
+	ctx := context.Background()
+	var userID, otherID int64 = 42, 43
+	var tokens []string
+	for i := 0; i < 2; i++ {
+		token, err := store.Issue(ctx, userID, time.Hour)
+		if err != nil {
+			t.Fatalf("error issuing token: %v", err)
+		}
+		tokens = append(tokens, token)
+	}
+	other, err := store.Issue(ctx, otherID, time.Hour)
+	if err != nil {
+		t.Fatalf("error issuing token: %v", err)
+	}
+
+	if err := store.RevokeAll(ctx, userID); err != nil {
+		t.Fatalf("error revoking tokens: %v", err)
+	}
+	for _, token := range tokens {
+		if _, err := store.Redeem(ctx, token); err != ErrTokenNotFound {
+			t.Errorf("incorrect error when redeeming a revoked token: expected %v but got %v", ErrTokenNotFound, err)
+		}
+	}
+	if id, err := store.Redeem(ctx, other); err != nil || id != otherID {
+		t.Errorf("token of a different user was revoked: %d, %v", id, err)
+	}
+}
@@ -3,29 +3,51 @@ package users
 import (
 	"crypto/md5"
 	"encoding/hex"
-	"fmt"
 	"net/mail"
+	"regexp"
 	"strings"
-
-	"golang.org/x/crypto/bcrypt"
+	"time"
+	"unicode/utf8"
 )
 
 //gravatarBasePhotoURL is the base URL for Gravatar image requests.
 //See https://id.gravatar.com/site/implement/images/ for details
 const gravatarBasePhotoURL = "https://www.gravatar.com/avatar/"
 
+//maxBioLength and maxDisplayNameLength are the most characters
+//allowed in a user's bio and display name
+const (
+	maxBioLength         = 500
+	maxDisplayNameLength = 64
+)
+
+//localeFormat matches BCP 47 language tags such as "en" or "pt-BR"
+var localeFormat = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
 //bcryptCost is the default bcrypt cost to use when hashing passwords
 var bcryptCost = 13
 
 //User represents a user account in the database
 type User struct {
-	ID        int64  `json:"id"`
-	Email     string `json:"-"` //never JSON encoded/decoded
-	PassHash  []byte `json:"-"` //never JSON encoded/decoded
-	UserName  string `json:"userName"`
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
-	PhotoURL  string `json:"photoURL"`
+	ID          int64  `json:"id"`
+	Email       string `json:"-"` //never JSON encoded/decoded
+	PassHash    []byte `json:"-"` //never JSON encoded/decoded
+	UserName    string `json:"userName"`
+	FirstName   string `json:"firstName"`
+	LastName    string `json:"lastName"`
+	PhotoURL    string `json:"photoURL"`
+	Bio         string `json:"bio"`
+	DisplayName string `json:"displayName"`
+	Locale      string `json:"locale"`
+	//EmailVerified is true once the user has shown that
+	//they can receive email sent to their address
+	EmailVerified bool `json:"emailVerified"`
+	//PendingEmail is the new address the user asked to change
+	//their email to, until they confirm it
+	PendingEmail string `json:"-"` //never JSON encoded/decoded
+	//DeletedAt is when the user deleted their account, if it is
+	//waiting out its grace period before being purged
+	DeletedAt *time.Time `json:"-"` //never JSON encoded/decoded
 }
 
 //Credentials represents user sign-in credentials
@@ -44,35 +66,68 @@ type NewUser struct {
 	LastName     string `json:"lastName"`
 }
 
-//Updates represents allowed updates to a user profile
+//Updates represents allowed updates to a user profile.
+//Only the fields that are not nil are changed, so a client
+//can send just the fields it wants to update. Stores change Email
+//without confirmation, so handlers should save it as the user's
+//PendingEmail and have the user confirm it instead.
 type Updates struct {
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
+	FirstName   *string `json:"firstName,omitempty"`
+	LastName    *string `json:"lastName,omitempty"`
+	UserName    *string `json:"userName,omitempty"`
+	Email       *string `json:"email,omitempty"`
+	Bio         *string `json:"bio,omitempty"`
+	DisplayName *string `json:"displayName,omitempty"`
+	Locale      *string `json:"locale,omitempty"`
+}
+
+//PasswordChange represents a signed-in user changing their password
+type PasswordChange struct {
+	CurrentPassword string `json:"currentPassword"`
+	Password        string `json:"password"`
+	PasswordConf    string `json:"passwordConf"`
+}
+
+//Reauthentication represents a signed-in user giving their password
+//again before a sensitive change, such as to their email address
+type Reauthentication struct {
+	Password string `json:"password"`
+}
+
+//ResetRequest represents a user asking for a password reset token
+type ResetRequest struct {
+	Email string `json:"email"`
+}
+
+//PasswordReset represents a user resetting a forgotten password
+//using the token that was emailed to them
+type PasswordReset struct {
+	Token        string `json:"token"`
+	Password     string `json:"password"`
+	PasswordConf string `json:"passwordConf"`
 }
 
 //Validate validates the new user and returns an error if
-//any of the validation rules fail, or nil if its valid
+//any of the validation rules fail, or nil if its valid.
+//The password is checked against DefaultPasswordPolicy.
+//All failing rules are reported at once, as ValidationErrors.
 func (nu *NewUser) Validate() error {
-	//TODO: validate the new user according to these rules:
-	//- Email field must be a valid email address (hint: see mail.ParseAddress)
-	//- Password must be at least 6 characters
-	//- Password and PasswordConf must match
-	//- UserName must be non-zero length and may not contain spaces
-	//use fmt.Errorf() to generate appropriate error messages if
-	//the new user doesn't pass one of the validation rules
-
+	var errs ValidationErrors
 	_, err := mail.ParseAddress(nu.Email)
 	if err != nil {
-		return fmt.Errorf("Email address is invalid.")
-	}
-	if len(nu.Password) < 6 {
-		return fmt.Errorf("Password must be at least 6 characters long.")
+		errs.add("email", RuleEmailFormat, "Email address is invalid.")
 	}
+	errs = append(errs, DefaultPasswordPolicy.Check(nu.Password, nu.UserName, nu.Email)...)
 	if nu.Password != nu.PasswordConf {
-		return fmt.Errorf("Password entries do not match.")
+		errs.add("passwordConf", RulePasswordMismatch, "Password entries do not match.")
+	}
+	if len(nu.UserName) == 0 {
+		errs.add("userName", RuleUserNameRequired, "Username must be non-zero length.")
+	} else if strings.Contains(nu.UserName, " ") {
+		errs.add("userName", RuleUserNameSpaces, "Username may not contain spaces.")
 	}
-	if len(nu.UserName) == 0 || strings.Contains(nu.UserName, " ") {
-		return fmt.Errorf("Username must be non-zero length and may not contain spaces.")
+	if len(errs) > 0 {
+		return errs
 	}
 	return nil
 }
@@ -97,9 +152,13 @@ func (nu *NewUser) ToUser() (*User, error) {
 		return &User{}, valErr
 	}
 	preppedEmail := strings.Trim(nu.Email, " ")
-	byteHash := md5.Sum([]byte(strings.ToLower(preppedEmail)))
-	imgHash := hex.EncodeToString(byteHash[:])
-	finUser := &User{0, preppedEmail, nil, nu.UserName, nu.FirstName, nu.LastName, gravatarBasePhotoURL + imgHash}
+	finUser := &User{
+		Email:     preppedEmail,
+		UserName:  nu.UserName,
+		FirstName: nu.FirstName,
+		LastName:  nu.LastName,
+		PhotoURL:  gravatarURL(preppedEmail),
+	}
 	passHashErr := finUser.SetPassword(nu.Password)
 	if passHashErr != nil {
 		return &User{}, passHashErr
@@ -121,12 +180,23 @@ func (u *User) FullName() string {
 	return strings.Trim(fullName, " ")
 }
 
-//SetPassword hashes the password and stores it in the PassHash field
-func (u *User) SetPassword(password string) error {
-	//TODO: use the bcrypt package to generate a new hash of the password
-	//https://godoc.org/golang.org/x/crypto/bcrypt
+//HasCustomPhoto reports whether the user's PhotoURL was set by the
+//user, rather than being the Gravatar image for their email address
+func (u *User) HasCustomPhoto() bool {
+	return len(u.PhotoURL) > 0 && !strings.HasPrefix(u.PhotoURL, gravatarBasePhotoURL)
+}
 
-	passHash, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
+//gravatarURL returns the URL of the Gravatar image for the email address.
+//See https://en.gravatar.com/site/implement/hash/
+func gravatarURL(email string) string {
+	byteHash := md5.Sum([]byte(strings.ToLower(strings.TrimSpace(email))))
+	return gravatarBasePhotoURL + hex.EncodeToString(byteHash[:])
+}
+
+//SetPassword hashes the password with DefaultHasher
+//and stores it in the PassHash field
+func (u *User) SetPassword(password string) error {
+	passHash, err := DefaultHasher.Hash(password)
 	if err != nil {
 		return err
 	}
@@ -134,21 +204,134 @@ func (u *User) SetPassword(password string) error {
 	return nil
 }
 
+//ValidatePassword validates a new password and its confirmation
+//for the user against DefaultPasswordPolicy, and returns
+//ValidationErrors for every rule that fails, or nil if its valid
+func (u *User) ValidatePassword(password string, passwordConf string) error {
+	errs := DefaultPasswordPolicy.Check(password, u.UserName, u.Email)
+	if password != passwordConf {
+		errs.add("passwordConf", RulePasswordMismatch, "Password entries do not match.")
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
 //Authenticate compares the plaintext password against the stored hash
-//and returns an error if they don't match, or nil if they do
+//and returns an error if they don't match, or nil if they do.
+//The hash may have been produced by any of the known PasswordHashers.
+//After a successful authentication, call NeedsRehash to find out
+//whether the stored hash should be upgraded.
 func (u *User) Authenticate(password string) error {
-	//TODO: use the bcrypt package to compare the supplied
-	//password with the stored PassHash
-	//https://godoc.org/golang.org/x/crypto/bcrypt
-	return bcrypt.CompareHashAndPassword(u.PassHash, []byte(password))
+	hasher := hasherFor(u.PassHash)
+	if hasher == nil {
+		return ErrUnknownHashFormat
+	}
+	return hasher.Verify(u.PassHash, password)
+}
+
+//NeedsRehash reports whether the stored hash was produced by a
+//different algorithm than DefaultHasher's, or with weaker parameters.
+//If so, the password should be hashed again with SetPassword and
+//saved the next time the user supplies it.
+func (u *User) NeedsRehash() bool {
+	return !DefaultHasher.Recognizes(u.PassHash) || DefaultHasher.NeedsRehash(u.PassHash)
 }
 
 //ApplyUpdates applies the updates to the user. An error
 //is returned if the updates are invalid
 func (u *User) ApplyUpdates(updates *Updates) error {
-	//TODO: set the fields of `u` to the values of the related
-	//field in the `updates` struct
-	u.FirstName = updates.FirstName
-	u.LastName = updates.LastName
+	if err := updates.Validate(); err != nil {
+		return err
+	}
+	updates.apply(u)
 	return nil
 }
+
+//Validate validates the fields being updated and returns
+//ValidationErrors for every rule that fails, or nil if they're valid
+func (up *Updates) Validate() error {
+	var errs ValidationErrors
+	if up.Email != nil {
+		if _, err := mail.ParseAddress(*up.Email); err != nil {
+			errs.add("email", RuleEmailFormat, "Email address is invalid.")
+		}
+	}
+	if up.UserName != nil {
+		if len(*up.UserName) == 0 {
+			errs.add("userName", RuleUserNameRequired, "Username must be non-zero length.")
+		} else if strings.Contains(*up.UserName, " ") {
+			errs.add("userName", RuleUserNameSpaces, "Username may not contain spaces.")
+		}
+	}
+	if up.Bio != nil && utf8.RuneCountInString(*up.Bio) > maxBioLength {
+		errs.add("bio", RuleBioLength, "Bio may not be longer than 500 characters.")
+	}
+	if up.DisplayName != nil && utf8.RuneCountInString(*up.DisplayName) > maxDisplayNameLength {
+		errs.add("displayName", RuleDisplayNameLength, "Display name may not be longer than 64 characters.")
+	}
+	if up.Locale != nil && len(*up.Locale) > 0 && !localeFormat.MatchString(*up.Locale) {
+		errs.add("locale", RuleLocaleFormat, "Locale must be a language tag such as en or pt-BR.")
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+//apply sets the fields of `u` that are being updated,
+//without validating the updates
+func (up *Updates) apply(u *User) {
+	if up.FirstName != nil {
+		u.FirstName = *up.FirstName
+	}
+	if up.LastName != nil {
+		u.LastName = *up.LastName
+	}
+	if up.UserName != nil {
+		u.UserName = *up.UserName
+	}
+	if up.Email != nil {
+		u.Email = strings.TrimSpace(*up.Email)
+	}
+	if up.Bio != nil {
+		u.Bio = *up.Bio
+	}
+	if up.DisplayName != nil {
+		u.DisplayName = *up.DisplayName
+	}
+	if up.Locale != nil {
+		u.Locale = *up.Locale
+	}
+}
+
+//columns returns the database columns being updated
+//and their new values, in matching order
+func (up *Updates) columns() ([]string, []interface{}) {
+	var cols []string
+	var vals []interface{}
+	for _, f := range []struct {
+		col string
+		val *string
+	}{
+		{"first_name", up.FirstName},
+		{"last_name", up.LastName},
+		{"username", up.UserName},
+		{"email", up.Email},
+		{"bio", up.Bio},
+		{"display_name", up.DisplayName},
+		{"locale", up.Locale},
+	} {
+		if f.val == nil {
+			continue
+		}
+		cols = append(cols, f.col)
+		if f.col == "email" {
+			vals = append(vals, strings.TrimSpace(*f.val))
+		} else {
+			vals = append(vals, *f.val)
+		}
+	}
+	return cols, vals
+}
@@ -1,6 +1,8 @@
 package users
 
 import (
+	"errors"
+	"strings"
 	"testing"
 
 	"golang.org/x/crypto/bcrypt"
@@ -62,6 +64,91 @@ func TestValidate(t *testing.T) {
 	}
 }
 
+func TestValidateReportsAllErrors(t *testing.T) {
+	cases := []struct {
+		name          string
+		testUser      *NewUser
+		expectedRules map[string]string
+	}{
+		{
+			"Single Failure",
+			&NewUser{"jelauria@uw.edu", "TophRocks1337", "TophRocks1337", "The Blind Bandit", "Joyce", "Elauria"},
+			map[string]string{"userName": RuleUserNameSpaces},
+		},
+		{
+			"Every Field Fails",
+			&NewUser{"blindBanditskajdbsjb.edu", "Rocks", "Rock", "", "Joyce", "Elauria"},
+			map[string]string{
+				"email":        RuleEmailFormat,
+				"password":     RulePasswordLength,
+				"passwordConf": RulePasswordMismatch,
+				"userName":     RuleUserNameRequired,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		err := c.testUser.Validate()
+		var valErrs ValidationErrors
+		if !errors.As(err, &valErrs) {
+			t.Errorf("case %s: expected ValidationErrors but got '%v'", c.name, err)
+			continue
+		}
+		if len(valErrs) != len(c.expectedRules) {
+			t.Errorf("case %s: expected %d validation errors but got %d: %v", c.name, len(c.expectedRules), len(valErrs), err)
+		}
+		for _, ve := range valErrs {
+			if rule, found := c.expectedRules[ve.Field]; !found || rule != ve.Rule {
+				t.Errorf("case %s: unexpected rule '%s' for field '%s'", c.name, ve.Rule, ve.Field)
+			}
+		}
+	}
+}
+
+func TestValidatePassword(t *testing.T) {
+	testUser := &User{UserName: "TheBlindBandit", Email: "jelauria@uw.edu"}
+	cases := []struct {
+		name         string
+		password     string
+		passwordConf string
+		expectErr    bool
+	}{
+		{
+			"Valid Password",
+			"TophRocks1337",
+			"TophRocks1337",
+			false,
+		},
+		{
+			"Short Password",
+			"Rocks",
+			"Rocks",
+			true,
+		},
+		{
+			"Non-matching Passwords",
+			"TophRocks1337",
+			"TophRocks133",
+			true,
+		},
+		{
+			"Same as Username",
+			"TheBlindBandit",
+			"TheBlindBandit",
+			true,
+		},
+	}
+	for _, c := range cases {
+		err := testUser.ValidatePassword(c.password, c.passwordConf)
+		if c.expectErr && err == nil {
+			t.Errorf("case %s: expected error but did not get one", c.name)
+		}
+		if !c.expectErr && err != nil {
+			t.Errorf("case %s: unexpected error '%v'", c.name, err)
+		}
+	}
+}
+
 func TestFullName(t *testing.T) {
 	cases := []struct {
 		name     string
@@ -70,22 +157,22 @@ func TestFullName(t *testing.T) {
 	}{
 		{
 			"First Name Only",
-			&User{0, "jelauria@uw.edu", nil, "TheBlindBandit", "Joyce", "", "test.com"},
+			&User{Email: "jelauria@uw.edu", UserName: "TheBlindBandit", FirstName: "Joyce", LastName: "", PhotoURL: "test.com"},
 			"Joyce",
 		},
 		{
 			"Last Name Only",
-			&User{0, "jelauria@uw.edu", nil, "TheBlindBandit", "", "Elauria", "test.com"},
+			&User{Email: "jelauria@uw.edu", UserName: "TheBlindBandit", FirstName: "", LastName: "Elauria", PhotoURL: "test.com"},
 			"Elauria",
 		},
 		{
 			"No Names",
-			&User{0, "jelauria@uw.edu", nil, "TheBlindBandit", "", "", "test.com"},
+			&User{Email: "jelauria@uw.edu", UserName: "TheBlindBandit", FirstName: "", LastName: "", PhotoURL: "test.com"},
 			"",
 		},
 		{
 			"Both Names Filled",
-			&User{0, "jelauria@uw.edu", nil, "TheBlindBandit", "Joyce", "Elauria", "test.com"},
+			&User{Email: "jelauria@uw.edu", UserName: "TheBlindBandit", FirstName: "Joyce", LastName: "Elauria", PhotoURL: "test.com"},
 			"Joyce Elauria",
 		},
 	}
@@ -199,40 +286,52 @@ func TestApplyUpdates(t *testing.T) {
 	}{
 		{
 			"Update First Name",
-			&Updates{"Toph", "Elauria"},
+			&Updates{FirstName: stringPtr("Toph"), LastName: stringPtr("Elauria")},
 			"Toph",
 			"Elauria",
 		},
 		{
 			"Update Last Name",
-			&Updates{"Joyce", "Beifong"},
+			&Updates{FirstName: stringPtr("Joyce"), LastName: stringPtr("Beifong")},
 			"Joyce",
 			"Beifong",
 		},
 		{
 			"Update Both Names",
-			&Updates{"Toph", "Beifong"},
+			&Updates{FirstName: stringPtr("Toph"), LastName: stringPtr("Beifong")},
 			"Toph",
 			"Beifong",
 		},
 		{
 			"Reduce to Last Name",
-			&Updates{"", "Elauria"},
+			&Updates{FirstName: stringPtr(""), LastName: stringPtr("Elauria")},
 			"",
 			"Elauria",
 		},
 		{
 			"Reduce to First Name",
-			&Updates{"Joyce", ""},
+			&Updates{FirstName: stringPtr("Joyce"), LastName: stringPtr("")},
 			"Joyce",
 			"",
 		},
 		{
 			"Remove Both Names",
-			&Updates{"", ""},
+			&Updates{FirstName: stringPtr(""), LastName: stringPtr("")},
 			"",
 			"",
 		},
+		{
+			"Only First Name Sent",
+			&Updates{FirstName: stringPtr("Toph")},
+			"Toph",
+			"Elauria",
+		},
+		{
+			"Nothing Sent",
+			&Updates{},
+			"Joyce",
+			"Elauria",
+		},
 	}
 	nUser := &NewUser{"jelauria@uw.edu", "Toph!Rocks!#1337", "Toph!Rocks!#1337", "TheBlindBandit", "Joyce", "Elauria"}
 	for _, c := range cases {
@@ -249,3 +348,75 @@ func TestApplyUpdates(t *testing.T) {
 		}
 	}
 }
+
+func TestApplyUpdatesValidation(t *testing.T) {
+	cases := []struct {
+		name         string
+		updates      *Updates
+		expectedRule string
+	}{
+		{
+			"Valid Profile",
+			&Updates{UserName: stringPtr("Toph"), Email: stringPtr("toph@beifong.com"), Bio: stringPtr("Greatest earthbender"),
+				DisplayName: stringPtr("Toph Beifong"), Locale: stringPtr("en-US")},
+			"",
+		},
+		{
+			"Invalid Email",
+			&Updates{Email: stringPtr("not an email")},
+			RuleEmailFormat,
+		},
+		{
+			"Empty UserName",
+			&Updates{UserName: stringPtr("")},
+			RuleUserNameRequired,
+		},
+		{
+			"UserName With Spaces",
+			&Updates{UserName: stringPtr("The Blind Bandit")},
+			RuleUserNameSpaces,
+		},
+		{
+			"Bio Too Long",
+			&Updates{Bio: stringPtr(strings.Repeat("a", maxBioLength+1))},
+			RuleBioLength,
+		},
+		{
+			"Display Name Too Long",
+			&Updates{DisplayName: stringPtr(strings.Repeat("a", maxDisplayNameLength+1))},
+			RuleDisplayNameLength,
+		},
+		{
+			"Invalid Locale",
+			&Updates{Locale: stringPtr("english")},
+			RuleLocaleFormat,
+		},
+		{
+			"Cleared Locale",
+			&Updates{Locale: stringPtr("")},
+			"",
+		},
+	}
+	for _, c := range cases {
+		testUser := &User{Email: "toph@test.com", UserName: "TheBlindBandit"}
+		err := testUser.ApplyUpdates(c.updates)
+		if len(c.expectedRule) == 0 {
+			if err != nil {
+				t.Errorf("case %s: unexpected error '%v'", c.name, err)
+			}
+			continue
+		}
+		var errs ValidationErrors
+		if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Rule != c.expectedRule {
+			t.Errorf("case %s: expected a %s validation error but got '%v'", c.name, c.expectedRule, err)
+		}
+		if testUser.UserName != "TheBlindBandit" || testUser.Email != "toph@test.com" {
+			t.Errorf("case %s: invalid updates were applied", c.name)
+		}
+	}
+}
+
+//stringPtr returns a pointer to `s`, for building Updates
+func stringPtr(s string) *string {
+	return &s
+}
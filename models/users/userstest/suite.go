@@ -0,0 +1,464 @@
+//Package userstest provides a conformance test suite for
+//implementations of users.Store. Run it from the tests of
+//each new store to prove that it behaves like the existing ones.
+package userstest
+
+import (
+	"assignments-jelauria/servers/gateway/models/users"
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+//StoreFactory returns a new users.Store holding no users.
+//It should call t.Skip if the store's database is unavailable.
+type StoreFactory func(t *testing.T) users.Store
+
+//RunStoreSuite runs the conformance tests against the stores
+//returned by `factory`. Each test gets a new, empty store.
+func RunStoreSuite(t *testing.T, factory StoreFactory) {
+	tests := []struct {
+		name string
+		test func(t *testing.T, store users.Store)
+	}{
+		{"NotFound", testNotFound},
+		{"InsertAndGet", testInsertAndGet},
+		{"Uniqueness", testUniqueness},
+		{"Update", testUpdate},
+		{"PartialUpdate", testPartialUpdate},
+		{"UpdateUniqueness", testUpdateUniqueness},
+		{"SetPassHash", testSetPassHash},
+		{"ConfirmEmail", testConfirmEmail},
+		{"VerifyEmail", testVerifyEmail},
+		{"Delete", testDelete},
+		{"SoftDelete", testSoftDelete},
+		{"ConcurrentInserts", testConcurrentInserts},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			test.test(t, factory(t))
+		})
+	}
+}
+
+//newUser returns a user to insert, made unique by `n`
+func newUser(n int) *users.User {
+	return &users.User{
+		Email:     fmt.Sprintf("user%d@test.com", n),
+		PassHash:  []byte("passhash123"),
+		UserName:  fmt.Sprintf("user%d", n),
+		FirstName: "Toph",
+		LastName:  "Beifong",
+		PhotoURL:  "photourl",
+	}
+}
+
+//insert inserts the user into the store, failing the test on error
+func insert(t *testing.T, store users.Store, user *users.User) *users.User {
+	inserted, err := store.Insert(context.Background(), user)
+	if err != nil {
+		t.Fatalf("error inserting user: %v", err)
+	}
+	return inserted
+}
+
+func testNotFound(t *testing.T, store users.Store) {
+	cases := []struct {
+		name string
+		call func() error
+	}{
+		{
+			"GetByID",
+			func() error { _, err := store.GetByID(context.Background(), 1); return err },
+		},
+		{
+			"GetByEmail",
+			func() error { _, err := store.GetByEmail(context.Background(), "toph@test.com"); return err },
+		},
+		{
+			"GetByUserName",
+			func() error { _, err := store.GetByUserName(context.Background(), "TheBlindBandit"); return err },
+		},
+		{
+			"Update",
+			func() error {
+				_, err := store.Update(context.Background(), 1, &users.Updates{FirstName: stringPtr("Toph")})
+				return err
+			},
+		},
+		{
+			"SetPassHash",
+			func() error { return store.SetPassHash(context.Background(), 1, []byte("passhash123")) },
+		},
+	}
+	for _, c := range cases {
+		if err := c.call(); err != users.ErrUserNotFound {
+			t.Errorf("case %s: incorrect error: expected %v but got %v", c.name, users.ErrUserNotFound, err)
+		}
+	}
+}
+
+func testInsertAndGet(t *testing.T, store users.Store) {
+	expected := insert(t, store, newUser(1))
+	if expected.ID == 0 {
+		t.Fatalf("inserted user was not assigned an ID")
+	}
+	cases := []struct {
+		name string
+		get  func() (*users.User, error)
+	}{
+		{
+			"GetByID",
+			func() (*users.User, error) { return store.GetByID(context.Background(), expected.ID) },
+		},
+		{
+			"GetByEmail",
+			func() (*users.User, error) { return store.GetByEmail(context.Background(), expected.Email) },
+		},
+		{
+			"GetByUserName",
+			func() (*users.User, error) { return store.GetByUserName(context.Background(), expected.UserName) },
+		},
+	}
+	for _, c := range cases {
+		user, err := c.get()
+		if err != nil {
+			t.Errorf("case %s: unexpected error: %v", c.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(user, expected) {
+			t.Errorf("case %s: incorrect user returned: expected %+v but got %+v", c.name, expected, user)
+		}
+	}
+
+	other := insert(t, store, newUser(2))
+	if other.ID == expected.ID {
+		t.Errorf("two users were assigned the same ID %d", other.ID)
+	}
+}
+
+func testUniqueness(t *testing.T, store users.Store) {
+	insert(t, store, newUser(1))
+	cases := []struct {
+		name        string
+		user        *users.User
+		expectError error
+	}{
+		{
+			"Email Taken",
+			&users.User{Email: "user1@test.com", PassHash: []byte("passhash123"), UserName: "TheBlindBandit"},
+			users.ErrEmailTaken,
+		},
+		{
+			"UserName Taken",
+			&users.User{Email: "toph@test.com", PassHash: []byte("passhash123"), UserName: "user1"},
+			users.ErrUserNameTaken,
+		},
+	}
+	for _, c := range cases {
+		if _, err := store.Insert(context.Background(), c.user); err != c.expectError {
+			t.Errorf("case %s: incorrect error: expected %v but got %v", c.name, c.expectError, err)
+		}
+	}
+}
+
+func testUpdate(t *testing.T, store users.Store) {
+	user := insert(t, store, newUser(1))
+	updated, err := store.Update(context.Background(), user.ID, &users.Updates{FirstName: stringPtr("Melty"), LastName: stringPtr("Blob")})
+	if err != nil {
+		t.Fatalf("error updating user: %v", err)
+	}
+	if updated.FirstName != "Melty" || updated.LastName != "Blob" {
+		t.Errorf("incorrect names after update: expected Melty Blob but got %s %s", updated.FirstName, updated.LastName)
+	}
+	stored, err := store.GetByID(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("error getting updated user: %v", err)
+	}
+	if !reflect.DeepEqual(stored, updated) {
+		t.Errorf("update was not saved: expected %+v but got %+v", updated, stored)
+	}
+}
+
+func testPartialUpdate(t *testing.T, store users.Store) {
+	user := insert(t, store, newUser(1))
+	updates := &users.Updates{
+		UserName:    stringPtr("MeltyBlob"),
+		Email:       stringPtr("melty@test.com"),
+		Bio:         stringPtr("Greatest earthbender in the world"),
+		DisplayName: stringPtr("Toph"),
+		Locale:      stringPtr("en-US"),
+	}
+	updated, err := store.Update(context.Background(), user.ID, updates)
+	if err != nil {
+		t.Fatalf("error updating user: %v", err)
+	}
+	expected := *user
+	expected.UserName = "MeltyBlob"
+	expected.Email = "melty@test.com"
+	expected.Bio = "Greatest earthbender in the world"
+	expected.DisplayName = "Toph"
+	expected.Locale = "en-US"
+	if !reflect.DeepEqual(updated, &expected) {
+		t.Errorf("incorrect user after update: expected %+v but got %+v", &expected, updated)
+	}
+	//fields that weren't sent must be left alone
+	updated, err = store.Update(context.Background(), user.ID, &users.Updates{LastName: stringPtr("")})
+	if err != nil {
+		t.Fatalf("error updating user: %v", err)
+	}
+	expected.LastName = ""
+	if !reflect.DeepEqual(updated, &expected) {
+		t.Errorf("incorrect user after partial update: expected %+v but got %+v", &expected, updated)
+	}
+	if updated, err = store.Update(context.Background(), user.ID, &users.Updates{}); err != nil || !reflect.DeepEqual(updated, &expected) {
+		t.Errorf("empty update changed the user: expected %+v but got %+v (error %v)", &expected, updated, err)
+	}
+}
+
+func testUpdateUniqueness(t *testing.T, store users.Store) {
+	user := insert(t, store, newUser(1))
+	other := insert(t, store, newUser(2))
+	cases := []struct {
+		name        string
+		updates     *users.Updates
+		expectError error
+	}{
+		{
+			"Email Taken",
+			&users.Updates{Email: stringPtr(other.Email)},
+			users.ErrEmailTaken,
+		},
+		{
+			"UserName Taken",
+			&users.Updates{UserName: stringPtr(other.UserName)},
+			users.ErrUserNameTaken,
+		},
+		{
+			"Unchanged",
+			&users.Updates{Email: stringPtr(user.Email), UserName: stringPtr(user.UserName)},
+			nil,
+		},
+	}
+	for _, c := range cases {
+		if _, err := store.Update(context.Background(), user.ID, c.updates); err != c.expectError {
+			t.Errorf("case %s: incorrect error: expected %v but got %v", c.name, c.expectError, err)
+		}
+	}
+}
+
+func testSetPassHash(t *testing.T, store users.Store) {
+	user := insert(t, store, newUser(1))
+	if err := store.SetPassHash(context.Background(), user.ID, []byte("newhash")); err != nil {
+		t.Fatalf("error setting password hash: %v", err)
+	}
+	stored, err := store.GetByID(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("error getting user: %v", err)
+	}
+	if string(stored.PassHash) != "newhash" {
+		t.Errorf("password hash was not replaced: got %q", stored.PassHash)
+	}
+}
+
+func testConfirmEmail(t *testing.T, plain users.Store) {
+	store, ok := plain.(users.EmailChangeStore)
+	if !ok {
+		t.Skip("store can't change email addresses")
+	}
+	user := newUser(1)
+	user.PhotoURL = "https://www.gravatar.com/avatar/old"
+	user = insert(t, store, user)
+	custom := newUser(2)
+	custom.PhotoURL = "https://example.com/toph.png"
+	custom = insert(t, store, custom)
+	taken := insert(t, store, newUser(3))
+
+	if _, err := store.ConfirmEmail(context.Background(), user.ID, "toph@test.com"); err != users.ErrNoPendingEmail {
+		t.Errorf("incorrect error confirming an email that was never asked for: expected %v but got %v", users.ErrNoPendingEmail, err)
+	}
+	if err := store.SetPendingEmail(context.Background(), user.ID, "toph@test.com"); err != nil {
+		t.Fatalf("error setting pending email: %v", err)
+	}
+	if _, err := store.ConfirmEmail(context.Background(), user.ID, "aang@test.com"); err != users.ErrNoPendingEmail {
+		t.Errorf("incorrect error confirming a different email: expected %v but got %v", users.ErrNoPendingEmail, err)
+	}
+	confirmed, err := store.ConfirmEmail(context.Background(), user.ID, "toph@test.com")
+	if err != nil {
+		t.Fatalf("error confirming email: %v", err)
+	}
+	photoHash := md5.Sum([]byte("toph@test.com"))
+	expectedPhoto := "https://www.gravatar.com/avatar/" + hex.EncodeToString(photoHash[:])
+	if confirmed.Email != "toph@test.com" || !confirmed.EmailVerified || len(confirmed.PendingEmail) > 0 || confirmed.PhotoURL != expectedPhoto {
+		t.Errorf("incorrect user after confirming email: %+v", confirmed)
+	}
+	if stored, _ := store.GetByEmail(context.Background(), "toph@test.com"); stored == nil || !reflect.DeepEqual(stored, confirmed) {
+		t.Errorf("confirmed email was not saved: expected %+v but got %+v", confirmed, stored)
+	}
+	if _, err := store.ConfirmEmail(context.Background(), user.ID, "toph@test.com"); err != users.ErrNoPendingEmail {
+		t.Errorf("incorrect error confirming an email twice: expected %v but got %v", users.ErrNoPendingEmail, err)
+	}
+
+	//custom photos are kept
+	store.SetPendingEmail(context.Background(), custom.ID, "melty@test.com")
+	confirmed, err = store.ConfirmEmail(context.Background(), custom.ID, "melty@test.com")
+	if err != nil {
+		t.Fatalf("error confirming email: %v", err)
+	}
+	if confirmed.PhotoURL != custom.PhotoURL {
+		t.Errorf("custom photo was replaced: expected %s but got %s", custom.PhotoURL, confirmed.PhotoURL)
+	}
+
+	//the address may have been taken since the change was asked for
+	store.SetPendingEmail(context.Background(), custom.ID, taken.Email)
+	if _, err := store.ConfirmEmail(context.Background(), custom.ID, taken.Email); err != users.ErrEmailTaken {
+		t.Errorf("incorrect error confirming a taken email: expected %v but got %v", users.ErrEmailTaken, err)
+	}
+	if err := store.SetPendingEmail(context.Background(), taken.ID+1, "aang@test.com"); err != users.ErrUserNotFound {
+		t.Errorf("incorrect error setting pending email of missing user: expected %v but got %v", users.ErrUserNotFound, err)
+	}
+}
+
+func testVerifyEmail(t *testing.T, plain users.Store) {
+	store, ok := plain.(users.VerificationStore)
+	if !ok {
+		t.Skip("store can't verify email addresses")
+	}
+	user := insert(t, store, newUser(1))
+	if user.EmailVerified {
+		t.Fatalf("new users should not be verified")
+	}
+	if _, err := store.VerifyEmail(context.Background(), user.ID, "old@test.com"); err != users.ErrInvalidToken {
+		t.Errorf("incorrect error verifying another address: expected %v but got %v", users.ErrInvalidToken, err)
+	}
+	for i := 0; i < 2; i++ {
+		//verifying again is harmless
+		verified, err := store.VerifyEmail(context.Background(), user.ID, user.Email)
+		if err != nil {
+			t.Fatalf("error verifying email: %v", err)
+		}
+		if !verified.EmailVerified {
+			t.Errorf("email was not marked verified")
+		}
+	}
+	if stored, _ := store.GetByID(context.Background(), user.ID); !stored.EmailVerified {
+		t.Errorf("verification was not saved")
+	}
+	if _, err := store.VerifyEmail(context.Background(), user.ID+1, user.Email); err != users.ErrUserNotFound {
+		t.Errorf("incorrect error verifying missing user: expected %v but got %v", users.ErrUserNotFound, err)
+	}
+}
+
+func testDelete(t *testing.T, store users.Store) {
+	user := insert(t, store, newUser(1))
+	if err := store.Delete(context.Background(), user.ID); err != nil {
+		t.Fatalf("error deleting user: %v", err)
+	}
+	if _, err := store.GetByID(context.Background(), user.ID); err != users.ErrUserNotFound {
+		t.Errorf("incorrect error getting deleted user: expected %v but got %v", users.ErrUserNotFound, err)
+	}
+	//the email and username are free to use again
+	insert(t, store, newUser(1))
+}
+
+func testSoftDelete(t *testing.T, plain users.Store) {
+	store, ok := plain.(users.SoftDeleteStore)
+	if !ok {
+		t.Skip("store can't soft-delete users")
+	}
+	user := insert(t, store, newUser(1))
+	other := insert(t, store, newUser(2))
+	//whole seconds, since MySQL datetimes drop fractions
+	deletedAt := time.Date(2020, time.March, 1, 12, 0, 0, 0, time.UTC)
+	if err := store.SetDeletedAt(context.Background(), user.ID, &deletedAt); err != nil {
+		t.Fatalf("error soft-deleting user: %v", err)
+	}
+	stored, err := store.GetByEmail(context.Background(), user.Email)
+	if err != nil {
+		t.Fatalf("error getting soft-deleted user: %v", err)
+	}
+	if stored.DeletedAt == nil || !stored.DeletedAt.Equal(deletedAt) {
+		t.Errorf("incorrect DeletedAt: expected %v but got %v", deletedAt, stored.DeletedAt)
+	}
+	if err := store.SetDeletedAt(context.Background(), other.ID+1, &deletedAt); err != users.ErrUserNotFound {
+		t.Errorf("incorrect error soft-deleting missing user: expected %v but got %v", users.ErrUserNotFound, err)
+	}
+
+	//users still in their grace period are not purged
+	purged, err := store.PurgeDeleted(context.Background(), deletedAt.Add(-time.Hour))
+	if err != nil || purged != 0 {
+		t.Errorf("incorrect result purging before deletion: expected 0 users but got %d, %v", purged, err)
+	}
+
+	//restored users are not purged either
+	if err := store.SetDeletedAt(context.Background(), other.ID, &deletedAt); err != nil {
+		t.Fatalf("error soft-deleting user: %v", err)
+	}
+	if err := store.SetDeletedAt(context.Background(), other.ID, nil); err != nil {
+		t.Fatalf("error restoring user: %v", err)
+	}
+	if stored, _ := store.GetByID(context.Background(), other.ID); stored.DeletedAt != nil {
+		t.Errorf("restored user still has DeletedAt %v", stored.DeletedAt)
+	}
+
+	purged, err = store.PurgeDeleted(context.Background(), deletedAt.Add(time.Hour))
+	if err != nil || purged != 1 {
+		t.Errorf("incorrect result purging after deletion: expected 1 user but got %d, %v", purged, err)
+	}
+	if _, err := store.GetByID(context.Background(), user.ID); err != users.ErrUserNotFound {
+		t.Errorf("incorrect error getting purged user: expected %v but got %v", users.ErrUserNotFound, err)
+	}
+	if _, err := store.GetByID(context.Background(), other.ID); err != nil {
+		t.Errorf("restored user was purged: %v", err)
+	}
+}
+
+func testConcurrentInserts(t *testing.T, store users.Store) {
+	const numUsers = 20
+	var wg sync.WaitGroup
+	for i := 0; i < numUsers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			store.Insert(context.Background(), newUser(i))
+			//every goroutine also tries to claim the same username
+			dup := newUser(i)
+			dup.Email = fmt.Sprintf("dup%d@test.com", i)
+			dup.UserName = "taken"
+			store.Insert(context.Background(), dup)
+		}(i)
+	}
+	wg.Wait()
+
+	ids := map[int64]bool{}
+	for i := 0; i < numUsers; i++ {
+		user, err := store.GetByUserName(context.Background(), newUser(i).UserName)
+		if err != nil {
+			t.Fatalf("error getting user %d: %v", i, err)
+		}
+		if ids[user.ID] {
+			t.Errorf("ID %d was assigned more than once", user.ID)
+		}
+		ids[user.ID] = true
+	}
+	taken := 0
+	for i := 0; i < numUsers; i++ {
+		if _, err := store.GetByEmail(context.Background(), fmt.Sprintf("dup%d@test.com", i)); err == nil {
+			taken++
+		}
+	}
+	if taken != 1 {
+		t.Errorf("incorrect number of users with a duplicate username: expected 1 but got %d", taken)
+	}
+}
+
+//stringPtr returns a pointer to `s`, for building Updates
+func stringPtr(s string) *string {
+	return &s
+}
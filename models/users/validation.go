@@ -0,0 +1,52 @@
+package users
+
+import (
+	"strings"
+)
+
+//Rule codes reported in ValidationError.Rule
+const (
+	RuleEmailFormat       = "email_format"
+	RulePasswordLength    = "password_length"
+	RulePasswordMismatch  = "password_mismatch"
+	RuleUserNameRequired  = "username_required"
+	RuleUserNameSpaces    = "username_spaces"
+	RuleBioLength         = "bio_length"
+	RuleDisplayNameLength = "display_name_length"
+	RuleLocaleFormat      = "locale_format"
+)
+
+//ValidationError describes a single validation rule
+//that a field failed
+type ValidationError struct {
+	//Field is the JSON name of the field that failed validation
+	Field string
+	//Rule is the code of the rule that failed, one of the Rule constants
+	Rule string
+	//Message is a human-readable description of the failure
+	Message string
+}
+
+//Error returns the message of the ValidationError
+func (ve *ValidationError) Error() string {
+	return ve.Message
+}
+
+//ValidationErrors collects every ValidationError found while
+//validating a value. Use errors.As to get at the individual
+//failures from an error returned by Validate.
+type ValidationErrors []*ValidationError
+
+//Error returns the messages of all the validation errors
+func (ves ValidationErrors) Error() string {
+	msgs := make([]string, len(ves))
+	for i, ve := range ves {
+		msgs[i] = ve.Message
+	}
+	return strings.Join(msgs, " ")
+}
+
+//add appends a new ValidationError to the collection
+func (ves *ValidationErrors) add(field string, rule string, message string) {
+	*ves = append(*ves, &ValidationError{field, rule, message})
+}
@@ -1,6 +1,6 @@
-module github.com/my/repo
+module assignments-jelauria/servers/gateway/sessions
 
-go 1.14
+go 1.18
 
 require (
 	github.com/go-redis/redis v6.15.7+incompatible
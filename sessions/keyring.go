@@ -0,0 +1,124 @@
+package sessions
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//keyringSignedLength is the full length of a SessionID signed by a
+//Keyring: the ID of the signing key, the ID portion and the signature
+const keyringSignedLength = 1 + idLength + sha256.Size
+
+//Keyring is a Signer that holds several HMAC keys, each named by a
+//one-byte key ID. New SessionIDs are signed with the active key, and
+//begin with its key ID, so that they can be validated against any of
+//the keys in the keyring. The byte slice layout is like so:
+//+--------------------------------------------------------------+
+//|key ID|...32 crypto random bytes...|HMAC hash of all prior bytes|
+//+--------------------------------------------------------------+
+//To rotate keys, add a new key and make it active, then remove the
+//old key once the sessions signed with it have expired. Removing a
+//key right away ends every session signed with it, for example after
+//the key is leaked. A Keyring can't be changed once constructed, so
+//it is safe for concurrent use.
+type Keyring struct {
+	activeID byte
+	keys     map[byte][]byte
+}
+
+//NewKeyring constructs a Keyring holding `keys`, which maps key IDs
+//to keys, that signs new SessionIDs with the key `activeID`
+func NewKeyring(activeID byte, keys map[byte]string) (*Keyring, error) {
+	if _, found := keys[activeID]; !found {
+		return nil, fmt.Errorf("active key %d is not in the keyring", activeID)
+	}
+	kr := &Keyring{activeID, map[byte][]byte{}}
+	for id, key := range keys {
+		if len(key) == 0 {
+			return nil, fmt.Errorf("key %d may not be empty", id)
+		}
+		kr.keys[id] = []byte(key)
+	}
+	return kr, nil
+}
+
+//ParseKeyring constructs a Keyring from a comma-separated list of
+//keys, each in the form `<key ID>:<key>`, such as "2:newkey,1:oldkey".
+//Key IDs are from 0 to 255, and the first key listed is active.
+func ParseKeyring(config string) (*Keyring, error) {
+	keys := map[byte]string{}
+	var activeID byte
+	for i, entry := range strings.Split(config, ",") {
+		parts := strings.SplitN(strings.TrimSpace(entry), ":", 2)
+		if len(parts) != 2 {
+			return nil, errors.New("keys must be in the form <key ID>:<key>")
+		}
+		id, err := strconv.ParseUint(parts[0], 10, 8)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key ID %q: must be from 0 to 255", parts[0])
+		}
+		if _, found := keys[byte(id)]; found {
+			return nil, fmt.Errorf("key ID %d is used more than once", id)
+		}
+		if i == 0 {
+			activeID = byte(id)
+		}
+		keys[byte(id)] = parts[1]
+	}
+	return NewKeyring(activeID, keys)
+}
+
+//NewSessionID creates and returns a new SessionID signed with
+//the active key. An error is returned only if there was an
+//error generating random bytes for the SessionID.
+func (kr *Keyring) NewSessionID() (SessionID, error) {
+	buf := make([]byte, 1+idLength, keyringSignedLength)
+	buf[0] = kr.activeID
+	if _, err := rand.Read(buf[1:]); err != nil {
+		return InvalidSessionID, err
+	}
+	buf = append(buf, kr.sign(kr.keys[kr.activeID], buf)...)
+	return SessionID(base64.URLEncoding.EncodeToString(buf)), nil
+}
+
+//ValidateID validates the string in the `id` parameter using the key
+//named by its key ID, and returns an error if invalid, or a SessionID
+//if valid. SessionIDs made by a SigningKey are validated against each
+//key in the keyring, so that switching from a SigningKey to a Keyring
+//holding the same key doesn't end every session.
+func (kr *Keyring) ValidateID(id string) (SessionID, error) {
+	if _, err := decodeID(id, signedLength); err == nil {
+		for _, key := range kr.keys {
+			if sid, err := ValidateID(id, string(key)); err == nil {
+				return sid, nil
+			}
+		}
+		return InvalidSessionID, ErrInvalidID
+	}
+	decodedID, err := decodeID(id, keyringSignedLength)
+	if err != nil {
+		return InvalidSessionID, err
+	}
+	key, found := kr.keys[decodedID[0]]
+	if !found {
+		return InvalidSessionID, ErrInvalidID
+	}
+	signed := decodedID[:1+idLength]
+	if !hmac.Equal(decodedID[1+idLength:], kr.sign(key, signed)) {
+		return InvalidSessionID, ErrInvalidID
+	}
+	return SessionID(id), nil
+}
+
+//sign returns the HMAC hash of `data` made with `key`
+func (kr *Keyring) sign(key []byte, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
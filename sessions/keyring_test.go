@@ -0,0 +1,215 @@
+package sessions
+
+import (
+	"encoding/base64"
+	"net/http"
+	"testing"
+)
+
+func TestKeyring(t *testing.T) {
+	oldRing, err := NewKeyring(1, map[byte]string{1: "old key"})
+	if err != nil {
+		t.Fatalf("unexpected error constructing keyring: %v", err)
+	}
+	rotated, err := NewKeyring(2, map[byte]string{1: "old key", 2: "new key"})
+	if err != nil {
+		t.Fatalf("unexpected error constructing keyring: %v", err)
+	}
+	revoked, err := NewKeyring(2, map[byte]string{2: "new key"})
+	if err != nil {
+		t.Fatalf("unexpected error constructing keyring: %v", err)
+	}
+	//a different key that reuses the old key's ID
+	reused, err := NewKeyring(1, map[byte]string{1: "other key"})
+	if err != nil {
+		t.Fatalf("unexpected error constructing keyring: %v", err)
+	}
+
+	cases := []struct {
+		name        string
+		signer      Signer
+		validator   Signer
+		sidMutator  func(SessionID) SessionID
+		expectError bool
+	}{
+		{
+			"Same Keyring",
+			oldRing,
+			oldRing,
+			nil,
+			false,
+		},
+		{
+			"Signed Before Rotation",
+			oldRing,
+			rotated,
+			nil,
+			false,
+		},
+		{
+			"Signed After Rotation",
+			rotated,
+			revoked,
+			nil,
+			false,
+		},
+		{
+			"Key Removed",
+			oldRing,
+			revoked,
+			nil,
+			true,
+		},
+		{
+			"Key ID Reused",
+			oldRing,
+			reused,
+			nil,
+			true,
+		},
+		{
+			"Signed By SigningKey",
+			SigningKey("old key"),
+			rotated,
+			nil,
+			false,
+		},
+		{
+			"Signed By Other SigningKey",
+			SigningKey("other key"),
+			rotated,
+			nil,
+			true,
+		},
+		{
+			"Mutated Key ID",
+			rotated,
+			rotated,
+			func(sid SessionID) SessionID {
+				buf, _ := base64.URLEncoding.DecodeString(string(sid))
+				buf[0] = 1
+				return SessionID(base64.URLEncoding.EncodeToString(buf))
+			},
+			true,
+		},
+		{
+			"Mutated ID Portion",
+			rotated,
+			rotated,
+			func(sid SessionID) SessionID {
+				buf, _ := base64.URLEncoding.DecodeString(string(sid))
+				buf[1] = buf[1] + 1
+				return SessionID(base64.URLEncoding.EncodeToString(buf))
+			},
+			true,
+		},
+		{
+			"Incorrect Length",
+			rotated,
+			rotated,
+			func(sid SessionID) SessionID {
+				buf, _ := base64.URLEncoding.DecodeString(string(sid))
+				return SessionID(base64.URLEncoding.EncodeToString(buf[:len(buf)-2]))
+			},
+			true,
+		},
+		{
+			"Invalid Base64 Encoding",
+			rotated,
+			rotated,
+			func(sid SessionID) SessionID { return "+" + sid[1:] },
+			true,
+		},
+	}
+
+	for _, c := range cases {
+		sid, err := c.signer.NewSessionID()
+		if err != nil {
+			t.Errorf("case %s: unexpected error generating new SessionID: %v", c.name, err)
+			continue
+		}
+		if c.sidMutator != nil {
+			sid = c.sidMutator(sid)
+		}
+		sid2, err := c.validator.ValidateID(string(sid))
+		if err != nil && !c.expectError {
+			t.Errorf("case %s: unexpected error validating SessionID: %v", c.name, err)
+		}
+		if c.expectError && err != ErrInvalidID {
+			t.Errorf("case %s: incorrect error: expected %v but got %v", c.name, ErrInvalidID, err)
+		}
+		if err == nil && sid2 != sid {
+			t.Errorf("case %s: validated SessionID does not equal original SessionID", c.name)
+		}
+	}
+}
+
+func TestParseKeyring(t *testing.T) {
+	cases := []struct {
+		name        string
+		config      string
+		expectError bool
+	}{
+		{
+			"Single Key",
+			"1:secret",
+			false,
+		},
+		{
+			"Several Keys",
+			"2:new secret, 1:old:secret",
+			false,
+		},
+		{
+			"Missing Key ID",
+			"secret",
+			true,
+		},
+		{
+			"Key ID Out Of Range",
+			"256:secret",
+			true,
+		},
+		{
+			"Empty Key",
+			"1:",
+			true,
+		},
+		{
+			"Duplicate Key ID",
+			"1:secret,1:other",
+			true,
+		},
+	}
+
+	for _, c := range cases {
+		_, err := ParseKeyring(c.config)
+		if err != nil && !c.expectError {
+			t.Errorf("case %s: unexpected error parsing keyring: %v", c.name, err)
+		}
+		if c.expectError && err == nil {
+			t.Errorf("case %s: expected error but didn't get one", c.name)
+		}
+	}
+
+	//the first key listed signs new SessionIDs
+	kr, _ := ParseKeyring("2:new secret,1:old secret")
+	sid, _ := kr.NewSessionID()
+	buf, _ := base64.URLEncoding.DecodeString(string(sid))
+	if len(buf) != keyringSignedLength || buf[0] != 2 {
+		t.Errorf("SessionID was not signed with the first key: key ID %d, length %d", buf[0], len(buf))
+	}
+}
+
+func TestKeyringGetSessionID(t *testing.T) {
+	kr, _ := ParseKeyring("2:new secret,1:old secret")
+	sid, err := kr.NewSessionID()
+	if err != nil {
+		t.Fatalf("error generating SessionID: %v", err)
+	}
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.Header.Add(headerAuthorization, schemeBearer+string(sid))
+	if sidRet, err := GetSessionID(req, kr, DefaultTransport); err != nil || sidRet != sid {
+		t.Errorf("incorrect result getting SessionID signed by keyring: %s, %v", sidRet, err)
+	}
+}
@@ -0,0 +1,43 @@
+package sessions
+
+import "time"
+
+//Aged is implemented by session states that record when their
+//session began. Stores with a MaxAge end such sessions once they are
+//older than the MaxAge, however recently they were used, while the
+//idle timeout still ends sessions that sit unused for too long.
+type Aged interface {
+	//SessionStart returns the time the session began
+	SessionStart() time.Time
+}
+
+//sessionTTL returns how long the session with `state` may live from
+//`now`: the `idleTimeout`, or less if the session reaches `maxAge`
+//before then. It returns false if the session has already passed
+//`maxAge`. Sessions whose state isn't Aged, and any session if
+//`maxAge` is zero, only have the idle timeout.
+func sessionTTL(state interface{}, idleTimeout time.Duration, maxAge time.Duration, now time.Time) (time.Duration, bool) {
+	deadline, ok := sessionDeadline(state, maxAge)
+	if !ok {
+		return idleTimeout, true
+	}
+	remaining := deadline.Sub(now)
+	if remaining <= 0 {
+		return 0, false
+	}
+	if remaining < idleTimeout {
+		return remaining, true
+	}
+	return idleTimeout, true
+}
+
+//sessionDeadline returns the time the session with `state` reaches
+//`maxAge`. It returns false if the session has no such deadline,
+//because its state isn't Aged or `maxAge` is zero.
+func sessionDeadline(state interface{}, maxAge time.Duration) (time.Time, bool) {
+	aged, ok := state.(Aged)
+	if maxAge <= 0 || !ok {
+		return time.Time{}, false
+	}
+	return aged.SessionStart().Add(maxAge), true
+}
@@ -0,0 +1,113 @@
+package sessions
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+//agedState is a session state that records when its session began
+type agedState struct {
+	Start time.Time
+}
+
+func (s *agedState) SessionStart() time.Time {
+	return s.Start
+}
+
+func TestSessionTTL(t *testing.T) {
+	now := time.Now()
+	cases := []struct {
+		name        string
+		state       interface{}
+		maxAge      time.Duration
+		expectedTTL time.Duration
+		expectOK    bool
+	}{
+		{
+			"No Max Age",
+			&agedState{now.Add(-48 * time.Hour)},
+			0,
+			time.Hour,
+			true,
+		},
+		{
+			"Not Aged",
+			&struct{ Sval string }{"testing"},
+			24 * time.Hour,
+			time.Hour,
+			true,
+		},
+		{
+			"New Session",
+			&agedState{now},
+			24 * time.Hour,
+			time.Hour,
+			true,
+		},
+		{
+			"Near Max Age",
+			&agedState{now.Add(-23*time.Hour - 30*time.Minute)},
+			24 * time.Hour,
+			30 * time.Minute,
+			true,
+		},
+		{
+			"Past Max Age",
+			&agedState{now.Add(-25 * time.Hour)},
+			24 * time.Hour,
+			0,
+			false,
+		},
+	}
+
+	for _, c := range cases {
+		ttl, ok := sessionTTL(c.state, time.Hour, c.maxAge, now)
+		if ok != c.expectOK {
+			t.Errorf("case %s: incorrect result: expected %t but got %t", c.name, c.expectOK, ok)
+		}
+		if ttl != c.expectedTTL {
+			t.Errorf("case %s: incorrect TTL: expected %v but got %v", c.name, c.expectedTTL, ttl)
+		}
+	}
+}
+
+func TestMemStoreMaxAge(t *testing.T) {
+	store := NewMemStore(time.Hour, time.Minute)
+	store.MaxAge = 500 * time.Millisecond
+	sid, err := NewSessionID("test key")
+	if err != nil {
+		t.Fatalf("error generating new SessionID: %v", err)
+	}
+	if err := store.Save(context.Background(), sid, &agedState{time.Now()}); err != nil {
+		t.Fatalf("error saving state: %v", err)
+	}
+	//using the session keeps it from sitting idle,
+	//but not from passing the max age
+	if err := store.Get(context.Background(), sid, &agedState{}); err != nil {
+		t.Fatalf("error getting state of a new session: %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if err := store.Peek(context.Background(), sid, &agedState{}); err != nil {
+		t.Fatalf("error peeking at state of a new session: %v", err)
+	}
+	if err := store.Get(context.Background(), sid, &agedState{}); err != nil {
+		t.Fatalf("error getting state of a new session: %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	if err := store.Peek(context.Background(), sid, &agedState{}); err != ErrStateNotFound {
+		t.Errorf("incorrect error when peeking at state past its max age: expected %v but got %v", ErrStateNotFound, err)
+	}
+	if err := store.Get(context.Background(), sid, &agedState{}); err != ErrStateNotFound {
+		t.Errorf("incorrect error when getting state past its max age: expected %v but got %v", ErrStateNotFound, err)
+	}
+
+	//saving a session past its max age ends it
+	old := &agedState{time.Now().Add(-time.Second)}
+	if err := store.Save(context.Background(), sid, old); err != nil {
+		t.Fatalf("error saving state: %v", err)
+	}
+	if _, found := store.entries.Get(sid.String()); found {
+		t.Errorf("state past its max age was saved")
+	}
+}
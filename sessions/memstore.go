@@ -1,7 +1,9 @@
 package sessions
 
 import (
+	"context"
 	"encoding/json"
+	"sync"
 	"time"
 
 	"github.com/patrickmn/go-cache"
@@ -9,44 +11,176 @@ import (
 
 //MemStore represents an in-process memory session store.
 //This should be used only for testing and prototyping.
-//Production systems should use a shared server store like redis
+//Production systems should use a shared server store like redis.
+//It never blocks on I/O, so it ignores the contexts passed to its methods.
 type MemStore struct {
+	//MaxAge is how long a session whose state is Aged may last,
+	//however recently it was used. Zero means no limit.
+	MaxAge time.Duration
+
 	entries *cache.Cache
+	//sessionDuration is how long a session may sit idle
+	sessionDuration time.Duration
+	//mx protects userIndex, and is held while Get and Update read
+	//an entry and set it again, and while entries are deleted, so that
+	//a deleted entry can't be set again by a Get or Update that read it
+	mx sync.Mutex
+	//userIndex maps a user ID to the set of that user's SessionIDs
+	userIndex map[int64]map[SessionID]struct{}
 }
 
 //NewMemStore constructs and returns a new MemStore
 func NewMemStore(sessionDuration time.Duration, purgeInterval time.Duration) *MemStore {
 	return &MemStore{
-		entries: cache.New(sessionDuration, purgeInterval),
+		entries:         cache.New(sessionDuration, purgeInterval),
+		sessionDuration: sessionDuration,
+		userIndex:       map[int64]map[SessionID]struct{}{},
 	}
 }
 
 //Save saves the provided `sessionState` and associated SessionID to the store.
 //The `sessionState` parameter is typically a pointer to a struct containing
 //all the data you want to associated with the given SessionID.
-func (ms *MemStore) Save(sid SessionID, state interface{}) error {
+//A session that has passed the MaxAge is deleted instead.
+func (ms *MemStore) Save(ctx context.Context, sid SessionID, state interface{}) error {
 	j, err := json.Marshal(state)
 	if nil != err {
 		return err
 	}
-	ms.entries.Set(sid.String(), j, cache.DefaultExpiration)
+	ttl, ok := sessionTTL(state, ms.sessionDuration, ms.MaxAge, time.Now())
+	if !ok {
+		ms.entries.Delete(sid.String())
+		return nil
+	}
+	ms.entries.Set(sid.String(), j, ttl)
 	return nil
 }
 
 //Get populates `sessionState` with the data previously saved
-//for the given SessionID
-func (ms *MemStore) Get(sid SessionID, state interface{}) error {
+//for the given SessionID, and resets its expiry time. A session
+//that has passed the MaxAge is deleted and not found.
+func (ms *MemStore) Get(ctx context.Context, sid SessionID, state interface{}) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
 	j, found := ms.entries.Get(sid.String())
 	if !found {
 		return ErrStateNotFound
 	}
+	if err := json.Unmarshal(j.([]byte), state); err != nil {
+		return err
+	}
+	ttl, ok := sessionTTL(state, ms.sessionDuration, ms.MaxAge, time.Now())
+	if !ok {
+		ms.entries.Delete(sid.String())
+		return ErrStateNotFound
+	}
 	//reset TTL
-	ms.entries.Set(sid.String(), j, 0)
-	return json.Unmarshal(j.([]byte), state)
+	ms.entries.Set(sid.String(), j, ttl)
+	return nil
 }
 
 //Delete deletes all state data associated with the SessionID from the store.
-func (ms *MemStore) Delete(sid SessionID) error {
+func (ms *MemStore) Delete(ctx context.Context, sid SessionID) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
 	ms.entries.Delete(sid.String())
 	return nil
 }
+
+//Index records that the session `sid` belongs to the user with
+//the given `userID`.
+func (ms *MemStore) Index(ctx context.Context, userID int64, sid SessionID) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	sids, found := ms.userIndex[userID]
+	if !found {
+		sids = map[SessionID]struct{}{}
+		ms.userIndex[userID] = sids
+	}
+	sids[sid] = struct{}{}
+	return nil
+}
+
+//Update saves `sessionState` like Save, but only if the session
+//`sid` still exists and is indexed under `userID`. Otherwise it
+//returns ErrStateNotFound.
+func (ms *MemStore) Update(ctx context.Context, userID int64, sid SessionID, state interface{}) error {
+	j, err := json.Marshal(state)
+	if nil != err {
+		return err
+	}
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	if _, found := ms.userIndex[userID][sid]; !found {
+		return ErrStateNotFound
+	}
+	if _, found := ms.entries.Get(sid.String()); !found {
+		return ErrStateNotFound
+	}
+	ttl, ok := sessionTTL(state, ms.sessionDuration, ms.MaxAge, time.Now())
+	if !ok {
+		ms.entries.Delete(sid.String())
+		return ErrStateNotFound
+	}
+	ms.entries.Set(sid.String(), j, ttl)
+	return nil
+}
+
+//DeleteAll deletes the state of every session indexed under
+//`userID`, except for the sessions listed in `keep`.
+func (ms *MemStore) DeleteAll(ctx context.Context, userID int64, keep ...SessionID) error {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	kept := map[SessionID]struct{}{}
+	for _, sid := range keep {
+		if _, found := ms.userIndex[userID][sid]; found {
+			kept[sid] = struct{}{}
+		}
+	}
+	for sid := range ms.userIndex[userID] {
+		if _, found := kept[sid]; !found {
+			ms.entries.Delete(sid.String())
+		}
+	}
+	if len(kept) > 0 {
+		ms.userIndex[userID] = kept
+	} else {
+		delete(ms.userIndex, userID)
+	}
+	return nil
+}
+
+//List returns the sessions indexed under `userID` that haven't
+//ended, pruning the ones that have from the index
+func (ms *MemStore) List(ctx context.Context, userID int64) ([]SessionID, error) {
+	ms.mx.Lock()
+	defer ms.mx.Unlock()
+	sids := []SessionID{}
+	for sid := range ms.userIndex[userID] {
+		if _, found := ms.entries.Get(sid.String()); found {
+			sids = append(sids, sid)
+		} else {
+			delete(ms.userIndex[userID], sid)
+		}
+	}
+	if len(sids) == 0 {
+		delete(ms.userIndex, userID)
+	}
+	return sids, nil
+}
+
+//Peek populates `sessionState` with the data previously saved
+//for the given SessionID, without resetting its expiry time
+func (ms *MemStore) Peek(ctx context.Context, sid SessionID, state interface{}) error {
+	j, found := ms.entries.Get(sid.String())
+	if !found {
+		return ErrStateNotFound
+	}
+	if err := json.Unmarshal(j.([]byte), state); err != nil {
+		return err
+	}
+	if _, ok := sessionTTL(state, ms.sessionDuration, ms.MaxAge, time.Now()); !ok {
+		return ErrStateNotFound
+	}
+	return nil
+}
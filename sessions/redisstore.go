@@ -1,24 +1,36 @@
 package sessions
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/go-redis/redis"
 )
 
 //RedisStore represents a session.Store backed by redis.
+//Redis failures are returned as errors of their own, so that
+//an outage isn't mistaken for every session having ended. Expiry
+//times are set in milliseconds, since EXPIRE would round durations
+//under a second down to zero and delete the keys. Get and Update run
+//scripts that build the key of the user's index themselves, so the store needs
+//a single redis server rather than a cluster.
 type RedisStore struct {
 	//Redis client used to talk to redis server.
 	Client *redis.Client
 	//Used for key expiry time on redis.
 	SessionDuration time.Duration
+	//MaxAge is how long a session whose state is Aged may last,
+	//however recently it was used. Zero means no limit.
+	MaxAge time.Duration
 }
 
 //NewRedisStore constructs a new RedisStore
 func NewRedisStore(client *redis.Client, sessionDuration time.Duration) *RedisStore {
 	//initialize and return a new RedisStore struct
-	return &RedisStore{client, sessionDuration}
+	return &RedisStore{Client: client, SessionDuration: sessionDuration}
 }
 
 //Store implementation
@@ -26,7 +38,10 @@ func NewRedisStore(client *redis.Client, sessionDuration time.Duration) *RedisSt
 //Save saves the provided `sessionState` and associated SessionID to the store.
 //The `sessionState` parameter is typically a pointer to a struct containing
 //all the data you want to associated with the given SessionID.
-func (rs *RedisStore) Save(sid SessionID, sessionState interface{}) error {
+//A session that has passed the MaxAge is deleted instead. Otherwise
+//the time it reaches the MaxAge is saved too, so that Get can stop
+//resetting its expiry beyond then.
+func (rs *RedisStore) Save(ctx context.Context, sid SessionID, sessionState interface{}) error {
 	//TODO: marshal the `sessionState` to JSON and save it in the redis database,
 	//using `sid.getRedisKey()` for the key.
 	//return any errors that occur along the way.
@@ -35,36 +50,265 @@ func (rs *RedisStore) Save(sid SessionID, sessionState interface{}) error {
 	if err != nil {
 		return err
 	}
-	rs.Client.Set(sid.getRedisKey(), seshState, rs.SessionDuration)
+	now := time.Now()
+	ttl, ok := sessionTTL(sessionState, rs.SessionDuration, rs.MaxAge, now)
+	if !ok {
+		return rs.Delete(ctx, sid)
+	}
+	deadline, hasDeadline := sessionDeadline(sessionState, rs.MaxAge)
+	_, err = rs.Client.WithContext(ctx).Pipelined(func(pipe redis.Pipeliner) error {
+		pipe.Set(sid.getRedisKey(), seshState, ttl)
+		if hasDeadline {
+			pipe.Set(sid.getDeadlineKey(), unixMillis(deadline), deadline.Sub(now))
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error saving session state to redis: %w", err)
+	}
+	return nil
+}
+
+//updateScript saves the state of a session only if it still exists
+//and is indexed, in one round trip. KEYS are the session's state, user
+//ID and deadline keys. ARGV are the user ID, the state, how long the
+//state may live and the SessionDuration, both in milliseconds, and
+//optionally the deadline in milliseconds since the Unix epoch and how
+//long it is until then. It returns 0 without saving anything if the
+//session's state is gone or the session is indexed under another user,
+//as it is once DeleteAll has ended the session.
+var updateScript = redis.NewScript(`
+if redis.call("GET", KEYS[2]) ~= ARGV[1] or redis.call("EXISTS", KEYS[1]) == 0 then
+	return 0
+end
+redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+redis.call("PEXPIRE", KEYS[2], ARGV[3])
+redis.call("PEXPIRE", "uid:" .. ARGV[1] .. ":sids", ARGV[4])
+if ARGV[5] then
+	redis.call("SET", KEYS[3], ARGV[5], "PX", ARGV[6])
+end
+return 1
+`)
+
+//Update saves `sessionState` like Save, but only if the session `sid`
+//still exists and is indexed under `userID`. Otherwise it returns
+//ErrStateNotFound. The check and the save are done together by
+//running updateScript, so that a concurrent DeleteAll can't come
+//between them.
+func (rs *RedisStore) Update(ctx context.Context, userID int64, sid SessionID, sessionState interface{}) error {
+	seshState, err := json.Marshal(sessionState)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	ttl, ok := sessionTTL(sessionState, rs.SessionDuration, rs.MaxAge, now)
+	if !ok {
+		if err := rs.Delete(ctx, sid); err != nil {
+			return err
+		}
+		return ErrStateNotFound
+	}
+	keys := []string{sid.getRedisKey(), sid.getUserIDKey(), sid.getDeadlineKey()}
+	args := []interface{}{userID, seshState, durationMillis(ttl), durationMillis(rs.SessionDuration)}
+	if deadline, hasDeadline := sessionDeadline(sessionState, rs.MaxAge); hasDeadline {
+		args = append(args, unixMillis(deadline), durationMillis(deadline.Sub(now)))
+	}
+	updated, err := updateScript.Run(rs.Client.WithContext(ctx), keys, args...).Int()
+	if err != nil {
+		return fmt.Errorf("error updating session state in redis: %w", err)
+	}
+	if updated == 0 {
+		return ErrStateNotFound
+	}
 	return nil
 }
 
+//getScript gets the state of a session and resets its expiry, in
+//one round trip. KEYS are the session's state, user ID and deadline
+//keys, and ARGV the SessionDuration and the current time, both in
+//milliseconds. The state and user ID expire after the SessionDuration,
+//or at the deadline if that is sooner, and the state is deleted and
+//not returned once the deadline has passed. If the session is indexed,
+//the expiry of its user's index is reset to the full SessionDuration,
+//so that the index lasts as long as the sessions in it.
+var getScript = redis.NewScript(`
+local state = redis.call("GET", KEYS[1])
+if not state then
+	return false
+end
+local ttl = tonumber(ARGV[1])
+local deadline = redis.call("GET", KEYS[3])
+if deadline then
+	ttl = math.min(ttl, tonumber(deadline) - tonumber(ARGV[2]))
+end
+if ttl <= 0 then
+	redis.call("DEL", KEYS[1], KEYS[2], KEYS[3])
+	return false
+end
+redis.call("PEXPIRE", KEYS[1], ttl)
+local uid = redis.call("GET", KEYS[2])
+if uid then
+	redis.call("PEXPIRE", KEYS[2], ttl)
+	redis.call("PEXPIRE", "uid:" .. uid .. ":sids", ARGV[1])
+end
+return state
+`)
+
 //Get populates `sessionState` with the data previously saved
-//for the given SessionID
-func (rs *RedisStore) Get(sid SessionID, sessionState interface{}) error {
-	//TODO: get the previously-saved session state data from redis,
-	//unmarshal it back into the `sessionState` parameter
-	//and reset the expiry time, so that it doesn't get deleted until
-	//the SessionDuration has elapsed.
-
-	//for extra-credit using the Pipeline feature of the redis
-	//package to do both the get and the reset of the expiry time
-	//in just one network round trip!
-
-	jzon, err := rs.Client.Get(sid.getRedisKey()).Result()
+//for the given SessionID, and resets its expiry time. The state is
+//read and the expiry reset in one round trip by running getScript,
+//which also resets the expiry of the user's index if the session
+//is indexed. A session that has passed the MaxAge is deleted and not
+//found, and one that reaches it sooner than it would sit idle
+//expires when it does.
+func (rs *RedisStore) Get(ctx context.Context, sid SessionID, sessionState interface{}) error {
+	keys := []string{sid.getRedisKey(), sid.getUserIDKey(), sid.getDeadlineKey()}
+	state, err := getScript.Run(rs.Client.WithContext(ctx), keys, rs.SessionDuration.Milliseconds(), unixMillis(time.Now())).String()
+	if err == redis.Nil {
+		return ErrStateNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("error getting session state from redis: %w", err)
+	}
+	if err := json.Unmarshal([]byte(state), sessionState); err != nil {
+		return err
+	}
+	//sessions saved before the MaxAge was set have no deadline
+	//in redis, so check their age here as well
+	if _, ok := sessionTTL(sessionState, rs.SessionDuration, rs.MaxAge, time.Now()); !ok {
+		if err := rs.Delete(ctx, sid); err != nil {
+			return err
+		}
+		return ErrStateNotFound
+	}
+	return nil
+}
+
+//Peek populates `sessionState` with the data previously saved
+//for the given SessionID, without resetting its expiry time
+func (rs *RedisStore) Peek(ctx context.Context, sid SessionID, sessionState interface{}) error {
+	j, err := rs.Client.WithContext(ctx).Get(sid.getRedisKey()).Bytes()
+	if err == redis.Nil {
+		return ErrStateNotFound
+	}
 	if err != nil {
+		return fmt.Errorf("error getting session state from redis: %w", err)
+	}
+	if err := json.Unmarshal(j, sessionState); err != nil {
+		return err
+	}
+	if _, ok := sessionTTL(sessionState, rs.SessionDuration, rs.MaxAge, time.Now()); !ok {
 		return ErrStateNotFound
 	}
-	rs.Client.Set(sid.getRedisKey(), jzon, rs.SessionDuration)
-	return json.Unmarshal([]byte(jzon), sessionState)
+	return nil
 }
 
 //Delete deletes all state data associated with the SessionID from the store.
-func (rs *RedisStore) Delete(sid SessionID) error {
-	rs.Client.Del(sid.getRedisKey())
+//The session is left in its user's index until the index is next listed.
+func (rs *RedisStore) Delete(ctx context.Context, sid SessionID) error {
+	if err := rs.Client.WithContext(ctx).Del(sid.getRedisKey(), sid.getUserIDKey(), sid.getDeadlineKey()).Err(); err != nil {
+		return fmt.Errorf("error deleting session state from redis: %w", err)
+	}
 	return nil
 }
 
+//Index records that the session `sid` belongs to the user with
+//the given `userID`. The index is kept in a redis set that expires
+//along with the most recently used session in it. The session also
+//records its user ID, so that Get can reset the index's expiry.
+func (rs *RedisStore) Index(ctx context.Context, userID int64, sid SessionID) error {
+	key := getUserIndexKey(userID)
+	_, err := rs.Client.WithContext(ctx).Pipelined(func(pipe redis.Pipeliner) error {
+		pipe.SAdd(key, sid.String())
+		pipe.PExpire(key, rs.SessionDuration)
+		pipe.Set(sid.getUserIDKey(), userID, rs.SessionDuration)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error indexing session in redis: %w", err)
+	}
+	return nil
+}
+
+//List returns the sessions indexed under `userID` that haven't
+//ended. The EXISTS checks are sent together in one pipeline, and
+//the sessions that have ended are then removed from the index.
+func (rs *RedisStore) List(ctx context.Context, userID int64) ([]SessionID, error) {
+	client := rs.Client.WithContext(ctx)
+	key := getUserIndexKey(userID)
+	members, err := client.SMembers(key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("error listing sessions in redis: %w", err)
+	}
+	exists := make([]*redis.IntCmd, len(members))
+	_, err = client.Pipelined(func(pipe redis.Pipeliner) error {
+		for i, member := range members {
+			exists[i] = pipe.Exists(SessionID(member).getRedisKey())
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error listing sessions in redis: %w", err)
+	}
+	sids := []SessionID{}
+	var ended []interface{}
+	for i, member := range members {
+		if exists[i].Val() > 0 {
+			sids = append(sids, SessionID(member))
+		} else {
+			ended = append(ended, member)
+		}
+	}
+	if len(ended) > 0 {
+		if err := client.SRem(key, ended...).Err(); err != nil {
+			return nil, fmt.Errorf("error pruning session index in redis: %w", err)
+		}
+	}
+	return sids, nil
+}
+
+//DeleteAll deletes the state of every session indexed under
+//`userID`, except for the sessions listed in `keep`.
+func (rs *RedisStore) DeleteAll(ctx context.Context, userID int64, keep ...SessionID) error {
+	client := rs.Client.WithContext(ctx)
+	key := getUserIndexKey(userID)
+	members, err := client.SMembers(key).Result()
+	if err != nil {
+		return fmt.Errorf("error deleting sessions from redis: %w", err)
+	}
+	kept := map[string]bool{}
+	for _, sid := range keep {
+		kept[sid.String()] = true
+	}
+	var delKeys []string
+	var delMembers []interface{}
+	for _, member := range members {
+		if !kept[member] {
+			sid := SessionID(member)
+			delKeys = append(delKeys, sid.getRedisKey(), sid.getUserIDKey(), sid.getDeadlineKey())
+			delMembers = append(delMembers, member)
+		}
+	}
+	if len(delKeys) == 0 {
+		return nil
+	}
+	_, err = client.Pipelined(func(pipe redis.Pipeliner) error {
+		pipe.Del(delKeys...)
+		pipe.SRem(key, delMembers...)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("error deleting sessions from redis: %w", err)
+	}
+	return nil
+}
+
+//getUserIndexKey returns the redis key of the set holding
+//the SessionIDs of the user with the given ID
+func getUserIndexKey(userID int64) string {
+	return "uid:" + strconv.FormatInt(userID, 10) + ":sids"
+}
+
 //getRedisKey() returns the redis key to use for the SessionID
 func (sid SessionID) getRedisKey() string {
 	//convert the SessionID to a string and add the prefix "sid:" to keep
@@ -72,3 +316,29 @@ func (sid SessionID) getRedisKey() string {
 	//redis instance
 	return "sid:" + sid.String()
 }
+
+//getUserIDKey returns the redis key holding the ID of
+//the user that the indexed SessionID belongs to
+func (sid SessionID) getUserIDKey() string {
+	return "sid:" + sid.String() + ":uid"
+}
+
+//getDeadlineKey returns the redis key holding the time, in
+//milliseconds since the Unix epoch, that the session reaches the MaxAge
+func (sid SessionID) getDeadlineKey() string {
+	return "sid:" + sid.String() + ":deadline"
+}
+
+//unixMillis returns `t` in milliseconds since the Unix epoch
+func unixMillis(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+//durationMillis returns `d` in milliseconds, rounded up to at least
+//one millisecond, since redis rejects an expiry time of zero
+func durationMillis(d time.Duration) int64 {
+	if ms := d.Milliseconds(); ms > 0 {
+		return ms
+	}
+	return 1
+}
@@ -1,78 +1,73 @@
 package sessions
 
 import (
+	"context"
 	"errors"
 	"net/http"
-	"strings"
 )
 
 const headerAuthorization = "Authorization"
 const paramAuthorization = "auth"
 const schemeBearer = "Bearer "
 
-//ErrNoSessionID is used when no session ID was found in the Authorization header
-var ErrNoSessionID = errors.New("no session ID found in " + headerAuthorization + " header")
+//ErrNoSessionID is used when no session ID was found in the request
+var ErrNoSessionID = errors.New("no session ID found in the request")
 
 //ErrInvalidScheme is used when the authorization scheme is not supported
 var ErrInvalidScheme = errors.New("authorization scheme not supported")
 
-//BeginSession creates a new SessionID, saves the `sessionState` to the store, adds an
-//Authorization header to the response with the SessionID, and returns the new SessionID
-func BeginSession(signingKey string, store Store, sessionState interface{}, w http.ResponseWriter) (SessionID, error) {
+//BeginSession creates a new SessionID, saves the `sessionState` to the store, sends
+//the SessionID to the client using `transport`, and returns the new SessionID.
+//The state is saved using `ctx`, which is usually the context of the request.
+//The SessionID is signed by `signer`.
+func BeginSession(ctx context.Context, signer Signer, transport Transport, store Store, sessionState interface{}, w http.ResponseWriter) (SessionID, error) {
 	//TODO:
 	//- create a new SessionID
 	//- save the sessionState to the store
-	//- add a header to the ResponseWriter that looks like this:
+	//- send the SessionID to the client, for example in
+	//  a header that looks like this:
 	//    "Authorization: Bearer <sessionID>"
-	//  where "<sessionID>" is replaced with the newly-created SessionID
-	//  (note the constants declared for you above, which will help you avoid typos)
 
-	seshID, err := NewSessionID(signingKey)
+	seshID, err := signer.NewSessionID()
 	if err != nil {
 		return InvalidSessionID, err
 	}
-	err = store.Save(seshID, sessionState)
+	err = store.Save(ctx, seshID, sessionState)
 	if err != nil {
 		return InvalidSessionID, err
 	}
-	w.Header().Add(headerAuthorization, schemeBearer+seshID.String())
+	transport.Write(w, seshID)
 	return seshID, nil
 }
 
-//GetSessionID extracts and validates the SessionID from the request headers
-func GetSessionID(r *http.Request, signingKey string) (SessionID, error) {
-	//TODO: get the value of the Authorization header,
-	//or the "auth" query string parameter if no Authorization header is present,
-	//and validate it. If it's valid, return the SessionID. If not
+//GetSessionID reads the SessionID from the request using `transport`
+//and validates it using `signer`. Malformed SessionIDs of any
+//length or encoding return ErrInvalidID.
+func GetSessionID(r *http.Request, signer Signer, transport Transport) (SessionID, error) {
+	//TODO: get the SessionID from the request and validate it.
+	//If it's valid, return the SessionID. If not
 	//return the validation error.
 
-	seshID := r.Header.Get(headerAuthorization)
-	if len(seshID) == 0 {
-		qResponse := r.URL.Query()[paramAuthorization]
-		if len(qResponse) == 0 {
-			return InvalidSessionID, ErrNoSessionID
-		}
-		seshID = qResponse[0]
-	}
-	if !strings.HasPrefix(seshID, schemeBearer) {
-		return InvalidSessionID, ErrInvalidScheme
+	seshID, err := transport.Read(r)
+	if err != nil {
+		return InvalidSessionID, err
 	}
-	seshID = strings.TrimPrefix(seshID, schemeBearer)
-	return ValidateID(seshID, signingKey)
+	return signer.ValidateID(seshID)
 }
 
 //GetState extracts the SessionID from the request,
 //gets the associated state from the provided store into
-//the `sessionState` parameter, and returns the SessionID
-func GetState(r *http.Request, signingKey string, store Store, sessionState interface{}) (SessionID, error) {
+//the `sessionState` parameter, and returns the SessionID.
+//The store is called with the request's context.
+func GetState(r *http.Request, signer Signer, transport Transport, store Store, sessionState interface{}) (SessionID, error) {
 	//TODO: get the SessionID from the request, and get the data
 	//associated with that SessionID from the store.
 
-	seshID, err := GetSessionID(r, signingKey)
+	seshID, err := GetSessionID(r, signer, transport)
 	if err != nil {
 		return InvalidSessionID, err
 	}
-	err = store.Get(seshID, sessionState)
+	err = store.Get(r.Context(), seshID, sessionState)
 	if err != nil {
 		return InvalidSessionID, err
 	}
@@ -80,15 +75,19 @@ func GetState(r *http.Request, signingKey string, store Store, sessionState inte
 }
 
 //EndSession extracts the SessionID from the request,
-//and deletes the associated data in the provided store, returning
-//the extracted SessionID.
-func EndSession(r *http.Request, signingKey string, store Store) (SessionID, error) {
+//deletes the associated data in the provided store, and tells the
+//client to forget the SessionID using `transport`, returning the
+//extracted SessionID. The store is called with the request's context.
+func EndSession(w http.ResponseWriter, r *http.Request, signer Signer, transport Transport, store Store) (SessionID, error) {
 	//TODO: get the SessionID from the request, and delete the
 	//data associated with it in the store.
-	seshID, err := GetSessionID(r, signingKey)
+	seshID, err := GetSessionID(r, signer, transport)
 	if err != nil {
 		return InvalidSessionID, err
 	}
-	store.Delete(seshID)
+	if err := store.Delete(r.Context(), seshID); err != nil {
+		return InvalidSessionID, err
+	}
+	transport.Clear(w)
 	return seshID, nil
 }
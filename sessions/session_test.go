@@ -1,16 +1,19 @@
 package sessions
 
 import (
+	"context"
+	"encoding/base64"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
 
 func TestSessionGetSessionID(t *testing.T) {
-	key := "test key"
-	sid, err := NewSessionID(key)
+	key := SigningKey("test key")
+	sid, err := key.NewSessionID()
 	if err != nil {
 		t.Fatalf("error generating SessionID: %v", err)
 	}
@@ -51,7 +54,7 @@ func TestSessionGetSessionID(t *testing.T) {
 		//test using Authorization header
 		req, _ := http.NewRequest("GET", "/", nil)
 		req.Header.Add(headerAuthorization, c.header)
-		sidRet, err := GetSessionID(req, key)
+		sidRet, err := GetSessionID(req, key, DefaultTransport)
 		if err != nil && !c.expectError {
 			t.Errorf("case %s: unexpected error: %v\nHINT: %s", c.name, err, c.hint)
 		}
@@ -65,15 +68,15 @@ func TestSessionGetSessionID(t *testing.T) {
 }
 
 func TestSessionGetSessionIDFromParam(t *testing.T) {
-	key := "test key"
-	sid, err := NewSessionID(key)
+	key := SigningKey("test key")
+	sid, err := key.NewSessionID()
 	if err != nil {
 		t.Fatalf("error generating SessionID: %v", err)
 	}
 
 	URL := fmt.Sprintf("/?%s=%s%s", paramAuthorization, schemeBearer, string(sid))
 	req, _ := http.NewRequest("GET", URL, nil)
-	sidRet, err := GetSessionID(req, key)
+	sidRet, err := GetSessionID(req, key, DefaultTransport)
 	if err != nil {
 		t.Errorf("error getting SessionID from query string parameter: %v", err)
 	}
@@ -89,13 +92,13 @@ uses the MemStore as the session store.
 */
 func TestSessionCycle(t *testing.T) {
 	store := NewMemStore(time.Hour, time.Minute)
-	key := "test key"
+	key := SigningKey("test key")
 
 	//first try getting the session state before a session
 	//has been started to ensure you get an error
 	var state int
 	req, _ := http.NewRequest("GET", "/", nil)
-	_, err := GetState(req, key, store, &state)
+	_, err := GetState(req, key, DefaultTransport, store, &state)
 	if err == nil {
 		t.Error("no error returned when getting state before session has started")
 	}
@@ -106,13 +109,13 @@ func TestSessionCycle(t *testing.T) {
 
 	//try beginning a session with an empty session signing key
 	//and ensure it fails
-	_, err = BeginSession("", store, state, respRec)
+	_, err = BeginSession(context.Background(), SigningKey(""), DefaultTransport, store, state, respRec)
 	if err == nil {
 		t.Error("expected error when beginning a new session with an empty signing key")
 	}
 
 	//then try with a valid signing key and make sure it works
-	sid, err := BeginSession(key, store, state, respRec)
+	sid, err := BeginSession(context.Background(), key, DefaultTransport, store, state, respRec)
 	if err != nil {
 		t.Fatalf("error beginning session: %v", err)
 	}
@@ -130,7 +133,7 @@ func TestSessionCycle(t *testing.T) {
 	req, _ = http.NewRequest("GET", "/", nil)
 	req.Header.Add(headerAuthorization, token)
 	var state2 int
-	sid2, err := GetState(req, key, store, &state2)
+	sid2, err := GetState(req, key, DefaultTransport, store, &state2)
 	if err != nil {
 		t.Errorf("unexpected error getting session state: %v", err)
 	}
@@ -142,7 +145,7 @@ func TestSessionCycle(t *testing.T) {
 	}
 
 	//end the session
-	sid2, err = EndSession(req, key, store)
+	sid2, err = EndSession(httptest.NewRecorder(), req, key, DefaultTransport, store)
 	if err != nil {
 		t.Errorf("unexpected error ending session: %v", err)
 	}
@@ -153,7 +156,7 @@ func TestSessionCycle(t *testing.T) {
 	//try getting the session state with the same token to ensure
 	//that we get back the correct error
 	state2 = 0
-	_, err = GetState(req, key, store, &state2)
+	_, err = GetState(req, key, DefaultTransport, store, &state2)
 	if err != ErrStateNotFound {
 		t.Error("getting state after session end did not return ErrStateNotFound")
 	}
@@ -161,8 +164,53 @@ func TestSessionCycle(t *testing.T) {
 	//try ending the session with no Authorization header in request
 	//and ensure it generates an error
 	req.Header.Del(headerAuthorization)
-	_, err = EndSession(req, key, store)
+	_, err = EndSession(httptest.NewRecorder(), req, key, DefaultTransport, store)
 	if err == nil {
 		t.Error("expected error when attempting to end session with no Authorization header in request")
 	}
 }
+
+//FuzzGetSessionID checks that GetSessionID never panics on malformed
+//Authorization headers, and that any malformed token after a valid
+//scheme is answered with ErrInvalidID, whichever Signer validates it.
+//Interesting inputs found by fuzzing are kept in
+//testdata/fuzz/FuzzGetSessionID.
+func FuzzGetSessionID(f *testing.F) {
+	keyring, err := ParseKeyring("2:new key,1:test key")
+	if err != nil {
+		f.Fatalf("error constructing keyring: %v", err)
+	}
+	signers := []Signer{SigningKey("test key"), keyring}
+	//the fuzzing workers run in their own processes,
+	//so the valid IDs must be the same in each of them
+	keyringID := append([]byte{2}, make([]byte, idLength)...)
+	keyringID = append(keyringID, keyring.sign(keyring.keys[2], keyringID)...)
+	valid := map[string]bool{
+		string(signedID("test key", make([]byte, idLength))): true,
+		base64.URLEncoding.EncodeToString(keyringID):         true,
+	}
+	for sid := range valid {
+		f.Add(schemeBearer + sid)
+	}
+	f.Add("")
+	f.Add(schemeBearer)
+	f.Add(schemeBearer + "abc")
+	f.Add("Basic dG9waDpyb2Nrcw==")
+	f.Fuzz(func(t *testing.T, header string) {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.Header.Set(headerAuthorization, header)
+		for _, signer := range signers {
+			sid, err := GetSessionID(req, signer, DefaultTransport)
+			switch {
+			case err == nil && !valid[string(sid)]:
+				t.Errorf("header %q was valid without being signed", header)
+			case err == ErrNoSessionID || err == ErrInvalidScheme:
+				if strings.HasPrefix(header, schemeBearer) {
+					t.Errorf("incorrect error for header %q with a valid scheme: %v", header, err)
+				}
+			case err != nil && err != ErrInvalidID:
+				t.Errorf("incorrect error getting SessionID from %q: expected %v but got %v", header, ErrInvalidID, err)
+			}
+		}
+	})
+}
@@ -14,6 +14,10 @@ const InvalidSessionID SessionID = ""
 //idLength is the length of the ID portion
 const idLength = 32
 
+//handleLength is the number of bytes of the SessionID's
+//hash that make up its Handle
+const handleLength = 16
+
 //signedLength is the full length of the signed session ID
 //(ID portion plus signature)
 const signedLength = idLength + sha256.Size
@@ -59,9 +63,10 @@ func NewSessionID(signingKey string) (SessionID, error) {
 
 //ValidateID validates the string in the `id` parameter
 //using the `signingKey` as the HMAC signing key
-//and returns an error if invalid, or a SessionID if valid
+//and returns an error if invalid, or a SessionID if valid.
+//Malformed IDs of any length or encoding return ErrInvalidID.
 func ValidateID(id string, signingKey string) (SessionID, error) {
-	decodedID, err := base64.URLEncoding.DecodeString(id)
+	decodedID, err := decodeID(id, signedLength)
 	if err != nil {
 		return InvalidSessionID, err
 	}
@@ -79,7 +84,30 @@ func ValidateID(id string, signingKey string) (SessionID, error) {
 	return InvalidSessionID, ErrInvalidID
 }
 
+//decodeID base64-url-decodes `id`, returning ErrInvalidID unless it
+//is the canonical encoding of exactly `length` bytes. The encoded
+//length is checked first, so that long inputs are never decoded.
+func decodeID(id string, length int) ([]byte, error) {
+	if len(id) != base64.URLEncoding.EncodedLen(length) {
+		return nil, ErrInvalidID
+	}
+	decodedID, err := base64.URLEncoding.Strict().DecodeString(id)
+	if err != nil || len(decodedID) != length {
+		return nil, ErrInvalidID
+	}
+	return decodedID, nil
+}
+
 //String returns a string representation of the sessionID
 func (sid SessionID) String() string {
 	return string(sid)
 }
+
+//Handle returns an opaque name for the session, made by hashing the
+//SessionID. Unlike the SessionID, which is a bearer credential, it
+//can be shown to users and put in URLs, since it can't be used to
+//authenticate or turned back into the SessionID.
+func (sid SessionID) Handle() string {
+	sum := sha256.Sum256([]byte(sid))
+	return base64.RawURLEncoding.EncodeToString(sum[:handleLength])
+}
@@ -1,10 +1,16 @@
 package sessions
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"encoding/base64"
+	"strings"
 	"testing"
 )
 
+//base64URLAlphabet is the alphabet of base64.URLEncoding
+const base64URLAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+
 func TestNewID(t *testing.T) {
 	cases := []struct {
 		name        string
@@ -128,6 +134,45 @@ func TestValidateID(t *testing.T) {
 			},
 			true,
 		},
+		{
+			"Shorter Than ID Portion",
+			"Check the length of the decoded ID before slicing it",
+			"test key",
+			"test key",
+			func(sid SessionID) SessionID { return "abc" },
+			true,
+		},
+		{
+			"Extra Bytes",
+			"Only IDs of exactly the signed length are valid",
+			"test key",
+			"test key",
+			func(sid SessionID) SessionID {
+				buf, _ := base64.URLEncoding.DecodeString(string(sid))
+				return SessionID(base64.URLEncoding.EncodeToString(append(buf, 0)))
+			},
+			true,
+		},
+		{
+			"Padding Stripped",
+			"Only the padded base64-url encoding is valid",
+			"test key",
+			"test key",
+			func(sid SessionID) SessionID { return SessionID(strings.TrimRight(string(sid), "=")) },
+			true,
+		},
+		{
+			"Non-Canonical Encoding",
+			"The unused bits before the padding must be zero",
+			"test key",
+			"test key",
+			func(sid SessionID) SessionID {
+				//the last character before the padding carries 4 unused bits
+				last := len(sid) - 3
+				return sid[:last] + SessionID(base64URLAlphabet[strings.IndexByte(base64URLAlphabet, sid[last])^1]) + sid[last+1:]
+			},
+			true,
+		},
 	}
 
 	for _, c := range cases {
@@ -145,8 +190,8 @@ func TestValidateID(t *testing.T) {
 		if err != nil && !c.expectError {
 			t.Errorf("case %s: unexpected error validating SessionID: %v\nHINT: %s", c.name, err, c.hint)
 		}
-		if c.expectError && err == nil {
-			t.Errorf("case %s: expected error but didn't get one\nHINT: %s", c.name, c.hint)
+		if c.expectError && err != ErrInvalidID {
+			t.Errorf("case %s: incorrect error: expected %v but got %v\nHINT: %s", c.name, ErrInvalidID, err, c.hint)
 		}
 
 		if err == nil && sid2 != sid {
@@ -154,3 +199,32 @@ func TestValidateID(t *testing.T) {
 		}
 	}
 }
+
+//FuzzValidateID checks that ValidateID answers every malformed ID
+//with ErrInvalidID rather than panicking. Interesting inputs found
+//by fuzzing are kept in testdata/fuzz/FuzzValidateID.
+func FuzzValidateID(f *testing.F) {
+	//the fuzzing workers run in their own processes,
+	//so the valid ID must be the same in each of them
+	sid := signedID("test key", make([]byte, idLength))
+	f.Add(string(sid))
+	f.Add("")
+	f.Add("abc")
+	f.Fuzz(func(t *testing.T, id string) {
+		validated, err := ValidateID(id, "test key")
+		if err == nil && validated != sid {
+			t.Errorf("ID %q was valid without being signed", id)
+		}
+		if err != nil && err != ErrInvalidID {
+			t.Errorf("incorrect error validating %q: expected %v but got %v", id, ErrInvalidID, err)
+		}
+	})
+}
+
+//signedID returns the SessionID that NewSessionID would make
+//from the ID portion `idPortion` signed with `signingKey`
+func signedID(signingKey string, idPortion []byte) SessionID {
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write(idPortion)
+	return SessionID(base64.URLEncoding.EncodeToString(append(idPortion, mac.Sum(nil)...)))
+}
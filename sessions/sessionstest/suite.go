@@ -0,0 +1,587 @@
+//Package sessionstest provides a conformance test suite for
+//implementations of sessions.Store. Run it from the tests of
+//each new store to prove that it behaves like the existing ones.
+package sessionstest
+
+import (
+	"assignments-jelauria/servers/gateway/sessions"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+//signingKey is the key used to sign the SessionIDs in the suite
+const signingKey = "test key"
+
+//StoreFactory returns a new, empty sessions.Store in which session
+//state expires after sitting idle for `sessionDuration`. If the store
+//needs a backing server, check that it is available before calling
+//RunStoreSuite and skip the calling test if it isn't, rather than
+//skipping in the factory, which would skip every test in the suite
+//while the calling test still passes.
+type StoreFactory func(t *testing.T, sessionDuration time.Duration) sessions.Store
+
+//MaxAgeStoreFactory returns a new, empty sessions.Store like a
+//StoreFactory, whose MaxAge is set to `maxAge`
+type MaxAgeStoreFactory func(t *testing.T, sessionDuration time.Duration, maxAge time.Duration) sessions.Store
+
+//Option configures optional parts of the suite
+type Option func(*options)
+
+//options holds the settings made by the Options passed to RunStoreSuite
+type options struct {
+	maxAgeFactory MaxAgeStoreFactory
+}
+
+//WithMaxAge also runs the MaxAge tests against the stores returned
+//by `factory`. Without it, those tests are skipped.
+func WithMaxAge(factory MaxAgeStoreFactory) Option {
+	return func(o *options) {
+		o.maxAgeFactory = factory
+	}
+}
+
+//sessionState is the session state saved in the suite
+type sessionState struct {
+	Sval string
+	Ival int
+}
+
+//agedState is the session state saved in the MaxAge tests,
+//which records when its session began
+type agedState struct {
+	sessionState
+	Start time.Time
+}
+
+//SessionStart returns Start, so that stores can end the session
+//once it reaches their MaxAge
+func (s *agedState) SessionStart() time.Time {
+	return s.Start
+}
+
+//RunStoreSuite runs the conformance tests against the stores
+//returned by `factory`. Each test gets a new store. If the store
+//is also a sessions.IndexedStore, the index is tested as well.
+//The MaxAge tests only run if WithMaxAge is passed in `opts`.
+func RunStoreSuite(t *testing.T, factory StoreFactory, opts ...Option) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	tests := []struct {
+		name string
+		test func(t *testing.T, factory StoreFactory)
+	}{
+		{"NotFound", testNotFound},
+		{"CRUD", testCRUD},
+		{"SaveUnmarshalable", testSaveUnmarshalable},
+		{"Expiry", testExpiry},
+		{"GetResetsExpiry", testGetResetsExpiry},
+		{"DeleteAll", testDeleteAll},
+		{"DeleteAllKeptAlive", testDeleteAllKeptAlive},
+		{"Update", testUpdate},
+		{"UpdateAfterDeleteAll", testUpdateAfterDeleteAll},
+		{"List", testList},
+		{"IndexExpiry", testIndexExpiry},
+		{"ConcurrentAccess", testConcurrentAccess},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			test.test(t, factory)
+		})
+	}
+
+	maxAgeTests := []struct {
+		name string
+		test func(t *testing.T, factory MaxAgeStoreFactory)
+	}{
+		{"MaxAgeExpiry", testMaxAgeExpiry},
+		{"MaxAgeCapsTTL", testMaxAgeCapsTTL},
+		{"MaxAgeUpdate", testMaxAgeUpdate},
+	}
+	for _, test := range maxAgeTests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			if o.maxAgeFactory == nil {
+				t.Skip("no MaxAgeStoreFactory was given with WithMaxAge")
+			}
+			test.test(t, o.maxAgeFactory)
+		})
+	}
+}
+
+//newSessionID returns a new SessionID, failing the test on error
+func newSessionID(t *testing.T) sessions.SessionID {
+	sid, err := sessions.NewSessionID(signingKey)
+	if err != nil {
+		t.Fatalf("error generating new SessionID: %v", err)
+	}
+	return sid
+}
+
+//newUserID returns a random user ID, so that the suite doesn't use
+//the sessions indexed by earlier runs, or by anyone else sharing the
+//store's backing server. It fails the test on error.
+func newUserID(t *testing.T) int64 {
+	var buf [8]byte
+	if _, err := rand.Read(buf[:]); err != nil {
+		t.Fatalf("error generating new user ID: %v", err)
+	}
+	return int64(binary.BigEndian.Uint64(buf[:]) >> 1)
+}
+
+func testNotFound(t *testing.T, factory StoreFactory) {
+	store := factory(t, time.Hour)
+	sid := newSessionID(t)
+	if err := store.Get(context.Background(), sid, &sessionState{}); err != sessions.ErrStateNotFound {
+		t.Errorf("incorrect error when getting state that was never stored: expected %v but got %v", sessions.ErrStateNotFound, err)
+	}
+	if err := store.Delete(context.Background(), sid); err != nil {
+		t.Errorf("unexpected error deleting state that was never stored: %v", err)
+	}
+}
+
+func testCRUD(t *testing.T, factory StoreFactory) {
+	store := factory(t, time.Hour)
+	sid := newSessionID(t)
+	state := &sessionState{"testing", 99}
+	if err := store.Save(context.Background(), sid, state); err != nil {
+		t.Fatalf("error saving state: %v", err)
+	}
+	stateRet := &sessionState{}
+	if err := store.Get(context.Background(), sid, stateRet); err != nil {
+		t.Fatalf("error getting state: %v", err)
+	}
+	if !reflect.DeepEqual(state, stateRet) {
+		t.Errorf("incorrect state retrieved: expected %+v but got %+v", state, stateRet)
+	}
+
+	//saving again should replace the state
+	state.Ival = 100
+	if err := store.Save(context.Background(), sid, state); err != nil {
+		t.Fatalf("error saving state: %v", err)
+	}
+	if err := store.Get(context.Background(), sid, stateRet); err != nil {
+		t.Fatalf("error getting state: %v", err)
+	}
+	if stateRet.Ival != 100 {
+		t.Errorf("saved state was not replaced: expected Ival 100 but got %d", stateRet.Ival)
+	}
+
+	if err := store.Delete(context.Background(), sid); err != nil {
+		t.Errorf("error deleting state: %v", err)
+	}
+	if err := store.Get(context.Background(), sid, stateRet); err != sessions.ErrStateNotFound {
+		t.Errorf("incorrect error when getting state that was deleted: expected %v but got %v", sessions.ErrStateNotFound, err)
+	}
+}
+
+func testSaveUnmarshalable(t *testing.T, factory StoreFactory) {
+	store := factory(t, time.Hour)
+	//function values can't be encoded in JSON
+	if err := store.Save(context.Background(), newSessionID(t), func() {}); err == nil {
+		t.Error("expected error when attempting to save an unmarshalable session state")
+	}
+}
+
+func testExpiry(t *testing.T, factory StoreFactory) {
+	store := factory(t, 500*time.Millisecond)
+	sid := newSessionID(t)
+	if err := store.Save(context.Background(), sid, &sessionState{"testing", 99}); err != nil {
+		t.Fatalf("error saving state: %v", err)
+	}
+	time.Sleep(800 * time.Millisecond)
+	if err := store.Get(context.Background(), sid, &sessionState{}); err != sessions.ErrStateNotFound {
+		t.Errorf("incorrect error when getting state that expired: expected %v but got %v", sessions.ErrStateNotFound, err)
+	}
+}
+
+func testGetResetsExpiry(t *testing.T, factory StoreFactory) {
+	store := factory(t, 500*time.Millisecond)
+	sid := newSessionID(t)
+	if err := store.Save(context.Background(), sid, &sessionState{"testing", 99}); err != nil {
+		t.Fatalf("error saving state: %v", err)
+	}
+	//the state is used more often than it expires,
+	//so it should outlive the original session duration
+	for i := 0; i < 3; i++ {
+		time.Sleep(300 * time.Millisecond)
+		if err := store.Get(context.Background(), sid, &sessionState{}); err != nil {
+			t.Fatalf("error getting state that was recently used: %v", err)
+		}
+	}
+}
+
+func testDeleteAll(t *testing.T, factory StoreFactory) {
+	store, ok := factory(t, time.Hour).(sessions.IndexedStore)
+	if !ok {
+		t.Skip("store is not a sessions.IndexedStore")
+	}
+	userID := newUserID(t)
+	var sids []sessions.SessionID
+	for i := 0; i < 3; i++ {
+		sid := newSessionID(t)
+		if err := store.Save(context.Background(), sid, &sessionState{"testing", i}); err != nil {
+			t.Fatalf("error saving state: %v", err)
+		}
+		if err := store.Index(context.Background(), userID, sid); err != nil {
+			t.Fatalf("error indexing session: %v", err)
+		}
+		sids = append(sids, sid)
+	}
+	//a session belonging to a different user
+	otherSid := newSessionID(t)
+	store.Save(context.Background(), otherSid, &sessionState{"other", 0})
+	store.Index(context.Background(), newUserID(t), otherSid)
+
+	if err := store.DeleteAll(context.Background(), userID, sids[0]); err != nil {
+		t.Fatalf("error deleting sessions: %v", err)
+	}
+	if err := store.Get(context.Background(), sids[0], &sessionState{}); err != nil {
+		t.Errorf("kept session was deleted: %v", err)
+	}
+	for _, sid := range sids[1:] {
+		if err := store.Get(context.Background(), sid, &sessionState{}); err != sessions.ErrStateNotFound {
+			t.Errorf("incorrect error when getting state that was deleted: expected %v but got %v", sessions.ErrStateNotFound, err)
+		}
+	}
+	if err := store.Get(context.Background(), otherSid, &sessionState{}); err != nil {
+		t.Errorf("session of a different user was deleted: %v", err)
+	}
+
+	if err := store.DeleteAll(context.Background(), userID); err != nil {
+		t.Fatalf("error deleting sessions: %v", err)
+	}
+	if err := store.Get(context.Background(), sids[0], &sessionState{}); err != sessions.ErrStateNotFound {
+		t.Errorf("incorrect error when getting state that was deleted: expected %v but got %v", sessions.ErrStateNotFound, err)
+	}
+}
+
+//testDeleteAllKeptAlive checks that a session used for longer than
+//the session duration stays in its user's index, so that DeleteAll
+//still ends it, for example after the user changes their password
+func testDeleteAllKeptAlive(t *testing.T, factory StoreFactory) {
+	store, ok := factory(t, 500*time.Millisecond).(sessions.IndexedStore)
+	if !ok {
+		t.Skip("store is not a sessions.IndexedStore")
+	}
+	userID := newUserID(t)
+	sid := newSessionID(t)
+	if err := store.Save(context.Background(), sid, &sessionState{"testing", 99}); err != nil {
+		t.Fatalf("error saving state: %v", err)
+	}
+	if err := store.Index(context.Background(), userID, sid); err != nil {
+		t.Fatalf("error indexing session: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		time.Sleep(300 * time.Millisecond)
+		if err := store.Get(context.Background(), sid, &sessionState{}); err != nil {
+			t.Fatalf("error getting state that was recently used: %v", err)
+		}
+	}
+	if err := store.DeleteAll(context.Background(), userID); err != nil {
+		t.Fatalf("error deleting sessions: %v", err)
+	}
+	if err := store.Get(context.Background(), sid, &sessionState{}); err != sessions.ErrStateNotFound {
+		t.Errorf("incorrect error when getting state of a session kept alive past the session duration: expected %v but got %v", sessions.ErrStateNotFound, err)
+	}
+}
+
+func testUpdate(t *testing.T, factory StoreFactory) {
+	store, ok := factory(t, time.Hour).(sessions.IndexedStore)
+	if !ok {
+		t.Skip("store is not a sessions.IndexedStore")
+	}
+	userID := newUserID(t)
+	sid := newSessionID(t)
+	state := &sessionState{"testing", 99}
+	//a session that was never saved can't be updated
+	if err := store.Update(context.Background(), userID, sid, state); err != sessions.ErrStateNotFound {
+		t.Errorf("incorrect error when updating state that was never stored: expected %v but got %v", sessions.ErrStateNotFound, err)
+	}
+	if err := store.Save(context.Background(), sid, state); err != nil {
+		t.Fatalf("error saving state: %v", err)
+	}
+	//nor can one that isn't indexed under the user
+	if err := store.Update(context.Background(), userID, sid, state); err != sessions.ErrStateNotFound {
+		t.Errorf("incorrect error when updating state that isn't indexed: expected %v but got %v", sessions.ErrStateNotFound, err)
+	}
+	if err := store.Index(context.Background(), userID, sid); err != nil {
+		t.Fatalf("error indexing session: %v", err)
+	}
+	if err := store.Update(context.Background(), newUserID(t), sid, state); err != sessions.ErrStateNotFound {
+		t.Errorf("incorrect error when updating state indexed under a different user: expected %v but got %v", sessions.ErrStateNotFound, err)
+	}
+
+	state.Ival = 100
+	if err := store.Update(context.Background(), userID, sid, state); err != nil {
+		t.Fatalf("error updating state: %v", err)
+	}
+	stateRet := &sessionState{}
+	if err := store.Get(context.Background(), sid, stateRet); err != nil {
+		t.Fatalf("error getting state: %v", err)
+	}
+	if !reflect.DeepEqual(state, stateRet) {
+		t.Errorf("incorrect state retrieved after update: expected %+v but got %+v", state, stateRet)
+	}
+}
+
+//testUpdateAfterDeleteAll checks that a session ended by DeleteAll
+//while a request was still using it isn't brought back when the
+//request updates its state
+func testUpdateAfterDeleteAll(t *testing.T, factory StoreFactory) {
+	store, ok := factory(t, time.Hour).(sessions.IndexedStore)
+	if !ok {
+		t.Skip("store is not a sessions.IndexedStore")
+	}
+	userID := newUserID(t)
+	sid := newSessionID(t)
+	if err := store.Save(context.Background(), sid, &sessionState{"testing", 99}); err != nil {
+		t.Fatalf("error saving state: %v", err)
+	}
+	if err := store.Index(context.Background(), userID, sid); err != nil {
+		t.Fatalf("error indexing session: %v", err)
+	}
+	state := &sessionState{}
+	if err := store.Get(context.Background(), sid, state); err != nil {
+		t.Fatalf("error getting state: %v", err)
+	}
+	if err := store.DeleteAll(context.Background(), userID); err != nil {
+		t.Fatalf("error deleting sessions: %v", err)
+	}
+	state.Ival = 100
+	if err := store.Update(context.Background(), userID, sid, state); err != sessions.ErrStateNotFound {
+		t.Errorf("incorrect error when updating state that was deleted: expected %v but got %v", sessions.ErrStateNotFound, err)
+	}
+	if err := store.Get(context.Background(), sid, &sessionState{}); err != sessions.ErrStateNotFound {
+		t.Errorf("incorrect error when getting state that was deleted and then updated: expected %v but got %v", sessions.ErrStateNotFound, err)
+	}
+}
+
+func testList(t *testing.T, factory StoreFactory) {
+	store, ok := factory(t, time.Hour).(sessions.IndexedStore)
+	if !ok {
+		t.Skip("store is not a sessions.IndexedStore")
+	}
+	userID := newUserID(t)
+	sids := map[sessions.SessionID]bool{}
+	var deleted sessions.SessionID
+	for i := 0; i < 3; i++ {
+		sid := newSessionID(t)
+		if err := store.Save(context.Background(), sid, &sessionState{"testing", i}); err != nil {
+			t.Fatalf("error saving state: %v", err)
+		}
+		if err := store.Index(context.Background(), userID, sid); err != nil {
+			t.Fatalf("error indexing session: %v", err)
+		}
+		sids[sid] = true
+		deleted = sid
+	}
+	//a session that has ended should not be listed
+	if err := store.Delete(context.Background(), deleted); err != nil {
+		t.Fatalf("error deleting state: %v", err)
+	}
+	delete(sids, deleted)
+
+	listed, err := store.List(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("error listing sessions: %v", err)
+	}
+	if len(listed) != len(sids) {
+		t.Errorf("incorrect number of sessions listed: expected %d but got %d", len(sids), len(listed))
+	}
+	for _, sid := range listed {
+		if !sids[sid] {
+			t.Errorf("incorrect session listed: %s", sid)
+		}
+		state := &sessionState{}
+		if err := store.Peek(context.Background(), sid, state); err != nil || state.Sval != "testing" {
+			t.Errorf("error peeking at listed session: %v", err)
+		}
+	}
+	if err := store.Peek(context.Background(), deleted, &sessionState{}); err != sessions.ErrStateNotFound {
+		t.Errorf("incorrect error when peeking at state that was deleted: expected %v but got %v", sessions.ErrStateNotFound, err)
+	}
+
+	if listed, err := store.List(context.Background(), newUserID(t)); err != nil || len(listed) != 0 {
+		t.Errorf("incorrect sessions listed for a user without any: %v, %v", listed, err)
+	}
+}
+
+func testIndexExpiry(t *testing.T, factory StoreFactory) {
+	store, ok := factory(t, 500*time.Millisecond).(sessions.IndexedStore)
+	if !ok {
+		t.Skip("store is not a sessions.IndexedStore")
+	}
+	userID := newUserID(t)
+	used, idle := newSessionID(t), newSessionID(t)
+	for _, sid := range []sessions.SessionID{used, idle} {
+		if err := store.Save(context.Background(), sid, &sessionState{"testing", 99}); err != nil {
+			t.Fatalf("error saving state: %v", err)
+		}
+		if err := store.Index(context.Background(), userID, sid); err != nil {
+			t.Fatalf("error indexing session: %v", err)
+		}
+	}
+	//peeking should not keep the idle session alive, while
+	//using the other one should keep it in the index
+	for i := 0; i < 3; i++ {
+		time.Sleep(300 * time.Millisecond)
+		if err := store.Get(context.Background(), used, &sessionState{}); err != nil {
+			t.Fatalf("error getting state that was recently used: %v", err)
+		}
+		store.Peek(context.Background(), idle, &sessionState{})
+	}
+	listed, err := store.List(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("error listing sessions: %v", err)
+	}
+	if len(listed) != 1 || listed[0] != used {
+		t.Errorf("incorrect sessions listed: expected only %s but got %v", used, listed)
+	}
+}
+
+//testMaxAgeExpiry checks that a session ends once it reaches the
+//MaxAge, even though it is used more often than it would expire
+func testMaxAgeExpiry(t *testing.T, factory MaxAgeStoreFactory) {
+	store := factory(t, 500*time.Millisecond, time.Second)
+	sid := newSessionID(t)
+	if err := store.Save(context.Background(), sid, &agedState{sessionState{"testing", 99}, time.Now()}); err != nil {
+		t.Fatalf("error saving state: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		time.Sleep(300 * time.Millisecond)
+		if err := store.Get(context.Background(), sid, &agedState{}); err != nil {
+			t.Fatalf("error getting state that was recently used: %v", err)
+		}
+	}
+	time.Sleep(300 * time.Millisecond)
+	if err := store.Get(context.Background(), sid, &agedState{}); err != sessions.ErrStateNotFound {
+		t.Errorf("incorrect error when getting state past the MaxAge: expected %v but got %v", sessions.ErrStateNotFound, err)
+	}
+
+	//a session that is already past the MaxAge isn't saved
+	old := newSessionID(t)
+	if err := store.Save(context.Background(), old, &agedState{sessionState{"testing", 99}, time.Now().Add(-time.Hour)}); err != nil {
+		t.Fatalf("error saving state: %v", err)
+	}
+	if err := store.Get(context.Background(), old, &agedState{}); err != sessions.ErrStateNotFound {
+		t.Errorf("incorrect error when getting state saved past the MaxAge: expected %v but got %v", sessions.ErrStateNotFound, err)
+	}
+}
+
+//testMaxAgeCapsTTL checks that the state of a session that reaches
+//the MaxAge before it would sit idle for the session duration expires
+//by itself at the MaxAge, rather than only being found to have ended
+//when it is next read. List doesn't read the state, so it only drops
+//sessions whose state has expired.
+func testMaxAgeCapsTTL(t *testing.T, factory MaxAgeStoreFactory) {
+	store, ok := factory(t, time.Hour, 600*time.Millisecond).(sessions.IndexedStore)
+	if !ok {
+		t.Skip("store is not a sessions.IndexedStore")
+	}
+	userID := newUserID(t)
+	saved, used := newSessionID(t), newSessionID(t)
+	for _, sid := range []sessions.SessionID{saved, used} {
+		if err := store.Save(context.Background(), sid, &agedState{sessionState{"testing", 99}, time.Now()}); err != nil {
+			t.Fatalf("error saving state: %v", err)
+		}
+		if err := store.Index(context.Background(), userID, sid); err != nil {
+			t.Fatalf("error indexing session: %v", err)
+		}
+	}
+	//getting the state resets its expiry, but not beyond the MaxAge
+	time.Sleep(300 * time.Millisecond)
+	if err := store.Get(context.Background(), used, &agedState{}); err != nil {
+		t.Fatalf("error getting state: %v", err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	listed, err := store.List(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("error listing sessions: %v", err)
+	}
+	if len(listed) != 0 {
+		t.Errorf("sessions past the MaxAge were listed: %v", listed)
+	}
+}
+
+//testMaxAgeUpdate checks that updating the state of a session
+//doesn't extend it beyond the MaxAge, and that a session past
+//the MaxAge can't be updated
+func testMaxAgeUpdate(t *testing.T, factory MaxAgeStoreFactory) {
+	store, ok := factory(t, time.Hour, 600*time.Millisecond).(sessions.IndexedStore)
+	if !ok {
+		t.Skip("store is not a sessions.IndexedStore")
+	}
+	userID := newUserID(t)
+	sid := newSessionID(t)
+	state := &agedState{sessionState{"testing", 99}, time.Now()}
+	if err := store.Save(context.Background(), sid, state); err != nil {
+		t.Fatalf("error saving state: %v", err)
+	}
+	if err := store.Index(context.Background(), userID, sid); err != nil {
+		t.Fatalf("error indexing session: %v", err)
+	}
+	time.Sleep(300 * time.Millisecond)
+	state.Ival = 100
+	if err := store.Update(context.Background(), userID, sid, state); err != nil {
+		t.Fatalf("error updating state: %v", err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	if listed, err := store.List(context.Background(), userID); err != nil || len(listed) != 0 {
+		t.Errorf("incorrect sessions listed after the MaxAge: %v, %v", listed, err)
+	}
+	if err := store.Get(context.Background(), sid, &agedState{}); err != sessions.ErrStateNotFound {
+		t.Errorf("incorrect error when getting updated state past the MaxAge: expected %v but got %v", sessions.ErrStateNotFound, err)
+	}
+	if err := store.Update(context.Background(), userID, sid, state); err != sessions.ErrStateNotFound {
+		t.Errorf("incorrect error when updating state past the MaxAge: expected %v but got %v", sessions.ErrStateNotFound, err)
+	}
+}
+
+func testConcurrentAccess(t *testing.T, factory StoreFactory) {
+	store := factory(t, time.Hour)
+	const numSessions = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, numSessions)
+	for i := 0; i < numSessions; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sid, err := sessions.NewSessionID(signingKey)
+			if err != nil {
+				errs <- err
+				return
+			}
+			state := &sessionState{fmt.Sprintf("session %d", i), i}
+			if err := store.Save(context.Background(), sid, state); err != nil {
+				errs <- fmt.Errorf("error saving state: %v", err)
+				return
+			}
+			stateRet := &sessionState{}
+			if err := store.Get(context.Background(), sid, stateRet); err != nil {
+				errs <- fmt.Errorf("error getting state: %v", err)
+				return
+			}
+			if !reflect.DeepEqual(state, stateRet) {
+				errs <- fmt.Errorf("incorrect state retrieved: expected %+v but got %+v", state, stateRet)
+				return
+			}
+			if err := store.Delete(context.Background(), sid); err != nil {
+				errs <- fmt.Errorf("error deleting state: %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
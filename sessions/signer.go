@@ -0,0 +1,30 @@
+package sessions
+
+//Signer creates new digitally-signed SessionIDs and validates
+//the SessionIDs sent back by clients. Use a SigningKey to sign
+//with a single key, or a Keyring to rotate keys without ending
+//every session at once.
+type Signer interface {
+	//NewSessionID creates and returns a new digitally-signed SessionID
+	NewSessionID() (SessionID, error)
+
+	//ValidateID validates the string in the `id` parameter and returns
+	//an error if invalid, or a SessionID if valid
+	ValidateID(id string) (SessionID, error)
+}
+
+//SigningKey is a Signer that signs and validates SessionIDs with a
+//single HMAC key, using NewSessionID and ValidateID. Changing the key
+//ends every session, so prefer a Keyring where keys are rotated.
+type SigningKey string
+
+//NewSessionID creates and returns a new SessionID signed with the key
+func (key SigningKey) NewSessionID() (SessionID, error) {
+	return NewSessionID(string(key))
+}
+
+//ValidateID validates the string in the `id` parameter using
+//the key, and returns an error if invalid, or a SessionID if valid
+func (key SigningKey) ValidateID(id string) (SessionID, error) {
+	return ValidateID(id, string(key))
+}
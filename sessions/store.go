@@ -1,6 +1,7 @@
 package sessions
 
 import (
+	"context"
 	"errors"
 )
 
@@ -13,16 +14,52 @@ var ErrStateNotFound = errors.New("no session state was found in the session sto
 //against several different types of data stores. For example,
 //session data could be stored in memory in a concurrent map,
 //or more typically in a shared key/value server store like redis.
+//Every method takes a context, and stores backed by a server should
+//give up and return the context's error once it is done. Errors other
+//than ErrStateNotFound mean the store itself failed, and must not be
+//mistaken for a missing session.
 type Store interface {
 	//Save saves the provided `sessionState` and associated SessionID to the store.
 	//The `sessionState` parameter is typically a pointer to a struct containing
 	//all the data you want to associated with the given SessionID.
-	Save(sid SessionID, sessionState interface{}) error
+	Save(ctx context.Context, sid SessionID, sessionState interface{}) error
 
 	//Get populates `sessionState` with the data previously saved
 	//for the given SessionID
-	Get(sid SessionID, sessionState interface{}) error
+	Get(ctx context.Context, sid SessionID, sessionState interface{}) error
 
 	//Delete deletes all state data associated with the SessionID from the store.
-	Delete(sid SessionID) error
+	Delete(ctx context.Context, sid SessionID) error
+}
+
+//IndexedStore is a Store that also keeps an index of the sessions
+//that belong to each user, so that they can be ended all at once,
+//for example after the user changes their password.
+//Check for it with a type assertion on a Store.
+type IndexedStore interface {
+	Store
+
+	//Index records that the session `sid` belongs to the user with
+	//the given `userID`. Call it after saving the session's state.
+	Index(ctx context.Context, userID int64, sid SessionID) error
+
+	//Update saves `sessionState` like Save, but only if the session
+	//`sid` still exists and is indexed under `userID`. Otherwise it
+	//returns ErrStateNotFound, so that a session ended by DeleteAll
+	//while a request was using it isn't saved again.
+	Update(ctx context.Context, userID int64, sid SessionID, sessionState interface{}) error
+
+	//DeleteAll deletes the state of every session indexed under
+	//`userID`, except for the sessions listed in `keep`.
+	DeleteAll(ctx context.Context, userID int64, keep ...SessionID) error
+
+	//List returns the sessions indexed under `userID` that haven't
+	//ended. Sessions that were deleted or expired are pruned from the
+	//index as they are found.
+	List(ctx context.Context, userID int64) ([]SessionID, error)
+
+	//Peek populates `sessionState` like Get, but leaves the session's
+	//expiry time alone, so that listing a user's sessions doesn't
+	//keep them all alive
+	Peek(ctx context.Context, sid SessionID, sessionState interface{}) error
 }
@@ -0,0 +1,49 @@
+package sessions_test
+
+import (
+	"assignments-jelauria/servers/gateway/sessions"
+	"assignments-jelauria/servers/gateway/sessions/sessionstest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+func TestMemStoreSuite(t *testing.T) {
+	sessionstest.RunStoreSuite(t, func(t *testing.T, sessionDuration time.Duration) sessions.Store {
+		return sessions.NewMemStore(sessionDuration, time.Minute)
+	}, sessionstest.WithMaxAge(func(t *testing.T, sessionDuration time.Duration, maxAge time.Duration) sessions.Store {
+		store := sessions.NewMemStore(sessionDuration, time.Minute)
+		store.MaxAge = maxAge
+		return store
+	}))
+}
+
+//TestRedisStoreSuite runs the suite against a local instance of
+//redis, or the one at REDISADDR, and is skipped if redis isn't running.
+//The suite only uses keys for new SessionIDs and random user IDs,
+//which are unlikely to clash with real ones, but it's best not to
+//point it at a production server.
+func TestRedisStoreSuite(t *testing.T) {
+	redisaddr := os.Getenv("REDISADDR")
+	if len(redisaddr) == 0 {
+		redisaddr = "127.0.0.1:6379"
+	}
+	//skip the whole suite here, so that it isn't reported as
+	//passing when every test in it was skipped
+	client := redis.NewClient(&redis.Options{
+		Addr: redisaddr,
+	})
+	defer client.Close()
+	if err := client.Ping().Err(); err != nil {
+		t.Skipf("redis is not available at %s: %v", redisaddr, err)
+	}
+	sessionstest.RunStoreSuite(t, func(t *testing.T, sessionDuration time.Duration) sessions.Store {
+		return sessions.NewRedisStore(client, sessionDuration)
+	}, sessionstest.WithMaxAge(func(t *testing.T, sessionDuration time.Duration, maxAge time.Duration) sessions.Store {
+		store := sessions.NewRedisStore(client, sessionDuration)
+		store.MaxAge = maxAge
+		return store
+	}))
+}
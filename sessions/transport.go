@@ -0,0 +1,173 @@
+package sessions
+
+import (
+	"net/http"
+	"strings"
+)
+
+//Transport carries SessionIDs between the server and its clients,
+//for example in a header or a cookie. Pick the Transport that suits
+//the clients of each deployment, or combine several with Transports.
+type Transport interface {
+	//Write sends the SessionID `sid` to the client in the response
+	Write(w http.ResponseWriter, sid SessionID)
+
+	//Read returns the SessionID sent by the client in the request,
+	//without validating it. It returns ErrNoSessionID if the request
+	//doesn't carry one, and ErrInvalidScheme if it isn't a bearer token.
+	Read(r *http.Request) (string, error)
+
+	//Clear tells the client to forget its SessionID, if the
+	//Transport is able to
+	Clear(w http.ResponseWriter)
+}
+
+//DefaultTransport reads SessionIDs from the Authorization header, or
+//the `auth` query string parameter if there is no header, and writes
+//them to the Authorization header
+var DefaultTransport Transport = Transports{HeaderTransport{}, QueryTransport{}}
+
+//HeaderTransport carries SessionIDs in the Authorization header,
+//like so:
+//  Authorization: Bearer <sessionID>
+//Clients must remember the SessionID and send it with each request.
+type HeaderTransport struct{}
+
+//Write adds an Authorization header with the SessionID to the response
+func (HeaderTransport) Write(w http.ResponseWriter, sid SessionID) {
+	w.Header().Add(headerAuthorization, schemeBearer+sid.String())
+}
+
+//Read returns the SessionID from the request's Authorization header
+func (HeaderTransport) Read(r *http.Request) (string, error) {
+	return readBearer(r.Header.Get(headerAuthorization))
+}
+
+//Clear does nothing, since clients keep the SessionID themselves
+func (HeaderTransport) Clear(w http.ResponseWriter) {}
+
+//QueryTransport reads SessionIDs from the `auth` query string
+//parameter, as in `?auth=Bearer%20<sessionID>`, for clients such
+//as WebSockets that can't set headers. It can't write SessionIDs
+//to clients, so pair it with another Transport using Transports.
+type QueryTransport struct{}
+
+//Write does nothing, since a response can't add to the request's URL
+func (QueryTransport) Write(w http.ResponseWriter, sid SessionID) {}
+
+//Read returns the SessionID from the request's `auth` parameter
+func (QueryTransport) Read(r *http.Request) (string, error) {
+	return readBearer(r.URL.Query().Get(paramAuthorization))
+}
+
+//Clear does nothing, since clients keep the SessionID themselves
+func (QueryTransport) Clear(w http.ResponseWriter) {}
+
+//CookieTransport carries SessionIDs in a cookie, which browsers send
+//with each request on their own. The cookie is always HttpOnly, so
+//that scripts can't read the SessionID. It expires along with the
+//browser session, while the session's state expires on the server.
+type CookieTransport struct {
+	//Name of the cookie
+	Name string
+	//Path and Domain the cookie is sent to. Path defaults to "/".
+	Path   string
+	Domain string
+	//Secure cookies are only sent over HTTPS
+	Secure bool
+	//SameSite controls whether the cookie is sent with requests
+	//that come from other sites
+	SameSite http.SameSite
+}
+
+//NewCookieTransport constructs a CookieTransport using the cookie
+//`name`, which is only sent over HTTPS, to every path, and never
+//with cross-site subrequests
+func NewCookieTransport(name string) *CookieTransport {
+	return &CookieTransport{
+		Name:     name,
+		Path:     "/",
+		Secure:   true,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+//Write sets the cookie to the SessionID
+func (ct *CookieTransport) Write(w http.ResponseWriter, sid SessionID) {
+	http.SetCookie(w, ct.cookie(sid.String(), 0))
+}
+
+//Read returns the SessionID from the cookie
+func (ct *CookieTransport) Read(r *http.Request) (string, error) {
+	cookie, err := r.Cookie(ct.Name)
+	if err != nil || len(cookie.Value) == 0 {
+		return "", ErrNoSessionID
+	}
+	return cookie.Value, nil
+}
+
+//Clear deletes the cookie
+func (ct *CookieTransport) Clear(w http.ResponseWriter) {
+	http.SetCookie(w, ct.cookie("", -1))
+}
+
+//cookie returns the cookie holding `value`,
+//with `maxAge` as in http.Cookie
+func (ct *CookieTransport) cookie(value string, maxAge int) *http.Cookie {
+	path := ct.Path
+	if len(path) == 0 {
+		path = "/"
+	}
+	return &http.Cookie{
+		Name:     ct.Name,
+		Value:    value,
+		Path:     path,
+		Domain:   ct.Domain,
+		MaxAge:   maxAge,
+		Secure:   ct.Secure,
+		HttpOnly: true,
+		SameSite: ct.SameSite,
+	}
+}
+
+//Transports combines several Transports. SessionIDs are written
+//and cleared with each of them, and read from the first one that
+//finds a SessionID in the request.
+type Transports []Transport
+
+//Write sends the SessionID with each Transport
+func (ts Transports) Write(w http.ResponseWriter, sid SessionID) {
+	for _, t := range ts {
+		t.Write(w, sid)
+	}
+}
+
+//Read returns the SessionID from the first Transport that
+//finds one, or ErrNoSessionID if none of them do
+func (ts Transports) Read(r *http.Request) (string, error) {
+	for _, t := range ts {
+		if token, err := t.Read(r); err != ErrNoSessionID {
+			return token, err
+		}
+	}
+	return "", ErrNoSessionID
+}
+
+//Clear clears the SessionID with each Transport
+func (ts Transports) Clear(w http.ResponseWriter) {
+	for _, t := range ts {
+		t.Clear(w)
+	}
+}
+
+//readBearer returns the token from the value of an Authorization
+//header or parameter, which must use the bearer scheme
+func readBearer(value string) (string, error) {
+	if len(value) == 0 {
+		return "", ErrNoSessionID
+	}
+	if !strings.HasPrefix(value, schemeBearer) {
+		return "", ErrInvalidScheme
+	}
+	return strings.TrimPrefix(value, schemeBearer), nil
+}
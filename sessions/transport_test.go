@@ -0,0 +1,182 @@
+package sessions
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestTransportRead(t *testing.T) {
+	cookies := NewCookieTransport("sid")
+	cases := []struct {
+		name          string
+		transport     Transport
+		header        string
+		param         string
+		cookie        string
+		expectedToken string
+		expectedError error
+	}{
+		{
+			"Header",
+			HeaderTransport{},
+			schemeBearer + "header",
+			"",
+			"",
+			"header",
+			nil,
+		},
+		{
+			"Header Invalid Scheme",
+			HeaderTransport{},
+			"Basic header",
+			"",
+			"",
+			"",
+			ErrInvalidScheme,
+		},
+		{
+			"Header Ignores Cookie",
+			HeaderTransport{},
+			"",
+			"",
+			"cookie",
+			"",
+			ErrNoSessionID,
+		},
+		{
+			"Query",
+			QueryTransport{},
+			"",
+			schemeBearer + "param",
+			"",
+			"param",
+			nil,
+		},
+		{
+			"Cookie",
+			cookies,
+			"",
+			"",
+			"cookie",
+			"cookie",
+			nil,
+		},
+		{
+			"Cookie Ignores Header",
+			cookies,
+			schemeBearer + "header",
+			"",
+			"",
+			"",
+			ErrNoSessionID,
+		},
+		{
+			"Default Prefers Header",
+			DefaultTransport,
+			schemeBearer + "header",
+			schemeBearer + "param",
+			"",
+			"header",
+			nil,
+		},
+		{
+			"Default Falls Back To Query",
+			DefaultTransport,
+			"",
+			schemeBearer + "param",
+			"",
+			"param",
+			nil,
+		},
+		{
+			"Combined Falls Back To Cookie",
+			Transports{HeaderTransport{}, cookies},
+			"",
+			"",
+			"cookie",
+			"cookie",
+			nil,
+		},
+		{
+			"Combined Stops At Invalid Scheme",
+			Transports{HeaderTransport{}, cookies},
+			"Basic header",
+			"",
+			"cookie",
+			"",
+			ErrInvalidScheme,
+		},
+		{
+			"None Found",
+			Transports{HeaderTransport{}, QueryTransport{}, cookies},
+			"",
+			"",
+			"",
+			"",
+			ErrNoSessionID,
+		},
+	}
+
+	for _, c := range cases {
+		req, _ := http.NewRequest("GET", "/?"+paramAuthorization+"="+url.QueryEscape(c.param), nil)
+		if len(c.header) > 0 {
+			req.Header.Set(headerAuthorization, c.header)
+		}
+		if len(c.cookie) > 0 {
+			req.AddCookie(&http.Cookie{Name: "sid", Value: c.cookie})
+		}
+		token, err := c.transport.Read(req)
+		if err != c.expectedError {
+			t.Errorf("case %s: incorrect error: expected %v but got %v", c.name, c.expectedError, err)
+		}
+		if token != c.expectedToken {
+			t.Errorf("case %s: incorrect token: expected %q but got %q", c.name, c.expectedToken, token)
+		}
+	}
+}
+
+func TestCookieTransportCycle(t *testing.T) {
+	store := NewMemStore(time.Hour, time.Minute)
+	key := SigningKey("test key")
+	transport := NewCookieTransport("sid")
+
+	respRec := httptest.NewRecorder()
+	sid, err := BeginSession(context.Background(), key, transport, store, 100, respRec)
+	if err != nil {
+		t.Fatalf("error beginning session: %v", err)
+	}
+	if len(respRec.Header().Get(headerAuthorization)) > 0 {
+		t.Error("cookie transport should not add an Authorization header")
+	}
+	cookies := respRec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("incorrect number of cookies set: expected 1 but got %d", len(cookies))
+	}
+	cookie := cookies[0]
+	if cookie.Value != sid.String() || !cookie.HttpOnly || !cookie.Secure || cookie.SameSite != http.SameSiteLaxMode || cookie.Path != "/" {
+		t.Errorf("incorrect session cookie: %+v", cookie)
+	}
+
+	req, _ := http.NewRequest("GET", "/", nil)
+	req.AddCookie(cookie)
+	var state int
+	if sid2, err := GetState(req, key, transport, store, &state); err != nil || sid2 != sid || state != 100 {
+		t.Errorf("incorrect result getting state from cookie: %s, %d, %v", sid2, state, err)
+	}
+
+	respRec = httptest.NewRecorder()
+	if _, err := EndSession(respRec, req, key, transport, store); err != nil {
+		t.Fatalf("error ending session: %v", err)
+	}
+	cookies = respRec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != "sid" || len(cookies[0].Value) > 0 || cookies[0].MaxAge >= 0 {
+		t.Errorf("session cookie was not cleared: %+v", cookies)
+	}
+	if _, err := GetState(req, key, transport, store, &state); err != ErrStateNotFound {
+		t.Errorf("incorrect error getting state after session end: expected %v but got %v", ErrStateNotFound, err)
+	}
+}